@@ -6,7 +6,9 @@ import (
 	"testex/internal/config"
 	"testex/internal/handler"
 	"testex/internal/service"
+	"testex/internal/service/secret"
 	"testex/internal/storage"
+	"testex/internal/storage/filesystem"
 	"testex/internal/storage/postgres"
 	"testex/pkg/server"
 	sl "testex/pkg/slog"
@@ -22,6 +24,14 @@ func main() {
 	//logger init
 	logger := setupLogger(cfg.Env)
 	logger.Info("App is starting on port "+cfg.HTTPServer.Port, slog.String("Env", cfg.Env))
+	//auth config check, before anything is run
+	if err := cfg.Auth.Validate(); err != nil {
+		logger.Error("invalid auth config", sl.Err(err))
+		os.Exit(1)
+	}
+	if !cfg.Auth.Enabled {
+		logger.Warn("auth is disabled, every request is made by an admin")
+	}
 
 	//pg init
 	db, err := postgres.New(cfg.Postgres)
@@ -29,13 +39,48 @@ func main() {
 		logger.Error("failed to init storage", sl.Err(err))
 		os.Exit(1)
 	}
+	//blob store init
+	blobs, err := filesystem.NewBlobStorage(cfg.Blobs.Dir)
+	if err != nil {
+		logger.Error("failed to init blob storage", sl.Err(err))
+		os.Exit(1)
+	}
 	//app storage init
-	appStorage := storage.New(db)
+	appStorage := storage.New(db, blobs)
+	//secrets key init
+	var cipher *secret.Cipher
+	key, err := cfg.Secrets.LoadKey()
+	if err == nil && key != nil {
+		cipher, err = secret.NewCipher(key)
+	}
+	if err != nil {
+		logger.Error("failed to load secrets key", sl.Err(err))
+		os.Exit(1)
+	}
+	if cipher == nil {
+		logger.Warn("secrets key is not configured, commands can't use secrets")
+	}
 
 	//service init
-	services := service.New(appStorage, logger, cfg)
+	services := service.New(appStorage, logger, cfg, cipher)
+	//settle executions left over from a previous run
+	if err = services.Reconcile(); err != nil {
+		logger.Error("failed to reconcile executions", sl.Err(err))
+		os.Exit(1)
+	}
+	if err = services.ReconcilePipelines(); err != nil {
+		logger.Error("failed to reconcile pipeline runs", sl.Err(err))
+		os.Exit(1)
+	}
+	//scheduler init
+	if err = services.StartScheduler(); err != nil {
+		logger.Error("failed to start scheduler", sl.Err(err))
+		os.Exit(1)
+	}
+	//retention janitor init
+	services.StartJanitor()
 	//router init
-	router := handler.New(services, logger)
+	router := handler.New(services, logger, cfg.HTTPServer.AllowedOrigins)
 	_ = router
 	//server init
 	srv := server.New(cfg.HTTPServer.Port, router.Mux, cfg.HTTPServer.Timeout)
@@ -1,9 +1,14 @@
 package config
 
 import (
+	"encoding/base64"
+	"errors"
+	"fmt"
 	"github.com/spf13/viper"
 	"log"
 	"os"
+	"path/filepath"
+	"strings"
 	"time"
 )
 
@@ -12,11 +17,196 @@ type Config struct {
 	Os         string           `yaml:"os"`
 	Postgres   PostgresDatabase `yaml:"postgres"`
 	HTTPServer HTTPServer       `mapstructure:"http_server"`
+	Execution  Execution        `yaml:"execution"`
+	Logs       Logs             `yaml:"logs"`
+	Retention  Retention        `yaml:"retention"`
+	Blobs      Blobs            `yaml:"blobs"`
+	Artifacts  Artifacts        `yaml:"artifacts"`
+	Workdir    Workdir          `yaml:"workdir"`
+	ScriptEnv  ScriptEnv        `mapstructure:"script_env"`
+	Secrets    Secrets          `yaml:"secrets"`
+	Auth       Auth             `yaml:"auth"`
+	RunAs      RunAs            `mapstructure:"run_as"`
+}
+
+// SecretValues are values of the configuration that must not reach scripts.
+func (c Config) SecretValues() []string {
+	var secrets []string
+	for _, value := range []string{c.Postgres.Password, c.Secrets.Key, c.Auth.BootstrapKey, c.Auth.JWTSecret} {
+		if value != "" {
+			secrets = append(secrets, value)
+		}
+	}
+	return secrets
 }
 
 type HTTPServer struct {
 	Port    string        `yaml:"port"`
 	Timeout time.Duration `yaml:"timeout"`
+	// AllowedOrigins may open log websockets besides the origin of the API
+	// itself, e.g. "https://dashboard.example.com".
+	AllowedOrigins []string `mapstructure:"allowed_origins"`
+}
+
+type Execution struct {
+	// MaxTimeout caps timeouts of all executions, zero means no limit.
+	MaxTimeout time.Duration `mapstructure:"max_timeout"`
+	// StopSignal and GracePeriod are used for commands that don't set their own.
+	StopSignal  string        `mapstructure:"stop_signal"`
+	GracePeriod time.Duration `mapstructure:"grace_period"`
+	// Workers limits executions running at once, zero means no limit.
+	Workers int `mapstructure:"workers"`
+	// QueueSize limits executions waiting for a worker, zero means no limit.
+	QueueSize int `mapstructure:"queue_size"`
+	// MaxLineLength cuts longer lines of output, zero means 64 KiB.
+	MaxLineLength int `mapstructure:"max_line_length"`
+	// MaxOutputBytes and MaxOutputLines cap the output kept per execution,
+	// the rest is discarded. Zero means no limit.
+	MaxOutputBytes int64 `mapstructure:"max_output_bytes"`
+	MaxOutputLines int   `mapstructure:"max_output_lines"`
+}
+
+// Logs configures batching of execution output before it is stored.
+type Logs struct {
+	BatchSize     int           `mapstructure:"batch_size"`
+	FlushInterval time.Duration `mapstructure:"flush_interval"`
+	// BufferSize is the maximum number of unsaved lines per execution,
+	// lines beyond it are dropped.
+	BufferSize int `mapstructure:"buffer_size"`
+}
+
+// Retention configures pruning of old executions and their logs. Zero limits
+// keep everything, commands may override them.
+type Retention struct {
+	// Interval between janitor passes, zero disables the janitor.
+	Interval time.Duration `mapstructure:"interval"`
+	// BatchSize limits rows deleted by one statement, so that hot tables
+	// aren't locked for long.
+	BatchSize int `mapstructure:"batch_size"`
+	// MaxAge of finished executions.
+	MaxAge time.Duration `mapstructure:"max_age"`
+	// MaxExecutions kept per command, the oldest are deleted first.
+	MaxExecutions int `mapstructure:"max_executions"`
+	// MaxLogBytes kept per finished execution, the earliest lines are
+	// deleted first.
+	MaxLogBytes int64 `mapstructure:"max_log_bytes"`
+}
+
+// Blobs configures the store of raw execution output.
+type Blobs struct {
+	Dir string `mapstructure:"dir"`
+}
+
+// Artifacts limits files collected after an execution, zero means no limit.
+type Artifacts struct {
+	MaxFiles int   `mapstructure:"max_files"`
+	MaxBytes int64 `mapstructure:"max_bytes"`
+}
+
+// Workdir configures working directories of executions.
+type Workdir struct {
+	// Root holds temporary directories and workspaces, relative directories
+	// of the fixed mode are resolved against it too. The system temporary
+	// directory is used by default.
+	Root string `mapstructure:"root"`
+	// TempMaxAge keeps temporary directories of finished executions around
+	// for debugging, zero removes them right after the run.
+	TempMaxAge time.Duration `mapstructure:"temp_max_age"`
+}
+
+// RootDir is the absolute path of Root.
+func (w Workdir) RootDir() (string, error) {
+	if w.Root == "" {
+		return filepath.Join(os.TempDir(), "testex"), nil
+	}
+	return filepath.Abs(w.Root)
+}
+
+// TempDir holds temporary directories of executions, named by their ids.
+func (w Workdir) TempDir() (string, error) {
+	root, err := w.RootDir()
+	return filepath.Join(root, "tmp"), err
+}
+
+// SecretsDir holds secret files of running executions, in directories named
+// by execution ids. It is kept apart from working directories, so secrets are
+// never collected as artifacts.
+func (w Workdir) SecretsDir() (string, error) {
+	root, err := w.RootDir()
+	return filepath.Join(root, "secrets"), err
+}
+
+// ScriptEnv configures variables of the testex environment passed to
+// scripts.
+type ScriptEnv struct {
+	// Allowlist names variables passed to commands with the allowlist
+	// policy.
+	Allowlist []string `mapstructure:"allowlist"`
+	// Deny names variables never passed to scripts. Variables holding
+	// secrets of this configuration are never passed either.
+	Deny []string `mapstructure:"deny"`
+}
+
+// RunAs configures the OS users scripts run as. Switching users needs testex
+// to run as root.
+type RunAs struct {
+	// Default is used for commands that don't set their own user, empty keeps
+	// the user of testex.
+	Default string `mapstructure:"default"`
+	// Allowed lists the values commands may set, "user" or "user:group".
+	Allowed []string `mapstructure:"allowed"`
+}
+
+// Secrets configures the key the secrets store is encrypted with: 32 bytes
+// encoded in base64, given as is or in a file. Without a key secrets can't be
+// stored nor used.
+type Secrets struct {
+	Key     string `mapstructure:"key"`
+	KeyFile string `mapstructure:"key_file"`
+}
+
+// LoadKey decodes the configured key, it returns nil if there is none.
+func (s Secrets) LoadKey() ([]byte, error) {
+	encoded := s.Key
+	if encoded == "" && s.KeyFile != "" {
+		data, err := os.ReadFile(s.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		encoded = strings.TrimSpace(string(data))
+	}
+	if encoded == "" {
+		return nil, nil
+	}
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("secrets key is not valid base64: %w", err)
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("secrets key must be 32 bytes long, got %d", len(key))
+	}
+	return key, nil
+}
+
+// Auth configures authentication of API requests.
+type Auth struct {
+	// Enabled requires credentials for every request. Without it every
+	// request is made by an admin.
+	Enabled bool `mapstructure:"enabled"`
+	// BootstrapKey is an admin API key that is always accepted, to create
+	// the first keys with.
+	BootstrapKey string `mapstructure:"bootstrap_key"`
+	// JWTSecret verifies HS256 bearer tokens, none are accepted without it.
+	JWTSecret string `mapstructure:"jwt_secret"`
+}
+
+// Validate rejects enabled auth that accepts no credentials at all, API keys
+// can't be created without the bootstrap key or a token of an admin.
+func (a Auth) Validate() error {
+	if a.Enabled && a.BootstrapKey == "" && a.JWTSecret == "" {
+		return errors.New("auth is enabled, but neither bootstrap_key nor jwt_secret is set")
+	}
+	return nil
 }
 
 type PostgresDatabase struct {
@@ -35,6 +225,9 @@ func MustLoad() Config {
 	viper.SetConfigName("config")
 	viper.AddConfigPath(configPath)
 	viper.SetConfigType("yaml")
+	// Credentials can be kept out of the config file.
+	_ = viper.BindEnv("auth.bootstrap_key", "AUTH_BOOTSTRAP_KEY")
+	_ = viper.BindEnv("auth.jwt_secret", "AUTH_JWT_SECRET")
 
 	if err := viper.ReadInConfig(); err != nil {
 		log.Fatal(err)
@@ -0,0 +1,117 @@
+package entities
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"slices"
+)
+
+// Permission is an action on a command granted by its ACL.
+type Permission string
+
+const (
+	PermissionExecute Permission = "execute"
+	PermissionStop    Permission = "stop"
+	// PermissionViewLogs covers executions of the command, their logs, output
+	// and artifacts.
+	PermissionViewLogs Permission = "view_logs"
+	// PermissionEdit lets operators change and delete the command.
+	PermissionEdit Permission = "edit"
+)
+
+func (p Permission) Valid() bool {
+	switch p {
+	case PermissionExecute, PermissionStop, PermissionViewLogs, PermissionEdit:
+		return true
+	default:
+		return false
+	}
+}
+
+// AclEntry grants permissions to a principal or to everyone in a group.
+type AclEntry struct {
+	Principal   string       `json:"principal,omitempty"`
+	Group       string       `json:"group,omitempty"`
+	Permissions []Permission `json:"permissions"`
+}
+
+func (e AclEntry) matches(principal Principal) bool {
+	if e.Principal != "" {
+		return e.Principal == principal.Name
+	}
+	return slices.Contains(principal.Groups, e.Group)
+}
+
+// Acl restricts who can see and use a command beyond the roles. An empty ACL
+// leaves the roles alone, admins are never restricted.
+type Acl []AclEntry
+
+func (a Acl) Value() (driver.Value, error) {
+	return jsonValue(a)
+}
+
+func (a *Acl) Scan(src any) error {
+	return jsonScan(src, a)
+}
+
+// Shows reports whether the principal can see the command at all.
+func (a Acl) Shows(principal Principal) bool {
+	if len(a) == 0 || principal.Role == RoleAdmin {
+		return true
+	}
+	return slices.ContainsFunc(a, func(e AclEntry) bool { return e.matches(principal) })
+}
+
+// Permits reports whether the principal has the permission. Without an ACL
+// editing is left to admins and the rest to the roles.
+func (a Acl) Permits(principal Principal, permission Permission) bool {
+	if principal.Role == RoleAdmin {
+		return true
+	}
+	if len(a) == 0 {
+		return permission != PermissionEdit
+	}
+	return slices.ContainsFunc(a, func(e AclEntry) bool {
+		return e.matches(principal) && slices.Contains(e.Permissions, permission)
+	})
+}
+
+// Authorize checks that the principal of a request may act on a command with
+// the ACL. Commands the principal can't see are reported as not found. Calls
+// without a principal come from the server itself and are not restricted.
+func Authorize(ctx context.Context, acl Acl, alias string, permission Permission) error {
+	principal, ok := PrincipalFromContext(ctx)
+	if !ok {
+		return nil
+	}
+	if !acl.Shows(principal) {
+		return ErrNotFound
+	}
+	if !acl.Permits(principal, permission) {
+		return fmt.Errorf("%w: %s of command %s is not permitted", ErrForbidden, permission, alias)
+	}
+	return nil
+}
+
+// ViewFilter returns whether the principal of ctx may view executions of a
+// command by its alias. Schedules, pipelines and their runs name commands,
+// params and executions, so they are shown as the executions are. Commands
+// are loaded only for restricted principals, unknown ones have no ACL.
+func ViewFilter(ctx context.Context, commands func() ([]Command, error)) (func(alias string) bool, error) {
+	principal, ok := PrincipalFromContext(ctx)
+	if !ok || principal.Role == RoleAdmin {
+		return func(string) bool { return true }, nil
+	}
+	all, err := commands()
+	if err != nil {
+		return nil, err
+	}
+	acls := make(map[string]Acl, len(all))
+	for _, command := range all {
+		acls[command.Alias] = command.Acl
+	}
+	return func(alias string) bool {
+		return acls[alias].Permits(principal, PermissionViewLogs)
+	}, nil
+}
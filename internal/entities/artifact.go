@@ -0,0 +1,32 @@
+package entities
+
+import (
+	"database/sql/driver"
+	"time"
+)
+
+// ArtifactPatterns are glob patterns of files collected from the working
+// directory after a run, relative to it. `**` matches any number of
+// directories.
+type ArtifactPatterns []string
+
+func (p ArtifactPatterns) Value() (driver.Value, error) {
+	return jsonValue(p)
+}
+
+func (p *ArtifactPatterns) Scan(src any) error {
+	return jsonScan(src, p)
+}
+
+// Artifact is a file collected after an execution, kept in the blob store
+// under BlobKey.
+type Artifact struct {
+	Id                int `db:"id" json:"id"`
+	ExecutedCommandId int `db:"executed_command_id" json:"executed_command_id"`
+	// Path of the file relative to the working directory, with forward
+	// slashes.
+	Path      string    `db:"path" json:"path"`
+	BlobKey   string    `db:"blob_key" json:"blob_key"`
+	Size      int64     `db:"size" json:"size"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
@@ -0,0 +1,62 @@
+package entities
+
+import (
+	"context"
+	"time"
+)
+
+type AuditOutcome string
+
+const (
+	AuditSuccess AuditOutcome = "success"
+	// AuditDenied is an action refused for lack of permissions.
+	AuditDenied  AuditOutcome = "denied"
+	AuditFailure AuditOutcome = "failure"
+)
+
+// AuditEvent records a change made through the service, or an attempt of it.
+// Events are never changed or deleted.
+type AuditEvent struct {
+	Id    int64     `db:"id" json:"id"`
+	At    time.Time `db:"at" json:"at"`
+	Actor string    `db:"actor" json:"actor"`
+	Role  Role      `db:"role" json:"role,omitempty"`
+	// SourceIp is the address the request came from, empty for actions of
+	// the server itself.
+	SourceIp string `db:"source_ip" json:"source_ip,omitempty"`
+	Action   string `db:"action" json:"action"`
+	// Target is the path of the entity acted on, e.g. commands/deploy.
+	Target string `db:"target" json:"target"`
+	// PayloadHash is the SHA-256 of the request payload, empty when there is
+	// none or it holds secrets.
+	PayloadHash string       `db:"payload_hash" json:"payload_hash,omitempty"`
+	Outcome     AuditOutcome `db:"outcome" json:"outcome"`
+	Error       string       `db:"error" json:"error,omitempty"`
+}
+
+type AuditFilter struct {
+	Actor   string
+	Action  string
+	Target  string
+	Outcome AuditOutcome
+	// From and To limit the time, zero values mean no limit.
+	From, To time.Time
+	// BeforeId and AfterId continue a listing in the respective order.
+	BeforeId  int64
+	AfterId   int64
+	Limit     int
+	Ascending bool
+}
+
+type sourceIpKey struct{}
+
+// ContextWithSourceIp records the address a request came from for the audit
+// trail.
+func ContextWithSourceIp(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, sourceIpKey{}, ip)
+}
+
+func SourceIpFromContext(ctx context.Context) string {
+	ip, _ := ctx.Value(sourceIpKey{}).(string)
+	return ip
+}
@@ -0,0 +1,99 @@
+package entities
+
+import (
+	"context"
+	"database/sql/driver"
+	"time"
+)
+
+// Role grants access to the API. Every role can do what the lower ones can.
+type Role string
+
+const (
+	// RoleViewer reads commands, executions and logs.
+	RoleViewer Role = "viewer"
+	// RoleOperator also executes and stops commands.
+	RoleOperator Role = "operator"
+	// RoleAdmin also creates and edits commands, and manages secrets and keys.
+	RoleAdmin Role = "admin"
+)
+
+func (r Role) rank() int {
+	switch r {
+	case RoleViewer:
+		return 1
+	case RoleOperator:
+		return 2
+	case RoleAdmin:
+		return 3
+	default:
+		return 0
+	}
+}
+
+// Valid reports whether r is a known role.
+func (r Role) Valid() bool {
+	return r.rank() > 0
+}
+
+// Allows reports whether r grants the access of the required role.
+func (r Role) Allows(required Role) bool {
+	return r.Valid() && r.rank() >= required.rank()
+}
+
+// ApiKey is a key for the API. Only a hash of the key is kept, the key itself
+// is shown once, when it is created.
+type ApiKey struct {
+	Id   int    `db:"id" json:"id"`
+	Name string `db:"name" json:"name"`
+	Role Role   `db:"role" json:"role"`
+	// Prefix is the beginning of the key, to tell keys apart.
+	Prefix    string    `db:"prefix" json:"prefix"`
+	Hash      string    `db:"hash" json:"-"`
+	Groups    Groups    `db:"groups" json:"groups,omitempty"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
+
+type ApiKeyDto struct {
+	Name   string `json:"name"`
+	Role   Role   `json:"role"`
+	Groups Groups `json:"groups,omitempty"`
+}
+
+// Groups name the groups a principal belongs to, command ACLs grant
+// permissions to them.
+type Groups []string
+
+func (g Groups) Value() (driver.Value, error) {
+	return jsonValue(g)
+}
+
+func (g *Groups) Scan(src any) error {
+	return jsonScan(src, g)
+}
+
+// CreatedApiKey is returned once, when the key is created.
+type CreatedApiKey struct {
+	ApiKey
+	Key string `json:"key"`
+}
+
+// Principal is the authenticated client a request is made by.
+type Principal struct {
+	Name   string `json:"name"`
+	Role   Role   `json:"role"`
+	Groups Groups `json:"groups,omitempty"`
+}
+
+type principalKey struct{}
+
+func ContextWithPrincipal(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFromContext returns the principal of a request, if it is
+// authenticated.
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(Principal)
+	return principal, ok
+}
@@ -6,23 +6,271 @@ import (
 )
 
 type Command struct {
-	Id     int    `json:"id"`
-	Alias  string `json:"alias"`
-	Script string `json:"script"`
+	Id     int           `json:"id"`
+	Alias  string        `json:"alias"`
+	Script string        `json:"script"`
+	Params CommandParams `json:"params,omitempty"`
+	// Timeout in seconds, zero means the server-wide maximum.
+	Timeout int `json:"timeout,omitempty"`
+	// StopSignal is sent to the process group on stop, SIGTERM by default.
+	StopSignal string `db:"stop_signal" json:"stop_signal,omitempty"`
+	// GracePeriod in seconds before a stopped command is killed with SIGKILL.
+	GracePeriod int `db:"grace_period" json:"grace_period,omitempty"`
+	// MaxConcurrency limits simultaneous runs of the command, zero means no limit.
+	MaxConcurrency int `db:"max_concurrency" json:"max_concurrency,omitempty"`
+	// Overflow tells what to do with a run over MaxConcurrency.
+	Overflow OverflowPolicy `db:"overflow" json:"overflow,omitempty"`
+	// OutputMode tells whether output is stored as log lines, as raw bytes, or
+	// both.
+	OutputMode OutputMode `db:"output_mode" json:"output_mode,omitempty"`
+	// Artifacts are collected from the working directory after every run.
+	Artifacts ArtifactPatterns `db:"artifacts" json:"artifacts,omitempty"`
+	// WorkdirMode tells where the script runs, Workdir is the directory of
+	// the fixed mode.
+	WorkdirMode WorkdirMode `db:"workdir_mode" json:"workdir_mode,omitempty"`
+	Workdir     string      `db:"workdir" json:"workdir,omitempty"`
+	// Env is set for the script on top of the variables inherited as
+	// EnvInherit says.
+	Env        EnvVars    `db:"env" json:"env,omitempty"`
+	EnvInherit EnvInherit `db:"env_inherit" json:"env_inherit,omitempty"`
+	// Secrets are resolved when the command is run, only names are kept.
+	Secrets SecretRefs `db:"secrets" json:"secrets,omitempty"`
+	// Acl restricts the command to some principals and groups, only admins
+	// change it.
+	Acl Acl `db:"acl" json:"acl,omitempty"`
+	// RunAs is the OS user the script runs as, "user" or "user:group". Empty
+	// means the default of the server.
+	RunAs string `db:"run_as" json:"run_as,omitempty"`
+	// MaxLineLength, MaxOutputBytes and MaxOutputLines override the output
+	// limits of the server, zero keeps the default.
+	MaxLineLength  int   `db:"max_line_length" json:"max_line_length,omitempty"`
+	MaxOutputBytes int64 `db:"max_output_bytes" json:"max_output_bytes,omitempty"`
+	MaxOutputLines int   `db:"max_output_lines" json:"max_output_lines,omitempty"`
+	// RetentionDays, KeepExecutions and MaxLogBytes override the configured
+	// retention for executions of the command, zero keeps the default.
+	RetentionDays  int   `db:"retention_days" json:"retention_days,omitempty"`
+	KeepExecutions int   `db:"keep_executions" json:"keep_executions,omitempty"`
+	MaxLogBytes    int64 `db:"max_log_bytes" json:"max_log_bytes,omitempty"`
+	// Version is incremented by every edit and serves as the ETag.
+	Version int `db:"version" json:"version"`
+	// RevisionId points at the revision holding the current script.
+	RevisionId int        `db:"revision_id" json:"revision_id"`
+	DeletedAt  *time.Time `db:"deleted_at" json:"deleted_at,omitempty"`
 }
 
+// CommandRevision is an immutable snapshot of a command script, kept for
+// every version of the command.
+type CommandRevision struct {
+	Id        int           `db:"id" json:"id"`
+	CommandId int           `db:"command_id" json:"command_id"`
+	Version   int           `db:"version" json:"version"`
+	Script    string        `db:"script" json:"script"`
+	Params    CommandParams `db:"params" json:"params,omitempty"`
+	CreatedAt time.Time     `db:"created_at" json:"created_at"`
+}
+
+type OverflowPolicy string
+
+const (
+	// OverflowQueue keeps the run queued until a slot frees up.
+	OverflowQueue OverflowPolicy = "queue"
+	// OverflowReject refuses the run.
+	OverflowReject OverflowPolicy = "reject"
+	// OverflowReplace stops the oldest run of the command and queues the new one.
+	OverflowReplace OverflowPolicy = "replace"
+)
+
+type OutputMode string
+
+const (
+	// OutputLines stores output as log lines.
+	OutputLines OutputMode = "lines"
+	// OutputRaw stores stdout and stderr as they are, in the blob store.
+	OutputRaw OutputMode = "raw"
+	// OutputBoth stores both log lines and raw output.
+	OutputBoth OutputMode = "both"
+)
+
+type WorkdirMode string
+
+const (
+	// WorkdirFixed runs every execution in the same given directory.
+	WorkdirFixed WorkdirMode = "fixed"
+	// WorkdirTemp runs every execution in a fresh directory, removed after
+	// the execution is finished.
+	WorkdirTemp WorkdirMode = "temp"
+	// WorkdirWorkspace runs every execution in a directory kept per command
+	// between executions.
+	WorkdirWorkspace WorkdirMode = "workspace"
+)
+
+type ExecutionStatus string
+
+const (
+	StatusQueued    ExecutionStatus = "queued"
+	StatusRunning   ExecutionStatus = "running"
+	StatusSucceeded ExecutionStatus = "succeeded"
+	StatusFailed    ExecutionStatus = "failed"
+	StatusStopped   ExecutionStatus = "stopped"
+	StatusTimedOut  ExecutionStatus = "timed_out"
+	StatusLost      ExecutionStatus = "lost"
+)
+
 type ExecutedCommand struct {
+	Id         int             `db:"id" json:"id"`
+	CommandId  int             `db:"command_id" json:"command_id"`
+	PID        int             `db:"pid" json:"pid"`
+	IsActive   bool            `db:"is_active" json:"is_active"`
+	Params     ParamValues     `db:"params" json:"params,omitempty"`
+	Status     ExecutionStatus `db:"status" json:"status"`
+	StartedAt  time.Time       `db:"started_at" json:"started_at"`
+	FinishedAt *time.Time      `db:"finished_at" json:"finished_at,omitempty"`
+	ExitCode   *int            `db:"exit_code" json:"exit_code,omitempty"`
+	Signal     *string         `db:"signal" json:"signal,omitempty"`
+	DurationMs *int64          `db:"duration_ms" json:"duration_ms,omitempty"`
+	// DroppedLines counts output lines lost because storage couldn't keep up.
+	DroppedLines int `db:"dropped_lines" json:"dropped_lines,omitempty"`
+	// TrimmedLines counts the earliest output lines deleted by retention to
+	// fit the log size limit.
+	TrimmedLines  int  `db:"trimmed_lines" json:"trimmed_lines,omitempty"`
+	LogsCompacted bool `db:"logs_compacted" json:"-"`
+	// Truncated is set when output was cut or discarded by the output limits.
+	Truncated bool `db:"truncated" json:"truncated,omitempty"`
+	// Workdir is the directory the process ran in.
+	Workdir string `db:"workdir" json:"workdir,omitempty"`
+	// Uid is the effective user id the process ran with, unset on Windows.
+	Uid *int `db:"uid" json:"uid,omitempty"`
+	// ProcessKey tells the started process apart from a later one with the same PID.
+	ProcessKey string `db:"process_key" json:"-"`
+	// RevisionId is the revision of the command script that was run.
+	RevisionId *int `db:"revision_id" json:"revision_id,omitempty"`
+	// ScheduleId is set for executions triggered by a schedule.
+	ScheduleId  *int          `db:"schedule_id" json:"schedule_id,omitempty"`
+	TriggeredBy TriggerSource `db:"triggered_by" json:"triggered_by,omitempty"`
+	// Actor is the principal that requested the execution, empty for the
+	// server itself.
+	Actor *string `db:"actor" json:"actor,omitempty"`
+	// Alias of the command, filled in by queries that join it.
+	Alias string `db:"alias" json:"alias,omitempty"`
+	// Acl of the command, filled in by queries that join it.
+	Acl Acl `db:"acl" json:"-"`
+	// QueuePosition is the 1-based place of a queued execution in the queue.
+	QueuePosition int `db:"-" json:"queue_position,omitempty"`
+}
+
+type TriggerSource string
+
+const (
+	TriggeredByApi      TriggerSource = "api"
+	TriggeredBySchedule TriggerSource = "schedule"
+	TriggeredByPipeline TriggerSource = "pipeline"
+)
+
+// ExecutionDetails is the full record of an execution: the snapshot of the
+// command it ran and the number of its log lines.
+type ExecutionDetails struct {
+	ExecutedCommand
+	Command   *CommandRevision  `json:"command,omitempty"`
+	LogLines  int               `json:"log_lines"`
+	Outputs   []ExecutionOutput `json:"outputs,omitempty"`
+	Artifacts []Artifact        `json:"artifacts,omitempty"`
+}
+
+// ExecutionOutput is the raw output of a stream of an execution, kept in the
+// blob store under BlobKey.
+type ExecutionOutput struct {
+	ExecutedCommandId int       `db:"executed_command_id" json:"executed_command_id"`
+	Stream            LogStream `db:"stream" json:"stream"`
+	BlobKey           string    `db:"blob_key" json:"blob_key"`
+	Size              int64     `db:"size" json:"size"`
+	CreatedAt         time.Time `db:"created_at" json:"created_at"`
+}
+
+type ExecutionFilter struct {
+	Alias       string
+	CommandId   int
+	Status      ExecutionStatus
+	TriggeredBy TriggerSource
+	// From and To limit the start time, zero values mean no limit.
+	From, To  time.Time
+	Limit     int
+	Ascending bool
+	// After continues a listing after the given execution.
+	After *ExecutionCursor
+	// ViewableBy limits executions to commands whose ACL lets the principal
+	// view logs.
+	ViewableBy *Principal
+}
+
+// ExecutionCursor is the position of an execution in a listing sorted by
+// start time.
+type ExecutionCursor struct {
+	StartedAt time.Time
 	Id        int
-	CommandId int `db:"command_id"`
-	PID       int
-	IsActive  bool `db:"is_active"`
 }
 
+type ExecutionPage struct {
+	Executions []ExecutedCommand `json:"executions"`
+	// NextCursor is passed as ?cursor= to get the next page, it is empty on
+	// the last one.
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+type LogStream string
+
+const (
+	StreamStdout LogStream = "stdout"
+	StreamStderr LogStream = "stderr"
+	// StreamSystem holds lines written by testex itself, e.g. the exit status.
+	StreamSystem LogStream = "system"
+)
+
+type LogLevel string
+
+const (
+	LevelInfo  LogLevel = "info"
+	LevelWarn  LogLevel = "warn"
+	LevelError LogLevel = "error"
+)
+
 type Log struct {
-	Id                int       `db:"id" json:"id"`
-	ExecutedCommandId int       `db:"executed_command_id" json:"executed_command_id"`
-	Message           string    `db:"message" json:"message"`
-	Date              time.Time `db:"date" json:"date"`
+	Id                int `db:"id" json:"id"`
+	ExecutedCommandId int `db:"executed_command_id" json:"executed_command_id"`
+	// Seq orders the lines of an execution across all streams.
+	Seq     int64     `db:"seq" json:"seq"`
+	Stream  LogStream `db:"stream" json:"stream"`
+	Level   LogLevel  `db:"level" json:"level"`
+	Message string    `db:"message" json:"message"`
+	Date    time.Time `db:"date" json:"date"`
+}
+
+type LogFilter struct {
+	Stream   LogStream
+	AfterSeq int64
+	// Limit of zero returns all matching lines.
+	Limit int
+}
+
+// LogSearch selects log lines matching a full-text query across executions.
+type LogSearch struct {
+	Query  string
+	Alias  string
+	Stream LogStream
+	Status ExecutionStatus
+	From   time.Time
+	To     time.Time
+	Limit  int
+	// ViewableBy limits matches as in ExecutionFilter.
+	ViewableBy *Principal
+}
+
+// LogMatch is a log line found by a search. Snippet is the message with the
+// matched words wrapped in <mark> tags.
+type LogMatch struct {
+	Log
+	Alias   string          `db:"alias" json:"alias"`
+	Status  ExecutionStatus `db:"status" json:"status"`
+	Snippet string          `db:"snippet" json:"snippet"`
 }
 
 type CommandInfo struct {
@@ -33,8 +281,78 @@ type CommandInfo struct {
 }
 
 type CommandDto struct {
-	Alias  string `json:"alias"`
-	Script string `json:"script"`
+	Alias       string        `json:"alias"`
+	Script      string        `json:"script"`
+	Params      CommandParams `json:"params,omitempty"`
+	Timeout     int           `json:"timeout,omitempty"`
+	StopSignal  string        `json:"stop_signal,omitempty"`
+	GracePeriod int           `json:"grace_period,omitempty"`
+	// MaxConcurrency and Overflow limit simultaneous runs, see Command.
+	MaxConcurrency int              `json:"max_concurrency,omitempty"`
+	Overflow       OverflowPolicy   `json:"overflow,omitempty"`
+	OutputMode     OutputMode       `json:"output_mode,omitempty"`
+	Artifacts      ArtifactPatterns `json:"artifacts,omitempty"`
+	WorkdirMode    WorkdirMode      `json:"workdir_mode,omitempty"`
+	Workdir        string           `json:"workdir,omitempty"`
+	Env            EnvVars          `json:"env,omitempty"`
+	EnvInherit     EnvInherit       `json:"env_inherit,omitempty"`
+	Secrets        SecretRefs       `json:"secrets,omitempty"`
+	Acl            Acl              `json:"acl,omitempty"`
+	RunAs          string           `json:"run_as,omitempty"`
+	// MaxLineLength, MaxOutputBytes and MaxOutputLines override the output
+	// limits, see Command.
+	MaxLineLength  int   `json:"max_line_length,omitempty"`
+	MaxOutputBytes int64 `json:"max_output_bytes,omitempty"`
+	MaxOutputLines int   `json:"max_output_lines,omitempty"`
+	// RetentionDays, KeepExecutions and MaxLogBytes override the retention,
+	// see Command.
+	RetentionDays  int   `json:"retention_days,omitempty"`
+	KeepExecutions int   `json:"keep_executions,omitempty"`
+	MaxLogBytes    int64 `json:"max_log_bytes,omitempty"`
+}
+
+// CommandPatchDto changes only the fields that are set, see CommandDto.
+type CommandPatchDto struct {
+	Alias          *string           `json:"alias,omitempty"`
+	Script         *string           `json:"script,omitempty"`
+	Params         *CommandParams    `json:"params,omitempty"`
+	Timeout        *int              `json:"timeout,omitempty"`
+	StopSignal     *string           `json:"stop_signal,omitempty"`
+	GracePeriod    *int              `json:"grace_period,omitempty"`
+	MaxConcurrency *int              `json:"max_concurrency,omitempty"`
+	Overflow       *OverflowPolicy   `json:"overflow,omitempty"`
+	OutputMode     *OutputMode       `json:"output_mode,omitempty"`
+	Artifacts      *ArtifactPatterns `json:"artifacts,omitempty"`
+	WorkdirMode    *WorkdirMode      `json:"workdir_mode,omitempty"`
+	Workdir        *string           `json:"workdir,omitempty"`
+	Env            *EnvVars          `json:"env,omitempty"`
+	EnvInherit     *EnvInherit       `json:"env_inherit,omitempty"`
+	Secrets        *SecretRefs       `json:"secrets,omitempty"`
+	Acl            *Acl              `json:"acl,omitempty"`
+	RunAs          *string           `json:"run_as,omitempty"`
+	MaxLineLength  *int              `json:"max_line_length,omitempty"`
+	MaxOutputBytes *int64            `json:"max_output_bytes,omitempty"`
+	MaxOutputLines *int              `json:"max_output_lines,omitempty"`
+	RetentionDays  *int              `json:"retention_days,omitempty"`
+	KeepExecutions *int              `json:"keep_executions,omitempty"`
+	MaxLogBytes    *int64            `json:"max_log_bytes,omitempty"`
+}
+
+type ExecuteCommandDto struct {
+	Alias  string         `json:"alias"`
+	Params map[string]any `json:"params,omitempty"`
+	// Timeout in seconds, overrides the timeout of the command.
+	Timeout int `json:"timeout,omitempty"`
+	// ScheduleId and TriggeredBy are set by the server, they can't come from
+	// a request.
+	ScheduleId  *int          `json:"-"`
+	TriggeredBy TriggerSource `json:"-"`
+}
+
+type StopCommandDto struct {
+	Id int `json:"id"`
+	// Signal overrides the stop signal of the command, e.g. SIGHUP for reloads.
+	Signal string `json:"signal,omitempty"`
 }
 
 type CommandIDResponse struct {
@@ -0,0 +1,26 @@
+package entities
+
+import "database/sql/driver"
+
+// EnvVars are environment variables set for the script of a command.
+type EnvVars map[string]string
+
+func (e EnvVars) Value() (driver.Value, error) {
+	return jsonValue(e)
+}
+
+func (e *EnvVars) Scan(src any) error {
+	return jsonScan(src, e)
+}
+
+// EnvInherit tells which variables of the testex environment a script gets.
+type EnvInherit string
+
+const (
+	// EnvInheritNone passes no variables, only the ones of the command.
+	EnvInheritNone EnvInherit = "none"
+	// EnvInheritAllowlist passes the variables allowed in the configuration.
+	EnvInheritAllowlist EnvInherit = "allowlist"
+	// EnvInheritAll passes all variables except the ones holding secrets.
+	EnvInheritAll EnvInherit = "all"
+)
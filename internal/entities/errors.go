@@ -0,0 +1,19 @@
+package entities
+
+import "errors"
+
+var (
+	ErrInvalidParams     = errors.New("invalid command params")
+	ErrExecutionRejected = errors.New("execution rejected")
+	ErrNotFound          = errors.New("not found")
+	// ErrConflict is returned when a change clashes with existing data,
+	// e.g. a rename to a taken alias.
+	ErrConflict = errors.New("conflict")
+	// ErrVersionMismatch is returned when an edit is based on an outdated
+	// version of an entity.
+	ErrVersionMismatch = errors.New("version mismatch")
+	// ErrUnauthorized is returned for requests without valid credentials.
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrForbidden is returned when the principal lacks a permission.
+	ErrForbidden = errors.New("forbidden")
+)
@@ -0,0 +1,80 @@
+package entities
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+)
+
+type ParamType string
+
+const (
+	ParamString ParamType = "string"
+	ParamInt    ParamType = "int"
+	ParamBool   ParamType = "bool"
+	ParamEnum   ParamType = "enum"
+)
+
+type CommandParam struct {
+	Name     string    `json:"name"`
+	Type     ParamType `json:"type"`
+	Required bool      `json:"required,omitempty"`
+	Default  *string   `json:"default,omitempty"`
+	Pattern  string    `json:"pattern,omitempty"`
+	Values   []string  `json:"values,omitempty"`
+}
+
+// CommandParams is stored as a JSONB column of the commands table.
+type CommandParams []CommandParam
+
+func (p CommandParams) Value() (driver.Value, error) {
+	return jsonValue(p)
+}
+
+func (p *CommandParams) Scan(src any) error {
+	return jsonScan(src, p)
+}
+
+// ParamValues holds resolved parameter values of a single execution.
+type ParamValues map[string]string
+
+func (p ParamValues) Value() (driver.Value, error) {
+	return jsonValue(p)
+}
+
+func (p *ParamValues) Scan(src any) error {
+	return jsonScan(src, p)
+}
+
+// ParamInput holds raw parameter values as they come in a request, e.g. the
+// params a schedule executes its command with.
+type ParamInput map[string]any
+
+func (p ParamInput) Value() (driver.Value, error) {
+	return jsonValue(p)
+}
+
+func (p *ParamInput) Scan(src any) error {
+	return jsonScan(src, p)
+}
+
+func jsonValue(v any) (driver.Value, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func jsonScan(src any, dst any) error {
+	switch v := src.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return json.Unmarshal(v, dst)
+	case string:
+		return json.Unmarshal([]byte(v), dst)
+	default:
+		return errors.New("unsupported json column type")
+	}
+}
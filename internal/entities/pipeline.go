@@ -0,0 +1,96 @@
+package entities
+
+import (
+	"database/sql/driver"
+	"time"
+)
+
+type Pipeline struct {
+	Id        int           `db:"id" json:"id"`
+	Name      string        `db:"name" json:"name"`
+	Steps     PipelineSteps `db:"steps" json:"steps"`
+	CreatedAt time.Time     `db:"created_at" json:"created_at"`
+}
+
+type StepCondition string
+
+const (
+	// WhenOnSuccess runs a step when all the steps it needs succeeded.
+	WhenOnSuccess StepCondition = "on_success"
+	// WhenOnFailure runs a step when one of the steps it needs failed.
+	WhenOnFailure StepCondition = "on_failure"
+	// WhenAlways runs a step once the steps it needs have finished.
+	WhenAlways StepCondition = "always"
+)
+
+type PipelineStep struct {
+	Name  string `json:"name"`
+	Alias string `json:"alias"`
+	// Needs names the steps that must finish before this one, steps without
+	// needs start right away.
+	Needs   []string      `json:"needs,omitempty"`
+	When    StepCondition `json:"when,omitempty"`
+	Params  ParamInput    `json:"params,omitempty"`
+	Timeout int           `json:"timeout,omitempty"`
+}
+
+type PipelineSteps []PipelineStep
+
+func (s PipelineSteps) Value() (driver.Value, error) {
+	return jsonValue(s)
+}
+
+func (s *PipelineSteps) Scan(src any) error {
+	return jsonScan(src, s)
+}
+
+type PipelineDto struct {
+	Name  string        `json:"name"`
+	Steps PipelineSteps `json:"steps"`
+}
+
+type RunPipelineDto struct {
+	Name string `json:"name"`
+}
+
+type PipelineStatus string
+
+const (
+	PipelineRunning   PipelineStatus = "running"
+	PipelineSucceeded PipelineStatus = "succeeded"
+	PipelineFailed    PipelineStatus = "failed"
+	// PipelineLost is set for runs interrupted by a server restart.
+	PipelineLost PipelineStatus = "lost"
+)
+
+type StepStatus string
+
+const (
+	StepPending   StepStatus = "pending"
+	StepRunning   StepStatus = "running"
+	StepSucceeded StepStatus = "succeeded"
+	StepFailed    StepStatus = "failed"
+	StepSkipped   StepStatus = "skipped"
+)
+
+type PipelineRun struct {
+	Id         int               `db:"id" json:"id"`
+	PipelineId int               `db:"pipeline_id" json:"pipeline_id"`
+	Status     PipelineStatus    `db:"status" json:"status"`
+	StartedAt  time.Time         `db:"started_at" json:"started_at"`
+	FinishedAt *time.Time        `db:"finished_at" json:"finished_at,omitempty"`
+	Steps      []PipelineRunStep `db:"-" json:"steps"`
+}
+
+type PipelineRunStep struct {
+	Id     int        `db:"id" json:"id"`
+	RunId  int        `db:"run_id" json:"run_id"`
+	Name   string     `db:"name" json:"name"`
+	Alias  string     `db:"alias" json:"alias"`
+	Status StepStatus `db:"status" json:"status"`
+	// ExecutedCommandId links the step to its execution and logs.
+	ExecutedCommandId *int       `db:"executed_command_id" json:"executed_command_id,omitempty"`
+	Error             string     `db:"error" json:"error,omitempty"`
+	StartedAt         *time.Time `db:"started_at" json:"started_at,omitempty"`
+	FinishedAt        *time.Time `db:"finished_at" json:"finished_at,omitempty"`
+}
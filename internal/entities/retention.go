@@ -0,0 +1,18 @@
+package entities
+
+import "time"
+
+// RetentionPolicy limits how much execution history is kept. Zero values
+// mean no limit.
+type RetentionPolicy struct {
+	MaxAge        time.Duration
+	MaxExecutions int
+	MaxLogBytes   int64
+}
+
+// LogCompaction is a finished execution whose logs haven't been checked
+// against the size limit yet. MaxLogBytes is the limit in effect for it.
+type LogCompaction struct {
+	ExecutedCommandId int   `db:"id"`
+	MaxLogBytes       int64 `db:"max_log_bytes"`
+}
@@ -0,0 +1,33 @@
+package entities
+
+import "time"
+
+type Schedule struct {
+	Id    int    `db:"id" json:"id"`
+	Alias string `db:"alias" json:"alias"`
+	// Cron is a standard five-field expression or a descriptor like @hourly.
+	Cron string `db:"cron" json:"cron"`
+	// Timezone is an IANA name the expression is evaluated in, UTC by default.
+	Timezone string     `db:"timezone" json:"timezone"`
+	Enabled  bool       `db:"enabled" json:"enabled"`
+	Params   ParamInput `db:"params" json:"params,omitempty"`
+	// SkipIfRunning skips a run while the previous one is still active.
+	SkipIfRunning bool `db:"skip_if_running" json:"skip_if_running"`
+	// CatchUp runs the command once on start if runs were missed while the
+	// server was down.
+	CatchUp         bool       `db:"catch_up" json:"catch_up"`
+	CreatedAt       time.Time  `db:"created_at" json:"created_at"`
+	LastRunAt       *time.Time `db:"last_run_at" json:"last_run_at,omitempty"`
+	LastExecutionId *int       `db:"last_execution_id" json:"last_execution_id,omitempty"`
+	NextRunAt       *time.Time `db:"-" json:"next_run_at,omitempty"`
+}
+
+type ScheduleDto struct {
+	Alias         string     `json:"alias"`
+	Cron          string     `json:"cron"`
+	Timezone      string     `json:"timezone"`
+	Enabled       *bool      `json:"enabled,omitempty"`
+	Params        ParamInput `json:"params,omitempty"`
+	SkipIfRunning bool       `json:"skip_if_running"`
+	CatchUp       bool       `json:"catch_up"`
+}
@@ -0,0 +1,39 @@
+package entities
+
+import (
+	"database/sql/driver"
+	"time"
+)
+
+// Secret is a named value kept encrypted in the database. The value is never
+// sent back to clients.
+type Secret struct {
+	Id   int    `db:"id" json:"id"`
+	Name string `db:"name" json:"name"`
+	// Value is the nonce followed by the sealed value.
+	Value     []byte    `db:"value" json:"-"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+}
+
+type SecretDto struct {
+	Value string `json:"value"`
+}
+
+// SecretRef makes a secret available to the script of a command in the
+// variable Env: as the value itself, or as the path of a file holding it.
+type SecretRef struct {
+	Name string `json:"name"`
+	Env  string `json:"env"`
+	File bool   `json:"file,omitempty"`
+}
+
+type SecretRefs []SecretRef
+
+func (r SecretRefs) Value() (driver.Value, error) {
+	return jsonValue(r)
+}
+
+func (r *SecretRefs) Scan(src any) error {
+	return jsonScan(src, r)
+}
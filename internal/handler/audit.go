@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"net/http"
+	sl "testex/pkg/slog"
+)
+
+// getAuditEvents lists audit events newest first, or exports all of them
+// oldest first as JSON Lines with format=jsonl.
+func (router Router) getAuditEvents(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		filter, err := parseAuditFilter(r)
+		if err != nil {
+			e := newError(err.Error(), http.StatusBadRequest)
+			http.Error(w, e.ToJson(), e.StatusCode)
+			return
+		}
+		switch r.URL.Query().Get("format") {
+		case "", "json":
+		case "jsonl":
+			w.Header().Set("Content-Type", "application/x-ndjson")
+			w.Header().Set("Content-Disposition", `attachment; filename="audit.jsonl"`)
+			// The status is sent with the first event, so a failure can only
+			// cut the export short.
+			if err = router.Service.ExportAuditEvents(filter, w); err != nil {
+				router.Logger.Error("failed to export audit events", sl.Err(err))
+			}
+			return
+		default:
+			e := newError("wrong format, expected json or jsonl", http.StatusBadRequest)
+			http.Error(w, e.ToJson(), e.StatusCode)
+			return
+		}
+		events, err := router.Service.GetAuditEvents(filter)
+		if err != nil {
+			router.serviceError(w, "audit event", "failed to get audit events", err)
+			return
+		}
+		sendJSONResponse(w, http.StatusOK, events)
+	case http.MethodOptions:
+		w.Header().Set("Allow", "GET, OPTIONS")
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		methodNotAllowed(w)
+	}
+}
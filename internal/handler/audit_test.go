@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testex/internal/entities"
+	"testex/internal/service"
+	mock_service "testex/internal/service/mocks"
+	"testex/pkg/slog/slogdiscard"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRouter_getAuditEvents(t *testing.T) {
+	type mockBehavior func(r *mock_service.MockAudit)
+
+	at := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name                 string
+		requestPath          string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedContentType  string
+		expectedResponseBody string
+	}{
+		{
+			name:        "Ok",
+			requestPath: "/audit?actor=ci&outcome=denied&before_id=10&limit=1",
+			mockBehavior: func(r *mock_service.MockAudit) {
+				r.EXPECT().GetAuditEvents(entities.AuditFilter{Actor: "ci", Outcome: entities.AuditDenied,
+					BeforeId: 10, Limit: 1}).
+					Return([]entities.AuditEvent{{Id: 9, At: at, Actor: "ci", Role: entities.RoleOperator,
+						Action: "command.stop", Target: "executions/7", Outcome: entities.AuditDenied,
+						Error: "forbidden"}}, nil)
+			},
+			expectedStatusCode:  http.StatusOK,
+			expectedContentType: "application/json",
+			expectedResponseBody: `[{"id":9,"at":"2024-05-01T10:00:00Z","actor":"ci","role":"operator",` +
+				`"action":"command.stop","target":"executions/7","outcome":"denied","error":"forbidden"}]`,
+		},
+		{
+			name:        "Export",
+			requestPath: "/audit?format=jsonl&target=commands/deploy",
+			mockBehavior: func(r *mock_service.MockAudit) {
+				r.EXPECT().ExportAuditEvents(entities.AuditFilter{Target: "commands/deploy"}, gomock.Any()).
+					DoAndReturn(func(_ entities.AuditFilter, w io.Writer) error {
+						_, err := io.WriteString(w, "{\"id\":1}\n{\"id\":2}\n")
+						return err
+					})
+			},
+			expectedStatusCode:   http.StatusOK,
+			expectedContentType:  "application/x-ndjson",
+			expectedResponseBody: "{\"id\":1}\n{\"id\":2}",
+		},
+		{
+			name:                 "WrongOutcome",
+			requestPath:          "/audit?outcome=maybe",
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedResponseBody: `{"message":"wrong outcome, expected success, denied or failure","status_code":400}`,
+		},
+		{
+			name:                 "WrongFormat",
+			requestPath:          "/audit?format=csv",
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedResponseBody: `{"message":"wrong format, expected json or jsonl","status_code":400}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			repo := mock_service.NewMockAudit(c)
+			if test.mockBehavior != nil {
+				test.mockBehavior(repo)
+			}
+
+			srv := &service.Service{Audit: repo}
+			mux := http.NewServeMux()
+			handler := &Router{Service: srv, Logger: slogdiscard.NewDiscardLogger(), Mux: mux}
+			mux.HandleFunc("/audit", handler.getAuditEvents)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, test.requestPath, nil)
+			mux.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatusCode, w.Code)
+			assert.Equal(t, test.expectedResponseBody, strings.TrimSpace(w.Body.String()))
+			if test.expectedContentType != "" {
+				assert.Equal(t, test.expectedContentType, w.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"testex/internal/entities"
+	sl "testex/pkg/slog"
+)
+
+// access are the roles a route requires to read and to change things.
+type access struct {
+	read  entities.Role
+	write entities.Role
+}
+
+var (
+	// manage lets viewers read and admins change commands, schedules and
+	// pipelines.
+	manage = access{read: entities.RoleViewer, write: entities.RoleAdmin}
+	// operate lets operators execute and stop commands.
+	operate = access{read: entities.RoleViewer, write: entities.RoleOperator}
+	// admin routes deal with secrets and keys.
+	admin = access{read: entities.RoleAdmin, write: entities.RoleAdmin}
+)
+
+func (a access) required(method string) entities.Role {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return a.read
+	default:
+		return a.write
+	}
+}
+
+// handle registers a handler that is reached only by clients with the access.
+func (router Router) handle(pattern string, a access, handler http.HandlerFunc) {
+	router.Mux.HandleFunc(pattern, router.authorize(a, handler))
+}
+
+// authorize authenticates the request and puts its principal into the
+// context.
+func (router Router) authorize(a access, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := entities.ContextWithSourceIp(r.Context(), sourceIp(r))
+		principal, err := router.Service.Authenticate(bearerToken(r))
+		if errors.Is(err, entities.ErrUnauthorized) {
+			router.Logger.Debug("request is not authenticated", sl.Err(err))
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			e := newError("unauthorized", http.StatusUnauthorized)
+			http.Error(w, e.ToJson(), e.StatusCode)
+			return
+		}
+		if err != nil {
+			e := newError("failed to authenticate", http.StatusInternalServerError)
+			http.Error(w, e.ToJson(), e.StatusCode)
+			router.Logger.Error(e.Message, sl.Err(err))
+			return
+		}
+		ctx = entities.ContextWithPrincipal(ctx, principal)
+		if required := a.required(r.Method); !principal.Role.Allows(required) {
+			if required != a.read {
+				// Attempts to change things are audited even when they don't
+				// reach the service.
+				router.Service.Record(ctx, "request."+strings.ToLower(r.Method), strings.TrimPrefix(r.URL.Path, "/"),
+					nil, fmt.Errorf("%w: %s role is required", entities.ErrForbidden, required))
+			}
+			e := newError("forbidden", http.StatusForbidden)
+			http.Error(w, e.ToJson(), e.StatusCode)
+			return
+		}
+		next(w, r.WithContext(ctx))
+	}
+}
+
+// sourceIp returns the address of the client, which is the proxy when the
+// server runs behind one.
+func sourceIp(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// bearerToken reads the token from the Authorization header. Browsers can't
+// set headers for event streams and websockets, so GET requests may pass it in
+// the access_token query parameter.
+func bearerToken(r *http.Request) string {
+	if header := r.Header.Get("Authorization"); header != "" {
+		scheme, token, ok := strings.Cut(header, " ")
+		if !ok || !strings.EqualFold(scheme, "Bearer") {
+			return ""
+		}
+		return strings.TrimSpace(token)
+	}
+	if r.Method == http.MethodGet {
+		return r.URL.Query().Get("access_token")
+	}
+	return ""
+}
+
+func (router Router) apiKeys(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		keys, err := router.Service.GetApiKeys()
+		if err != nil {
+			router.serviceError(w, "key", "failed to get keys", err)
+			return
+		}
+		sendJSONResponse(w, http.StatusOK, keys)
+	case http.MethodPost:
+		var keyDto entities.ApiKeyDto
+		if err := json.NewDecoder(r.Body).Decode(&keyDto); err != nil {
+			e := newError("failed to parse request body", http.StatusBadRequest)
+			http.Error(w, e.ToJson(), e.StatusCode)
+			router.Logger.Error(e.Message, sl.Err(err))
+			return
+		}
+		defer r.Body.Close()
+		key, err := router.Service.CreateApiKey(r.Context(), keyDto)
+		if err != nil {
+			router.serviceError(w, "key", "failed to create key", err)
+			return
+		}
+		sendJSONResponse(w, http.StatusCreated, key)
+	case http.MethodOptions:
+		w.Header().Set("Allow", "GET, POST, OPTIONS")
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		methodNotAllowed(w)
+	}
+}
+
+func (router Router) apiKey(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodDelete:
+		id, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil {
+			e := newError("wrong id format", http.StatusBadRequest)
+			http.Error(w, e.ToJson(), e.StatusCode)
+			return
+		}
+		if err = router.Service.DeleteApiKey(r.Context(), id); err != nil {
+			router.serviceError(w, "key", "failed to delete key", err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodOptions:
+		w.Header().Set("Allow", "DELETE, OPTIONS")
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		methodNotAllowed(w)
+	}
+}
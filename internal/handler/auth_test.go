@@ -0,0 +1,233 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testex/internal/entities"
+	"testex/internal/service"
+	mock_service "testex/internal/service/mocks"
+	"testex/pkg/slog/slogdiscard"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRouter_authorize(t *testing.T) {
+	type mockBehavior func(r *mock_service.MockAuth, a *mock_service.MockAudit)
+
+	tests := []struct {
+		name                 string
+		requestMethod        string
+		requestPath          string
+		header               string
+		access               access
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:          "Ok",
+			requestMethod: http.MethodPost,
+			requestPath:   "/test",
+			header:        "Bearer tx_key",
+			access:        operate,
+			mockBehavior: func(r *mock_service.MockAuth, a *mock_service.MockAudit) {
+				r.EXPECT().Authenticate("tx_key").
+					Return(entities.Principal{Name: "ci", Role: entities.RoleOperator}, nil)
+			},
+			expectedStatusCode:   http.StatusOK,
+			expectedResponseBody: "ci",
+		},
+		{
+			name:          "QueryToken",
+			requestMethod: http.MethodGet,
+			requestPath:   "/test?access_token=tx_key",
+			access:        manage,
+			mockBehavior: func(r *mock_service.MockAuth, a *mock_service.MockAudit) {
+				r.EXPECT().Authenticate("tx_key").
+					Return(entities.Principal{Name: "ci", Role: entities.RoleViewer}, nil)
+			},
+			expectedStatusCode:   http.StatusOK,
+			expectedResponseBody: "ci",
+		},
+		{
+			name:          "NoToken",
+			requestMethod: http.MethodGet,
+			requestPath:   "/test",
+			access:        manage,
+			mockBehavior: func(r *mock_service.MockAuth, a *mock_service.MockAudit) {
+				r.EXPECT().Authenticate("").
+					Return(entities.Principal{}, fmt.Errorf("%w: no token", entities.ErrUnauthorized))
+			},
+			expectedStatusCode:   http.StatusUnauthorized,
+			expectedResponseBody: `{"message":"unauthorized","status_code":401}`,
+		},
+		{
+			name:          "WrongScheme",
+			requestMethod: http.MethodGet,
+			requestPath:   "/test",
+			header:        "Basic dXNlcjpwYXNz",
+			access:        manage,
+			mockBehavior: func(r *mock_service.MockAuth, a *mock_service.MockAudit) {
+				r.EXPECT().Authenticate("").
+					Return(entities.Principal{}, fmt.Errorf("%w: no token", entities.ErrUnauthorized))
+			},
+			expectedStatusCode:   http.StatusUnauthorized,
+			expectedResponseBody: `{"message":"unauthorized","status_code":401}`,
+		},
+		{
+			name:          "Forbidden",
+			requestMethod: http.MethodPost,
+			requestPath:   "/test",
+			header:        "Bearer tx_key",
+			access:        operate,
+			mockBehavior: func(r *mock_service.MockAuth, a *mock_service.MockAudit) {
+				r.EXPECT().Authenticate("tx_key").
+					Return(entities.Principal{Name: "dashboard", Role: entities.RoleViewer}, nil)
+				a.EXPECT().Record(gomock.Any(), "request.post", "test", nil, gomock.Any()).
+					Do(func(ctx context.Context, _, _ string, _ any, err error) {
+						principal, _ := entities.PrincipalFromContext(ctx)
+						assert.Equal(t, "dashboard", principal.Name)
+						assert.Equal(t, "192.0.2.1", entities.SourceIpFromContext(ctx))
+						assert.ErrorIs(t, err, entities.ErrForbidden)
+					})
+			},
+			expectedStatusCode:   http.StatusForbidden,
+			expectedResponseBody: `{"message":"forbidden","status_code":403}`,
+		},
+		{
+			name:          "AdminOnlyRead",
+			requestMethod: http.MethodGet,
+			requestPath:   "/test",
+			header:        "Bearer tx_key",
+			access:        admin,
+			mockBehavior: func(r *mock_service.MockAuth, a *mock_service.MockAudit) {
+				r.EXPECT().Authenticate("tx_key").
+					Return(entities.Principal{Name: "ci", Role: entities.RoleOperator}, nil)
+			},
+			expectedStatusCode:   http.StatusForbidden,
+			expectedResponseBody: `{"message":"forbidden","status_code":403}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			repo := mock_service.NewMockAuth(c)
+			auditor := mock_service.NewMockAudit(c)
+			test.mockBehavior(repo, auditor)
+
+			srv := &service.Service{Auth: repo, Audit: auditor}
+			handler := &Router{Service: srv, Logger: slogdiscard.NewDiscardLogger(), Mux: http.NewServeMux()}
+			handler.handle("/test", test.access, func(w http.ResponseWriter, r *http.Request) {
+				principal, _ := entities.PrincipalFromContext(r.Context())
+				_, _ = w.Write([]byte(principal.Name))
+			})
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(test.requestMethod, test.requestPath, nil)
+			if test.header != "" {
+				req.Header.Set("Authorization", test.header)
+			}
+			handler.Mux.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatusCode, w.Code)
+			assert.Equal(t, test.expectedResponseBody, strings.TrimSpace(w.Body.String()))
+			if test.expectedStatusCode == http.StatusUnauthorized {
+				assert.Equal(t, "Bearer", w.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
+
+func TestRouter_apiKeys(t *testing.T) {
+	type mockBehavior func(r *mock_service.MockAuth)
+
+	tests := []struct {
+		name                 string
+		requestMethod        string
+		requestPath          string
+		requestBody          string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:          "Create",
+			requestMethod: http.MethodPost,
+			requestPath:   "/auth/keys",
+			requestBody:   `{"name": "ci", "role": "operator"}`,
+			mockBehavior: func(r *mock_service.MockAuth) {
+				r.EXPECT().CreateApiKey(gomock.Any(), entities.ApiKeyDto{Name: "ci", Role: entities.RoleOperator}).
+					Return(entities.CreatedApiKey{
+						ApiKey: entities.ApiKey{Id: 1, Name: "ci", Role: entities.RoleOperator, Prefix: "tx_abcdefgh",
+							Hash: "hash"},
+						Key: "tx_abcdefghijk",
+					}, nil)
+			},
+			expectedStatusCode: http.StatusCreated,
+			expectedResponseBody: `{"id":1,"name":"ci","role":"operator","prefix":"tx_abcdefgh",` +
+				`"created_at":"0001-01-01T00:00:00Z","key":"tx_abcdefghijk"}`,
+		},
+		{
+			name:          "Create_Invalid",
+			requestMethod: http.MethodPost,
+			requestPath:   "/auth/keys",
+			requestBody:   `{"name": "ci", "role": "root"}`,
+			mockBehavior: func(r *mock_service.MockAuth) {
+				r.EXPECT().CreateApiKey(gomock.Any(), entities.ApiKeyDto{Name: "ci", Role: "root"}).
+					Return(entities.CreatedApiKey{}, fmt.Errorf("%w: unknown role \"root\"", entities.ErrInvalidParams))
+			},
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedResponseBody: `{"message":"invalid command params: unknown role \"root\"","status_code":400}`,
+		},
+		{
+			name:          "Delete",
+			requestMethod: http.MethodDelete,
+			requestPath:   "/auth/keys/1",
+			mockBehavior: func(r *mock_service.MockAuth) {
+				r.EXPECT().DeleteApiKey(gomock.Any(), 1).Return(nil)
+			},
+			expectedStatusCode: http.StatusNoContent,
+		},
+		{
+			name:                 "Delete_WrongId",
+			requestMethod:        http.MethodDelete,
+			requestPath:          "/auth/keys/one",
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedResponseBody: `{"message":"wrong id format","status_code":400}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			repo := mock_service.NewMockAuth(c)
+			if test.mockBehavior != nil {
+				test.mockBehavior(repo)
+			}
+
+			srv := &service.Service{Auth: repo}
+			mux := http.NewServeMux()
+			handler := &Router{Service: srv, Logger: slogdiscard.NewDiscardLogger(), Mux: mux}
+			mux.HandleFunc("/auth/keys", handler.apiKeys)
+			mux.HandleFunc("/auth/keys/{id}", handler.apiKey)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(test.requestMethod, test.requestPath, bytes.NewBufferString(test.requestBody))
+			mux.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatusCode, w.Code)
+			assert.Equal(t, test.expectedResponseBody, strings.TrimSpace(w.Body.String()))
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package handler
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"testex/internal/entities"
+	sl "testex/pkg/slog"
+)
+
+func (router Router) getExecutions(w http.ResponseWriter, r *http.Request) {
+	router.listExecutions(w, r, func(filter entities.ExecutionFilter, cursor string) (entities.ExecutionPage, error) {
+		return router.Service.GetExecutions(r.Context(), filter, cursor)
+	})
+}
+
+func (router Router) getCommandExecutions(w http.ResponseWriter, r *http.Request) {
+	alias := r.PathValue("alias")
+	router.listExecutions(w, r, func(filter entities.ExecutionFilter, cursor string) (entities.ExecutionPage, error) {
+		return router.Service.GetCommandExecutions(r.Context(), alias, filter, cursor)
+	})
+}
+
+func (router Router) listExecutions(w http.ResponseWriter, r *http.Request,
+	list func(filter entities.ExecutionFilter, cursor string) (entities.ExecutionPage, error)) {
+	switch r.Method {
+	case http.MethodGet:
+		filter, err := parseExecutionFilter(r)
+		if err != nil {
+			e := newError(err.Error(), http.StatusBadRequest)
+			http.Error(w, e.ToJson(), e.StatusCode)
+			return
+		}
+		page, err := list(filter, r.URL.Query().Get("cursor"))
+		if err != nil {
+			router.serviceError(w, "command", "failed to get executions", err)
+			return
+		}
+		sendJSONResponse(w, http.StatusOK, page)
+	case http.MethodOptions:
+		w.Header().Set("Allow", "GET, OPTIONS")
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		methodNotAllowed(w)
+	}
+}
+
+func (router Router) getExecution(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		id, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil {
+			e := newError("wrong id format", http.StatusBadRequest)
+			http.Error(w, e.ToJson(), e.StatusCode)
+			router.Logger.Error(e.Message, sl.Err(err))
+			return
+		}
+		execution, err := router.Service.GetExecution(r.Context(), id)
+		if err != nil {
+			router.serviceError(w, "execution", "failed to get execution", err)
+			return
+		}
+		sendJSONResponse(w, http.StatusOK, execution)
+	case http.MethodOptions:
+		w.Header().Set("Allow", "GET, OPTIONS")
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		methodNotAllowed(w)
+	}
+}
+
+// getExecutionOutput sends the raw output of an execution as a file. Range
+// requests are served as is, the whole file is gzipped if the client accepts
+// it.
+func (router Router) getExecutionOutput(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		id, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil {
+			e := newError("wrong id format", http.StatusBadRequest)
+			http.Error(w, e.ToJson(), e.StatusCode)
+			router.Logger.Error(e.Message, sl.Err(err))
+			return
+		}
+		stream := entities.LogStream(r.URL.Query().Get("stream"))
+		switch stream {
+		case "":
+			stream = entities.StreamStdout
+		case entities.StreamStdout, entities.StreamStderr:
+		default:
+			e := newError("wrong stream, expected stdout or stderr", http.StatusBadRequest)
+			http.Error(w, e.ToJson(), e.StatusCode)
+			return
+		}
+		output, blob, err := router.Service.OpenOutput(r.Context(), id, stream)
+		if err != nil {
+			router.serviceError(w, "output", "failed to get output", err)
+			return
+		}
+		defer blob.Close()
+
+		name := fmt.Sprintf("execution-%d-%s", id, stream)
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, name))
+		w.Header().Add("Vary", "Accept-Encoding")
+		if r.Header.Get("Range") != "" || !acceptsGzip(r) {
+			w.Header().Set("ETag", `"`+output.BlobKey+`"`)
+			http.ServeContent(w, r, name, output.CreatedAt, blob)
+			return
+		}
+		// The compressed body differs from the blob, so it gets its own validator.
+		w.Header().Set("ETag", `"`+output.BlobKey+`-gzip"`)
+		w.Header().Set("Content-Encoding", "gzip")
+		w.WriteHeader(http.StatusOK)
+		if r.Method == http.MethodHead {
+			return
+		}
+		gz := gzip.NewWriter(w)
+		if _, err = io.Copy(gz, blob); err == nil {
+			err = gz.Close()
+		}
+		if err != nil {
+			router.Logger.Error("failed to send output", slog.Int("id", id), sl.Err(err))
+		}
+	case http.MethodOptions:
+		w.Header().Set("Allow", "GET, HEAD, OPTIONS")
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		methodNotAllowed(w)
+	}
+}
+
+func (router Router) getArtifacts(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		id, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil {
+			e := newError("wrong id format", http.StatusBadRequest)
+			http.Error(w, e.ToJson(), e.StatusCode)
+			router.Logger.Error(e.Message, sl.Err(err))
+			return
+		}
+		artifacts, err := router.Service.GetArtifacts(r.Context(), id)
+		if err != nil {
+			router.serviceError(w, "execution", "failed to get artifacts", err)
+			return
+		}
+		sendJSONResponse(w, http.StatusOK, artifacts)
+	case http.MethodOptions:
+		w.Header().Set("Allow", "GET, OPTIONS")
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		methodNotAllowed(w)
+	}
+}
+
+// getArtifact sends a file collected after an execution, the path in the URL
+// is the path of the file relative to the working directory.
+func (router Router) getArtifact(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		id, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil {
+			e := newError("wrong id format", http.StatusBadRequest)
+			http.Error(w, e.ToJson(), e.StatusCode)
+			router.Logger.Error(e.Message, sl.Err(err))
+			return
+		}
+		artifact, blob, err := router.Service.OpenArtifact(r.Context(), id, r.PathValue("path"))
+		if err != nil {
+			router.serviceError(w, "artifact", "failed to get artifact", err)
+			return
+		}
+		defer blob.Close()
+
+		name := path.Base(artifact.Path)
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name}))
+		w.Header().Set("ETag", `"`+artifact.BlobKey+`"`)
+		http.ServeContent(w, r, name, artifact.CreatedAt, blob)
+	case http.MethodOptions:
+		w.Header().Set("Allow", "GET, HEAD, OPTIONS")
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		methodNotAllowed(w)
+	}
+}
@@ -0,0 +1,319 @@
+package handler
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testex/internal/entities"
+	"testex/internal/service"
+	mock_service "testex/internal/service/mocks"
+	"testex/pkg/slog/slogdiscard"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRouter_getExecutions(t *testing.T) {
+	type mockBehavior func(r *mock_service.MockCommand)
+
+	tests := []struct {
+		name                 string
+		requestPath          string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:        "Filtered",
+			requestPath: "/executions?alias=build&status=failed&triggered_by=schedule&from=2024-05-01T00:00:00Z&limit=2&order=asc&cursor=abc",
+			mockBehavior: func(r *mock_service.MockCommand) {
+				r.EXPECT().GetExecutions(gomock.Any(), entities.ExecutionFilter{
+					Alias:       "build",
+					Status:      entities.StatusFailed,
+					TriggeredBy: entities.TriggeredBySchedule,
+					From:        time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+					Limit:       2,
+					Ascending:   true,
+				}, "abc").Return(entities.ExecutionPage{
+					Executions: []entities.ExecutedCommand{{Id: 7, CommandId: 1, Alias: "build", Status: entities.StatusFailed}},
+					NextCursor: "def",
+				}, nil)
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedResponseBody: `{"executions":[{"id":7,"command_id":1,"pid":0,"is_active":false,"status":"failed",` +
+				`"started_at":"0001-01-01T00:00:00Z","alias":"build"}],"next_cursor":"def"}`,
+		},
+		{
+			name:                 "WrongStatus",
+			requestPath:          "/executions?status=done",
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedResponseBody: `{"message":"wrong status","status_code":400}`,
+		},
+		{
+			name:                 "WrongFrom",
+			requestPath:          "/executions?from=yesterday",
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedResponseBody: `{"message":"wrong from format, expected RFC 3339","status_code":400}`,
+		},
+		{
+			name:                 "WrongLimit",
+			requestPath:          "/executions?limit=5000",
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedResponseBody: `{"message":"wrong limit, expected 1 to 1000","status_code":400}`,
+		},
+		{
+			name:        "WrongCursor",
+			requestPath: "/executions?cursor=zzz",
+			mockBehavior: func(r *mock_service.MockCommand) {
+				r.EXPECT().GetExecutions(gomock.Any(), entities.ExecutionFilter{}, "zzz").
+					Return(entities.ExecutionPage{}, entities.ErrInvalidParams)
+			},
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedResponseBody: `{"message":"invalid command params","status_code":400}`,
+		},
+		{
+			name:        "ByCommand",
+			requestPath: "/commands/build/executions?status=succeeded&to=2024-05-02T00:00:00Z&cursor=abc",
+			mockBehavior: func(r *mock_service.MockCommand) {
+				r.EXPECT().GetCommandExecutions(gomock.Any(), "build", entities.ExecutionFilter{
+					Status: entities.StatusSucceeded,
+					To:     time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC),
+				}, "abc").Return(entities.ExecutionPage{}, nil)
+			},
+			expectedStatusCode:   http.StatusOK,
+			expectedResponseBody: `{"executions":null}`,
+		},
+		{
+			name:        "ByCommand_NotFound",
+			requestPath: "/commands/missing/executions",
+			mockBehavior: func(r *mock_service.MockCommand) {
+				r.EXPECT().GetCommandExecutions(gomock.Any(), "missing", entities.ExecutionFilter{}, "").
+					Return(entities.ExecutionPage{}, entities.ErrNotFound)
+			},
+			expectedStatusCode:   http.StatusNotFound,
+			expectedResponseBody: `{"message":"command not found","status_code":404}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			repo := mock_service.NewMockCommand(c)
+			if test.mockBehavior != nil {
+				test.mockBehavior(repo)
+			}
+			auth := mock_service.NewMockAuth(c)
+			auth.EXPECT().Authenticate("tx_key").
+				Return(entities.Principal{Name: "dashboard", Role: entities.RoleViewer}, nil)
+
+			// The history routes share the /commands/{alias} prefix with other
+			// routes, so they go through the real router.
+			srv := &service.Service{Command: repo, Auth: auth}
+			router := New(srv, slogdiscard.NewDiscardLogger(), nil)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, test.requestPath, nil)
+			req.Header.Set("Authorization", "Bearer tx_key")
+			router.Mux.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatusCode, w.Code)
+			assert.Equal(t, test.expectedResponseBody, strings.TrimSpace(w.Body.String()))
+		})
+	}
+}
+
+type nopSeekCloser struct {
+	*strings.Reader
+}
+
+func (nopSeekCloser) Close() error { return nil }
+
+func TestRouter_getExecutionOutput(t *testing.T) {
+	const content = "id,name\n1,build\n2,deploy\n"
+	output := entities.ExecutionOutput{ExecutedCommandId: 7, Stream: entities.StreamStdout, BlobKey: "abc",
+		Size: int64(len(content))}
+
+	tests := []struct {
+		name                 string
+		requestQuery         string
+		requestHeaders       map[string]string
+		mockBehavior         func(r *mock_service.MockCommand)
+		expectedStatusCode   int
+		expectedHeaders      map[string]string
+		expectedResponseBody string
+	}{
+		{
+			name: "Whole",
+			mockBehavior: func(r *mock_service.MockCommand) {
+				r.EXPECT().OpenOutput(gomock.Any(), 7, entities.StreamStdout).
+					Return(output, nopSeekCloser{strings.NewReader(content)}, nil)
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedHeaders: map[string]string{
+				"Content-Type":        "application/octet-stream",
+				"Content-Disposition": `attachment; filename="execution-7-stdout"`,
+				"ETag":                `"abc"`,
+				"Vary":                "Accept-Encoding",
+			},
+			expectedResponseBody: content,
+		},
+		{
+			name:           "Range",
+			requestHeaders: map[string]string{"Range": "bytes=8-15", "Accept-Encoding": "gzip"},
+			mockBehavior: func(r *mock_service.MockCommand) {
+				r.EXPECT().OpenOutput(gomock.Any(), 7, entities.StreamStdout).
+					Return(output, nopSeekCloser{strings.NewReader(content)}, nil)
+			},
+			expectedStatusCode:   http.StatusPartialContent,
+			expectedHeaders:      map[string]string{"Content-Range": "bytes 8-15/25"},
+			expectedResponseBody: "1,build\n",
+		},
+		{
+			name:           "Gzip",
+			requestHeaders: map[string]string{"Accept-Encoding": "gzip, deflate"},
+			mockBehavior: func(r *mock_service.MockCommand) {
+				r.EXPECT().OpenOutput(gomock.Any(), 7, entities.StreamStdout).
+					Return(output, nopSeekCloser{strings.NewReader(content)}, nil)
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedHeaders: map[string]string{
+				"Content-Encoding": "gzip",
+				"ETag":             `"abc-gzip"`,
+				"Vary":             "Accept-Encoding",
+			},
+			expectedResponseBody: content,
+		},
+		{
+			name:         "NotFound",
+			requestQuery: "?stream=stderr",
+			mockBehavior: func(r *mock_service.MockCommand) {
+				r.EXPECT().OpenOutput(gomock.Any(), 7, entities.StreamStderr).Return(entities.ExecutionOutput{}, nil, entities.ErrNotFound)
+			},
+			expectedStatusCode:   http.StatusNotFound,
+			expectedResponseBody: `{"message":"output not found","status_code":404}` + "\n",
+		},
+		{
+			name:                 "WrongStream",
+			requestQuery:         "?stream=system",
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedResponseBody: `{"message":"wrong stream, expected stdout or stderr","status_code":400}` + "\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			repo := mock_service.NewMockCommand(c)
+			if test.mockBehavior != nil {
+				test.mockBehavior(repo)
+			}
+
+			srv := &service.Service{Command: repo}
+			mux := http.NewServeMux()
+			handler := &Router{Service: srv, Logger: slogdiscard.NewDiscardLogger(), Mux: mux}
+			mux.HandleFunc("/executions/{id}/output", handler.getExecutionOutput)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/executions/7/output"+test.requestQuery, nil)
+			for key, value := range test.requestHeaders {
+				req.Header.Set(key, value)
+			}
+			mux.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatusCode, w.Code)
+			for key, value := range test.expectedHeaders {
+				assert.Equal(t, value, w.Header().Get(key), key)
+			}
+			body := w.Body.String()
+			if w.Header().Get("Content-Encoding") == "gzip" {
+				gz, err := gzip.NewReader(w.Body)
+				assert.NoError(t, err)
+				data, err := io.ReadAll(gz)
+				assert.NoError(t, err)
+				body = string(data)
+			}
+			assert.Equal(t, test.expectedResponseBody, body)
+		})
+	}
+}
+
+func TestRouter_getArtifact(t *testing.T) {
+	const content = "<html>coverage</html>"
+	artifact := entities.Artifact{ExecutedCommandId: 7, Path: "coverage/report.html", BlobKey: "abc",
+		Size: int64(len(content))}
+
+	tests := []struct {
+		name                 string
+		requestPath          string
+		mockBehavior         func(r *mock_service.MockCommand)
+		expectedStatusCode   int
+		expectedHeaders      map[string]string
+		expectedResponseBody string
+	}{
+		{
+			name:        "OK",
+			requestPath: "/executions/7/artifacts/coverage/report.html",
+			mockBehavior: func(r *mock_service.MockCommand) {
+				r.EXPECT().OpenArtifact(gomock.Any(), 7, "coverage/report.html").
+					Return(artifact, nopSeekCloser{strings.NewReader(content)}, nil)
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedHeaders: map[string]string{
+				"Content-Type":        "text/html; charset=utf-8",
+				"Content-Disposition": `attachment; filename=report.html`,
+				"ETag":                `"abc"`,
+			},
+			expectedResponseBody: content,
+		},
+		{
+			name:        "NotFound",
+			requestPath: "/executions/7/artifacts/missing.txt",
+			mockBehavior: func(r *mock_service.MockCommand) {
+				r.EXPECT().OpenArtifact(gomock.Any(), 7, "missing.txt").Return(entities.Artifact{}, nil, entities.ErrNotFound)
+			},
+			expectedStatusCode:   http.StatusNotFound,
+			expectedResponseBody: `{"message":"artifact not found","status_code":404}` + "\n",
+		},
+		{
+			name:                 "WrongId",
+			requestPath:          "/executions/x/artifacts/report.html",
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedResponseBody: `{"message":"wrong id format","status_code":400}` + "\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			repo := mock_service.NewMockCommand(c)
+			if test.mockBehavior != nil {
+				test.mockBehavior(repo)
+			}
+
+			srv := &service.Service{Command: repo}
+			mux := http.NewServeMux()
+			handler := &Router{Service: srv, Logger: slogdiscard.NewDiscardLogger(), Mux: mux}
+			mux.HandleFunc("/executions/{id}/artifacts/{path...}", handler.getArtifact)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, test.requestPath, nil)
+			mux.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatusCode, w.Code)
+			for key, value := range test.expectedHeaders {
+				assert.Equal(t, value, w.Header().Get(key), key)
+			}
+			assert.Equal(t, test.expectedResponseBody, w.Body.String())
+		})
+	}
+}
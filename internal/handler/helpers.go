@@ -2,7 +2,22 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testex/internal/entities"
+	sl "testex/pkg/slog"
+	"time"
+)
+
+const (
+	maxLogsLimit       = 10000
+	maxExecutionsLimit = 1000
+	maxSearchLimit     = 1000
+	maxAuditLimit      = 1000
 )
 
 func sendJSONResponse(w http.ResponseWriter, statusCode int, response interface{}) {
@@ -12,8 +27,218 @@ func sendJSONResponse(w http.ResponseWriter, statusCode int, response interface{
 	w.Write(data)
 }
 
+// serviceError maps errors of the service layer to responses: missing
+// entities to 404, invalid input to 400, conflicts to 409 and 412, and the
+// rest to 500 with message.
+func (router Router) serviceError(w http.ResponseWriter, entity string, message string, err error) {
+	var e Error
+	switch {
+	case errors.Is(err, entities.ErrNotFound):
+		e = newError(entity+" not found", http.StatusNotFound)
+	case errors.Is(err, entities.ErrInvalidParams):
+		e = newError(err.Error(), http.StatusBadRequest)
+	case errors.Is(err, entities.ErrConflict):
+		e = newError(err.Error(), http.StatusConflict)
+	case errors.Is(err, entities.ErrVersionMismatch):
+		e = newError(err.Error(), http.StatusPreconditionFailed)
+	case errors.Is(err, entities.ErrForbidden):
+		e = newError(err.Error(), http.StatusForbidden)
+	default:
+		e = newError(message, http.StatusInternalServerError)
+		router.Logger.Error(e.Message, sl.Err(err))
+	}
+	http.Error(w, e.ToJson(), e.StatusCode)
+}
+
 func methodNotAllowed(w http.ResponseWriter) {
 	w.Header().Set("Allow", "GET, POST, OPTIONS")
 	e := newError("method not allowed", http.StatusMethodNotAllowed)
 	http.Error(w, e.ToJson(), e.StatusCode)
 }
+
+// parseLogFilter reads the stream, after_seq and limit query parameters.
+func parseLogFilter(r *http.Request) (entities.LogFilter, error) {
+	var filter entities.LogFilter
+	query := r.URL.Query()
+
+	switch stream := entities.LogStream(query.Get("stream")); stream {
+	case "", entities.StreamStdout, entities.StreamStderr, entities.StreamSystem:
+		filter.Stream = stream
+	default:
+		return filter, errors.New("wrong stream, expected stdout, stderr or system")
+	}
+	if value := query.Get("after_seq"); value != "" {
+		afterSeq, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || afterSeq < 0 {
+			return filter, errors.New("wrong after_seq format")
+		}
+		filter.AfterSeq = afterSeq
+	}
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit <= 0 || limit > maxLogsLimit {
+			return filter, errors.New("wrong limit, expected a number from 1 to 10000")
+		}
+		filter.Limit = limit
+	}
+	return filter, nil
+}
+
+func etag(version int) string {
+	return fmt.Sprintf(`"%d"`, version)
+}
+
+// ifMatch reads the version an edit is based on from the If-Match header,
+// zero means any version.
+func ifMatch(r *http.Request) (int, error) {
+	value := strings.TrimPrefix(r.Header.Get("If-Match"), "W/")
+	if value == "" || value == "*" {
+		return 0, nil
+	}
+	return strconv.Atoi(strings.Trim(value, `"`))
+}
+
+// parseExecutionFilter reads the alias, status, triggered_by, from, to, limit
+// and order query parameters.
+func parseExecutionFilter(r *http.Request) (entities.ExecutionFilter, error) {
+	var filter entities.ExecutionFilter
+	query := r.URL.Query()
+
+	filter.Alias = query.Get("alias")
+	status, err := parseStatus(query)
+	if err != nil {
+		return filter, err
+	}
+	filter.Status = status
+	switch source := entities.TriggerSource(query.Get("triggered_by")); source {
+	case "", entities.TriggeredByApi, entities.TriggeredBySchedule, entities.TriggeredByPipeline:
+		filter.TriggeredBy = source
+	default:
+		return filter, errors.New("wrong triggered_by, expected api, schedule or pipeline")
+	}
+	if filter.From, filter.To, err = parseTimeRange(query); err != nil {
+		return filter, err
+	}
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxExecutionsLimit {
+			return filter, fmt.Errorf("wrong limit, expected 1 to %d", maxExecutionsLimit)
+		}
+		filter.Limit = limit
+	}
+	switch query.Get("order") {
+	case "", "desc":
+	case "asc":
+		filter.Ascending = true
+	default:
+		return filter, errors.New("wrong order, expected asc or desc")
+	}
+	return filter, nil
+}
+
+// parseLogSearch reads the q, alias, stream, status, from, to and limit query
+// parameters.
+func parseLogSearch(r *http.Request) (entities.LogSearch, error) {
+	var search entities.LogSearch
+	query := r.URL.Query()
+
+	search.Query = strings.TrimSpace(query.Get("q"))
+	if search.Query == "" {
+		return search, errors.New("missing search query q")
+	}
+	search.Alias = query.Get("alias")
+	switch stream := entities.LogStream(query.Get("stream")); stream {
+	case "", entities.StreamStdout, entities.StreamStderr, entities.StreamSystem:
+		search.Stream = stream
+	default:
+		return search, errors.New("wrong stream, expected stdout, stderr or system")
+	}
+	status, err := parseStatus(query)
+	if err != nil {
+		return search, err
+	}
+	search.Status = status
+	if search.From, search.To, err = parseTimeRange(query); err != nil {
+		return search, err
+	}
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxSearchLimit {
+			return search, fmt.Errorf("wrong limit, expected 1 to %d", maxSearchLimit)
+		}
+		search.Limit = limit
+	}
+	return search, nil
+}
+
+// parseAuditFilter reads the actor, action, target, outcome, from, to,
+// before_id and limit query parameters.
+func parseAuditFilter(r *http.Request) (entities.AuditFilter, error) {
+	var filter entities.AuditFilter
+	query := r.URL.Query()
+
+	filter.Actor = query.Get("actor")
+	filter.Action = query.Get("action")
+	filter.Target = query.Get("target")
+	switch outcome := entities.AuditOutcome(query.Get("outcome")); outcome {
+	case "", entities.AuditSuccess, entities.AuditDenied, entities.AuditFailure:
+		filter.Outcome = outcome
+	default:
+		return filter, errors.New("wrong outcome, expected success, denied or failure")
+	}
+	var err error
+	if filter.From, filter.To, err = parseTimeRange(query); err != nil {
+		return filter, err
+	}
+	if value := query.Get("before_id"); value != "" {
+		id, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || id < 1 {
+			return filter, errors.New("wrong before_id")
+		}
+		filter.BeforeId = id
+	}
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxAuditLimit {
+			return filter, fmt.Errorf("wrong limit, expected 1 to %d", maxAuditLimit)
+		}
+		filter.Limit = limit
+	}
+	return filter, nil
+}
+
+// parseStatus reads the status query parameter.
+func parseStatus(query url.Values) (entities.ExecutionStatus, error) {
+	switch status := entities.ExecutionStatus(query.Get("status")); status {
+	case "", entities.StatusQueued, entities.StatusRunning, entities.StatusSucceeded, entities.StatusFailed,
+		entities.StatusStopped, entities.StatusTimedOut, entities.StatusLost:
+		return status, nil
+	default:
+		return "", errors.New("wrong status")
+	}
+}
+
+// parseTimeRange reads the RFC 3339 from and to query parameters. A missing
+// bound stays zero.
+func parseTimeRange(query url.Values) (from, to time.Time, err error) {
+	for name, dst := range map[string]*time.Time{"from": &from, "to": &to} {
+		if value := query.Get(name); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return time.Time{}, time.Time{}, fmt.Errorf("wrong %s format, expected RFC 3339", name)
+			}
+			*dst = t
+		}
+	}
+	return from, to, nil
+}
+
+func acceptsGzip(r *http.Request) bool {
+	for _, value := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(value), ";")
+		if strings.EqualFold(strings.TrimSpace(coding), "gzip") && strings.ReplaceAll(params, " ", "") != "q=0" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testex/internal/entities"
+	sl "testex/pkg/slog"
+)
+
+func (router Router) pipelines(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		pipelines, err := router.Service.GetPipelines(r.Context())
+		if err != nil {
+			e := newError("failed to get pipelines", http.StatusInternalServerError)
+			http.Error(w, e.ToJson(), e.StatusCode)
+			router.Logger.Error(e.Message, sl.Err(err))
+			return
+		}
+		sendJSONResponse(w, http.StatusOK, pipelines)
+	case http.MethodPost:
+		var pipelineDto entities.PipelineDto
+		if err := json.NewDecoder(r.Body).Decode(&pipelineDto); err != nil {
+			e := newError("failed to parse request body", http.StatusBadRequest)
+			http.Error(w, e.ToJson(), e.StatusCode)
+			router.Logger.Error(e.Message, sl.Err(err))
+			return
+		}
+		defer r.Body.Close()
+		id, err := router.Service.CreatePipeline(r.Context(), pipelineDto)
+		if err != nil {
+			router.serviceError(w, "pipeline", "failed to add new pipeline", err)
+			return
+		}
+		sendJSONResponse(w, http.StatusCreated, entities.CommandIDResponse{Id: id})
+	case http.MethodOptions:
+		w.Header().Set("Allow", "GET, POST, OPTIONS")
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		methodNotAllowed(w)
+	}
+}
+
+func (router Router) getPipeline(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		pipeline, err := router.Service.GetPipeline(r.Context(), r.PathValue("name"))
+		if err != nil {
+			router.serviceError(w, "pipeline", "failed to get pipeline", err)
+			return
+		}
+		sendJSONResponse(w, http.StatusOK, pipeline)
+	case http.MethodOptions:
+		w.Header().Set("Allow", "GET, OPTIONS")
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		methodNotAllowed(w)
+	}
+}
+
+func (router Router) runPipeline(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		var runDto entities.RunPipelineDto
+		if err := json.NewDecoder(r.Body).Decode(&runDto); err != nil {
+			e := newError("failed to parse request body", http.StatusBadRequest)
+			http.Error(w, e.ToJson(), e.StatusCode)
+			router.Logger.Error(e.Message, sl.Err(err))
+			return
+		}
+		defer r.Body.Close()
+		id, err := router.Service.RunPipeline(r.Context(), runDto)
+		if err != nil {
+			router.serviceError(w, "pipeline", "failed to run pipeline", err)
+			return
+		}
+		sendJSONResponse(w, http.StatusOK, entities.CommandIDResponse{Id: id})
+	case http.MethodOptions:
+		w.Header().Set("Allow", "POST, OPTIONS")
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		methodNotAllowed(w)
+	}
+}
+
+func (router Router) getPipelineRun(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		id, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil {
+			e := newError("wrong id format", http.StatusBadRequest)
+			http.Error(w, e.ToJson(), e.StatusCode)
+			router.Logger.Error(e.Message, sl.Err(err))
+			return
+		}
+		run, err := router.Service.GetPipelineRun(r.Context(), id)
+		if err != nil {
+			router.serviceError(w, "pipeline run", "failed to get pipeline run", err)
+			return
+		}
+		sendJSONResponse(w, http.StatusOK, run)
+	case http.MethodOptions:
+		w.Header().Set("Allow", "GET, OPTIONS")
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		methodNotAllowed(w)
+	}
+}
@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testex/internal/entities"
+	"testex/internal/service"
+	mock_service "testex/internal/service/mocks"
+	"testex/pkg/slog/slogdiscard"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRouter_pipelines(t *testing.T) {
+	type mockBehavior func(r *mock_service.MockPipeline)
+
+	tests := []struct {
+		name                 string
+		requestMethod        string
+		requestPath          string
+		requestBody          string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:          "Create",
+			requestMethod: http.MethodPost,
+			requestPath:   "/pipelines",
+			requestBody:   `{"name": "release", "steps": [{"name": "build", "alias": "build"}, {"name": "test", "alias": "test", "needs": ["build"]}]}`,
+			mockBehavior: func(r *mock_service.MockPipeline) {
+				r.EXPECT().CreatePipeline(gomock.Any(), entities.PipelineDto{
+					Name: "release",
+					Steps: entities.PipelineSteps{
+						{Name: "build", Alias: "build"},
+						{Name: "test", Alias: "test", Needs: []string{"build"}},
+					},
+				}).Return(1, nil)
+			},
+			expectedStatusCode:   http.StatusCreated,
+			expectedResponseBody: `{"id":1}`,
+		},
+		{
+			name:          "Create_Cycle",
+			requestMethod: http.MethodPost,
+			requestPath:   "/pipelines",
+			requestBody:   `{"name": "release", "steps": [{"name": "build", "alias": "build", "needs": ["build"]}]}`,
+			mockBehavior: func(r *mock_service.MockPipeline) {
+				r.EXPECT().CreatePipeline(gomock.Any(), gomock.Any()).
+					Return(-1, fmt.Errorf("%w: step \"build\" is part of a dependency cycle", entities.ErrInvalidParams))
+			},
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedResponseBody: `{"message":"invalid command params: step \"build\" is part of a dependency cycle","status_code":400}`,
+		},
+		{
+			name:          "Run",
+			requestMethod: http.MethodPost,
+			requestPath:   "/pipelines/run",
+			requestBody:   `{"name": "release"}`,
+			mockBehavior: func(r *mock_service.MockPipeline) {
+				r.EXPECT().RunPipeline(gomock.Any(), entities.RunPipelineDto{Name: "release"}).Return(5, nil)
+			},
+			expectedStatusCode:   http.StatusOK,
+			expectedResponseBody: `{"id":5}`,
+		},
+		{
+			name:          "Run_NotFound",
+			requestMethod: http.MethodPost,
+			requestPath:   "/pipelines/run",
+			requestBody:   `{"name": "missing"}`,
+			mockBehavior: func(r *mock_service.MockPipeline) {
+				r.EXPECT().RunPipeline(gomock.Any(), entities.RunPipelineDto{Name: "missing"}).Return(-1, entities.ErrNotFound)
+			},
+			expectedStatusCode:   http.StatusNotFound,
+			expectedResponseBody: `{"message":"pipeline not found","status_code":404}`,
+		},
+		{
+			name:          "GetRun",
+			requestMethod: http.MethodGet,
+			requestPath:   "/pipelines/runs/5",
+			mockBehavior: func(r *mock_service.MockPipeline) {
+				id := 10
+				r.EXPECT().GetPipelineRun(gomock.Any(), 5).Return(entities.PipelineRun{
+					Id:         5,
+					PipelineId: 1,
+					Status:     entities.PipelineRunning,
+					Steps: []entities.PipelineRunStep{
+						{Id: 1, RunId: 5, Name: "build", Alias: "build", Status: entities.StepRunning, ExecutedCommandId: &id},
+					},
+				}, nil)
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedResponseBody: `{"id":5,"pipeline_id":1,"status":"running","started_at":"0001-01-01T00:00:00Z",` +
+				`"steps":[{"id":1,"run_id":5,"name":"build","alias":"build","status":"running","executed_command_id":10}]}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			repo := mock_service.NewMockPipeline(c)
+			if test.mockBehavior != nil {
+				test.mockBehavior(repo)
+			}
+
+			srv := &service.Service{Pipeline: repo}
+			mux := http.NewServeMux()
+			handler := &Router{Service: srv, Logger: slogdiscard.NewDiscardLogger(), Mux: mux}
+			mux.HandleFunc("/pipelines", handler.pipelines)
+			mux.HandleFunc("/pipelines/run", handler.runPipeline)
+			mux.HandleFunc("/pipelines/runs/{id}", handler.getPipelineRun)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(test.requestMethod, test.requestPath, bytes.NewBufferString(test.requestBody))
+			mux.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatusCode, w.Code)
+			assert.Equal(t, test.expectedResponseBody, strings.TrimSpace(w.Body.String()))
+		})
+	}
+}
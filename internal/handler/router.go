@@ -2,6 +2,7 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"log/slog"
 	"net/http"
 	"strconv"
@@ -14,26 +15,50 @@ type Router struct {
 	Mux     *http.ServeMux
 	Service *service.Service
 	Logger  *slog.Logger
+	// AllowedOrigins may open log websockets besides the API itself.
+	AllowedOrigins []string
 }
 
-func New(service2 *service.Service, logger *slog.Logger) *Router {
+func New(service2 *service.Service, logger *slog.Logger, allowedOrigins []string) *Router {
 	r := &Router{
-		Mux:     http.NewServeMux(),
-		Service: service2,
-		Logger:  logger,
+		Mux:            http.NewServeMux(),
+		Service:        service2,
+		Logger:         logger,
+		AllowedOrigins: allowedOrigins,
 	}
 	r.initRoutes()
 	return r
 }
 
 func (router Router) initRoutes() {
-	router.Mux.HandleFunc("/commands/execute", router.executeCommand)
-	router.Mux.HandleFunc("/commands/{alias}", router.getCommand)
-	router.Mux.HandleFunc("/commands", router.getAllCommands)
-	router.Mux.HandleFunc("/commands/add", router.addCommand)
-	router.Mux.HandleFunc("/commands/stop", router.stopCommand)
-	router.Mux.HandleFunc("/commands/logs/{id}", router.getLogs)
-	router.Mux.HandleFunc("/commands/active", router.getActiveExecutedCommands)
+	router.handle("/commands/execute", operate, router.executeCommand)
+	router.handle("/commands/{alias}", operate, router.command)
+	router.handle("/commands/{alias}/revisions", manage, router.getCommandRevisions)
+	router.handle("/commands/{alias}/executions", manage, router.getCommandExecutions)
+	router.handle("/commands", manage, router.getAllCommands)
+	router.handle("/commands/add", manage, router.addCommand)
+	router.handle("/commands/stop", operate, router.stopCommand)
+	router.handle("/commands/active", manage, router.getActiveExecutedCommands)
+	router.handle("/logs/search", manage, router.searchLogs)
+	router.handle("/executions", manage, router.getExecutions)
+	router.handle("/executions/{id}", manage, router.getExecution)
+	router.handle("/executions/{id}/logs", manage, router.getLogs)
+	router.handle("/executions/{id}/logs/stream", manage, router.streamLogs)
+	router.handle("/executions/{id}/logs/ws", manage, router.streamLogsWS)
+	router.handle("/executions/{id}/output", manage, router.getExecutionOutput)
+	router.handle("/executions/{id}/artifacts", manage, router.getArtifacts)
+	router.handle("/executions/{id}/artifacts/{path...}", manage, router.getArtifact)
+	router.handle("/schedules", manage, router.schedules)
+	router.handle("/schedules/{id}", manage, router.schedule)
+	router.handle("/pipelines", manage, router.pipelines)
+	router.handle("/pipelines/{name}", manage, router.getPipeline)
+	router.handle("/pipelines/run", operate, router.runPipeline)
+	router.handle("/pipelines/runs/{id}", manage, router.getPipelineRun)
+	router.handle("/secrets", admin, router.secrets)
+	router.handle("/secrets/{name}", admin, router.secret)
+	router.handle("/auth/keys", admin, router.apiKeys)
+	router.handle("/auth/keys/{id}", admin, router.apiKey)
+	router.handle("/audit", admin, router.getAuditEvents)
 }
 
 func (router Router) addCommand(w http.ResponseWriter, r *http.Request) {
@@ -46,7 +71,17 @@ func (router Router) addCommand(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		defer r.Body.Close()
-		id, err := router.Service.Command.Create(commandDto.Alias, commandDto.Script)
+		id, err := router.Service.Command.Create(r.Context(), commandDto)
+		if errors.Is(err, entities.ErrInvalidParams) {
+			e := newError(err.Error(), http.StatusBadRequest)
+			http.Error(w, e.ToJson(), e.StatusCode)
+			return
+		}
+		if errors.Is(err, entities.ErrConflict) {
+			e := newError(err.Error(), http.StatusConflict)
+			http.Error(w, e.ToJson(), e.StatusCode)
+			return
+		}
 		if err != nil {
 			e := newError("failed to add new command", http.StatusInternalServerError)
 			http.Error(w, e.ToJson(), e.StatusCode)
@@ -66,15 +101,35 @@ func (router Router) addCommand(w http.ResponseWriter, r *http.Request) {
 func (router Router) executeCommand(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodPost:
-		var commandDto entities.CommandDto
-		if err := json.NewDecoder(r.Body).Decode(&commandDto); err != nil {
+		var executeDto entities.ExecuteCommandDto
+		if err := json.NewDecoder(r.Body).Decode(&executeDto); err != nil {
 			e := newError("failed to parse request body", http.StatusBadRequest)
 			http.Error(w, e.ToJson(), e.StatusCode)
 			router.Logger.Error(e.Message, sl.Err(err))
 			return
 		}
 		defer r.Body.Close()
-		output, err := router.Service.Execute(commandDto.Alias)
+		output, err := router.Service.Execute(r.Context(), executeDto)
+		if errors.Is(err, entities.ErrInvalidParams) {
+			e := newError(err.Error(), http.StatusBadRequest)
+			http.Error(w, e.ToJson(), e.StatusCode)
+			return
+		}
+		if errors.Is(err, entities.ErrExecutionRejected) {
+			e := newError(err.Error(), http.StatusTooManyRequests)
+			http.Error(w, e.ToJson(), e.StatusCode)
+			return
+		}
+		if errors.Is(err, entities.ErrNotFound) {
+			e := newError("command not found", http.StatusNotFound)
+			http.Error(w, e.ToJson(), e.StatusCode)
+			return
+		}
+		if errors.Is(err, entities.ErrForbidden) {
+			e := newError(err.Error(), http.StatusForbidden)
+			http.Error(w, e.ToJson(), e.StatusCode)
+			return
+		}
 		if err != nil {
 			e := newError("failed to execute command", http.StatusInternalServerError)
 			http.Error(w, e.ToJson(), e.StatusCode)
@@ -92,22 +147,83 @@ func (router Router) executeCommand(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func (router Router) getCommand(w http.ResponseWriter, r *http.Request) {
+func (router Router) command(w http.ResponseWriter, r *http.Request) {
+	alias := r.PathValue("alias")
 	switch r.Method {
 	case http.MethodGet:
 		defer r.Body.Close()
-		alias := r.PathValue("alias")
-
-		command, err := router.Service.GetOne(alias)
+		command, err := router.Service.GetOne(r.Context(), alias)
+		if err != nil {
+			router.serviceError(w, "command", "failed to get command", err)
+			return
+		}
+		w.Header().Set("ETag", etag(command.Version))
+		sendJSONResponse(w, http.StatusOK, command)
+	case http.MethodPut, http.MethodPatch:
+		version, err := ifMatch(r)
 		if err != nil {
-			e := newError("failed to get command", http.StatusInternalServerError)
+			e := newError("wrong If-Match format", http.StatusBadRequest)
 			http.Error(w, e.ToJson(), e.StatusCode)
-			router.Logger.Error(e.Message, sl.Err(err))
 			return
 		}
+		defer r.Body.Close()
+		var command entities.Command
+		if r.Method == http.MethodPut {
+			var commandDto entities.CommandDto
+			if err = json.NewDecoder(r.Body).Decode(&commandDto); err != nil {
+				e := newError("failed to parse request body", http.StatusBadRequest)
+				http.Error(w, e.ToJson(), e.StatusCode)
+				router.Logger.Error(e.Message, sl.Err(err))
+				return
+			}
+			command, err = router.Service.Update(r.Context(), alias, version, commandDto)
+		} else {
+			var patchDto entities.CommandPatchDto
+			if err = json.NewDecoder(r.Body).Decode(&patchDto); err != nil {
+				e := newError("failed to parse request body", http.StatusBadRequest)
+				http.Error(w, e.ToJson(), e.StatusCode)
+				router.Logger.Error(e.Message, sl.Err(err))
+				return
+			}
+			command, err = router.Service.Patch(r.Context(), alias, version, patchDto)
+		}
+		if err != nil {
+			router.serviceError(w, "command", "failed to update command", err)
+			return
+		}
+		w.Header().Set("ETag", etag(command.Version))
 		sendJSONResponse(w, http.StatusOK, command)
+	case http.MethodDelete:
+		version, err := ifMatch(r)
+		if err != nil {
+			e := newError("wrong If-Match format", http.StatusBadRequest)
+			http.Error(w, e.ToJson(), e.StatusCode)
+			return
+		}
+		if err = router.Service.Delete(r.Context(), alias, version); err != nil {
+			router.serviceError(w, "command", "failed to delete command", err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
 	case http.MethodOptions:
-		w.Header().Set("Allow", "GET, POST, OPTIONS")
+		w.Header().Set("Allow", "GET, PUT, PATCH, DELETE, OPTIONS")
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		methodNotAllowed(w)
+	}
+}
+
+func (router Router) getCommandRevisions(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		revisions, err := router.Service.GetRevisions(r.Context(), r.PathValue("alias"))
+		if err != nil {
+			router.serviceError(w, "command", "failed to get command revisions", err)
+			return
+		}
+		sendJSONResponse(w, http.StatusOK, revisions)
+	case http.MethodOptions:
+		w.Header().Set("Allow", "GET, OPTIONS")
 		w.WriteHeader(http.StatusNoContent)
 	default:
 		methodNotAllowed(w)
@@ -117,7 +233,7 @@ func (router Router) getCommand(w http.ResponseWriter, r *http.Request) {
 func (router Router) getAllCommands(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodGet:
-		command, err := router.Service.GetAll()
+		command, err := router.Service.GetAll(r.Context())
 		if err != nil {
 			e := newError("failed to get commands", http.StatusInternalServerError)
 			http.Error(w, e.ToJson(), e.StatusCode)
@@ -136,17 +252,20 @@ func (router Router) getAllCommands(w http.ResponseWriter, r *http.Request) {
 func (router Router) stopCommand(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodPost:
-		var idDto struct {
-			Id int `json:"id"`
-		}
-		if err := json.NewDecoder(r.Body).Decode(&idDto); err != nil {
+		var stopDto entities.StopCommandDto
+		if err := json.NewDecoder(r.Body).Decode(&stopDto); err != nil {
 			e := newError("failed to parse request body", http.StatusBadRequest)
 			http.Error(w, e.ToJson(), e.StatusCode)
 			router.Logger.Error(e.Message, sl.Err(err))
 			return
 		}
 		defer r.Body.Close()
-		err := router.Service.StopCommand(idDto.Id)
+		err := router.Service.StopCommand(r.Context(), stopDto)
+		if errors.Is(err, entities.ErrForbidden) {
+			e := newError(err.Error(), http.StatusForbidden)
+			http.Error(w, e.ToJson(), e.StatusCode)
+			return
+		}
 		if err != nil {
 			e := newError("failed to stop command", http.StatusBadRequest)
 			http.Error(w, e.ToJson(), e.StatusCode)
@@ -173,11 +292,15 @@ func (router Router) getLogs(w http.ResponseWriter, r *http.Request) {
 			router.Logger.Error(e.Message, sl.Err(err))
 			return
 		}
-		logs, err := router.Service.Command.GetLogs(parsedId)
+		filter, err := parseLogFilter(r)
 		if err != nil {
-			e := newError("failed to get logs", http.StatusInternalServerError)
+			e := newError(err.Error(), http.StatusBadRequest)
 			http.Error(w, e.ToJson(), e.StatusCode)
-			router.Logger.Error(e.Message, sl.Err(err))
+			return
+		}
+		logs, err := router.Service.Command.GetLogs(r.Context(), parsedId, filter)
+		if err != nil {
+			router.serviceError(w, "execution", "failed to get logs", err)
 			return
 		}
 		sendJSONResponse(w, http.StatusOK, logs)
@@ -189,10 +312,33 @@ func (router Router) getLogs(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func (router Router) searchLogs(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		search, err := parseLogSearch(r)
+		if err != nil {
+			e := newError(err.Error(), http.StatusBadRequest)
+			http.Error(w, e.ToJson(), e.StatusCode)
+			return
+		}
+		matches, err := router.Service.SearchLogs(r.Context(), search)
+		if err != nil {
+			router.serviceError(w, "log", "failed to search logs", err)
+			return
+		}
+		sendJSONResponse(w, http.StatusOK, matches)
+	case http.MethodOptions:
+		w.Header().Set("Allow", "GET, OPTIONS")
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		methodNotAllowed(w)
+	}
+}
+
 func (router Router) getActiveExecutedCommands(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodGet:
-		command, err := router.Service.GetActiveExecutedCommand()
+		command, err := router.Service.GetActiveExecutedCommand(r.Context())
 		if err != nil {
 			e := newError("failed to get commands", http.StatusInternalServerError)
 			http.Error(w, e.ToJson(), e.StatusCode)
@@ -19,12 +19,12 @@ import (
 
 func TestRouter_addCommand(t *testing.T) {
 	// Init Test Table
-	type mockBehavior func(r *mock_service.MockCommand, e entities.Command)
+	type mockBehavior func(r *mock_service.MockCommand, e entities.CommandDto)
 
 	tests := []struct {
 		name                 string
 		inputBody            string
-		inputCommand         entities.Command
+		inputCommand         entities.CommandDto
 		mockBehavior         mockBehavior
 		expectedStatusCode   int
 		expectedResponseBody string
@@ -33,12 +33,12 @@ func TestRouter_addCommand(t *testing.T) {
 		{
 			name:      "Ok",
 			inputBody: `{"alias": "echo", "script": "echo hello"}`,
-			inputCommand: entities.Command{
+			inputCommand: entities.CommandDto{
 				Alias:  "echo",
 				Script: "echo hello",
 			},
-			mockBehavior: func(r *mock_service.MockCommand, e entities.Command) {
-				r.EXPECT().Create(e.Alias, e.Script).Return(1, nil)
+			mockBehavior: func(r *mock_service.MockCommand, e entities.CommandDto) {
+				r.EXPECT().Create(gomock.Any(), e).Return(1, nil)
 			},
 			expectedStatusCode:   http.StatusCreated,
 			expectedResponseBody: `{"id":1}`,
@@ -54,17 +54,32 @@ func TestRouter_addCommand(t *testing.T) {
 		{
 			name:      "InternalServerError_CreateCommandFailed",
 			inputBody: `{"alias": "echo", "script": "echo hello"}`,
-			inputCommand: entities.Command{
+			inputCommand: entities.CommandDto{
 				Alias:  "echo",
 				Script: "echo hello",
 			},
-			mockBehavior: func(r *mock_service.MockCommand, e entities.Command) {
-				r.EXPECT().Create(e.Alias, e.Script).Return(-1, errors.New("failed to create command"))
+			mockBehavior: func(r *mock_service.MockCommand, e entities.CommandDto) {
+				r.EXPECT().Create(gomock.Any(), e).Return(-1, errors.New("failed to create command"))
 			},
 			expectedStatusCode:   http.StatusInternalServerError,
 			expectedResponseBody: `{"message":"failed to add new command","status_code":500}`,
 			requestMethod:        http.MethodPost,
 		},
+		{
+			name:      "BadRequest_InvalidParams",
+			inputBody: `{"alias": "greet", "script": "echo $name", "params": [{"name": "name", "type": "uuid"}]}`,
+			inputCommand: entities.CommandDto{
+				Alias:  "greet",
+				Script: "echo $name",
+				Params: entities.CommandParams{{Name: "name", Type: "uuid"}},
+			},
+			mockBehavior: func(r *mock_service.MockCommand, e entities.CommandDto) {
+				r.EXPECT().Create(gomock.Any(), e).Return(-1, fmt.Errorf("%w: unknown type", entities.ErrInvalidParams))
+			},
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedResponseBody: `{"message":"invalid command params: unknown type","status_code":400}`,
+			requestMethod:        http.MethodPost,
+		},
 		{
 
 			name:                 "MethodNotAllowed",
@@ -123,17 +138,17 @@ func TestRouter_getCommand(t *testing.T) {
 			requestMethod: http.MethodGet,
 			requestAlias:  "test_alias",
 			mockBehavior: func(r *mock_service.MockCommand, alias string, command entities.Command, err error) {
-				r.EXPECT().GetOne(alias).Return(command, err)
+				r.EXPECT().GetOne(gomock.Any(), alias).Return(command, err)
 			},
 			expectedStatusCode:   http.StatusOK,
-			expectedResponseBody: `{"id":1,"alias":"test_alias","script":"test_script"}`,
+			expectedResponseBody: `{"id":1,"alias":"test_alias","script":"test_script","version":0,"revision_id":0}`,
 		},
 		{
 			name:          "GetCommand_InternalServerError",
 			requestMethod: http.MethodGet,
 			requestAlias:  "test_alias",
 			mockBehavior: func(r *mock_service.MockCommand, alias string, command entities.Command, err error) {
-				r.EXPECT().GetOne(alias).Return(entities.Command{}, errors.New("something went wrong"))
+				r.EXPECT().GetOne(gomock.Any(), alias).Return(entities.Command{}, errors.New("something went wrong"))
 			},
 			expectedStatusCode:   http.StatusInternalServerError,
 			expectedResponseBody: `{"message":"failed to get command","status_code":500}`,
@@ -163,7 +178,7 @@ func TestRouter_getCommand(t *testing.T) {
 			logger := slogdiscard.NewDiscardLogger()
 			mux := http.NewServeMux()
 			handler := &Router{Service: srv, Logger: logger, Mux: mux}
-			mux.HandleFunc("/commands/{alias}", handler.getCommand)
+			mux.HandleFunc("/commands/{alias}", handler.command)
 
 			// Create Request
 			w := httptest.NewRecorder()
@@ -179,6 +194,124 @@ func TestRouter_getCommand(t *testing.T) {
 	}
 }
 
+func TestRouter_updateCommand(t *testing.T) {
+	type mockBehavior func(r *mock_service.MockCommand)
+
+	script := "echo bye"
+	tests := []struct {
+		name                 string
+		requestMethod        string
+		requestBody          string
+		ifMatch              string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedETag         string
+		expectedResponseBody string
+	}{
+		{
+			name:          "Put",
+			requestMethod: http.MethodPut,
+			requestBody:   `{"alias": "greet", "script": "echo hi"}`,
+			ifMatch:       `"1"`,
+			mockBehavior: func(r *mock_service.MockCommand) {
+				r.EXPECT().Update(gomock.Any(), "greet", 1, entities.CommandDto{Alias: "greet", Script: "echo hi"}).
+					Return(entities.Command{Id: 1, Alias: "greet", Script: "echo hi", Version: 2, RevisionId: 5}, nil)
+			},
+			expectedStatusCode:   http.StatusOK,
+			expectedETag:         `"2"`,
+			expectedResponseBody: `{"id":1,"alias":"greet","script":"echo hi","version":2,"revision_id":5}`,
+		},
+		{
+			name:          "Patch",
+			requestMethod: http.MethodPatch,
+			requestBody:   `{"script": "echo bye"}`,
+			mockBehavior: func(r *mock_service.MockCommand) {
+				r.EXPECT().Patch(gomock.Any(), "greet", 0, entities.CommandPatchDto{Script: &script}).
+					Return(entities.Command{Id: 1, Alias: "greet", Script: script, Version: 3, RevisionId: 6}, nil)
+			},
+			expectedStatusCode:   http.StatusOK,
+			expectedETag:         `"3"`,
+			expectedResponseBody: `{"id":1,"alias":"greet","script":"echo bye","version":3,"revision_id":6}`,
+		},
+		{
+			name:          "Put_VersionMismatch",
+			requestMethod: http.MethodPut,
+			requestBody:   `{"alias": "greet", "script": "echo hi"}`,
+			ifMatch:       `"1"`,
+			mockBehavior: func(r *mock_service.MockCommand) {
+				r.EXPECT().Update(gomock.Any(), "greet", 1, entities.CommandDto{Alias: "greet", Script: "echo hi"}).
+					Return(entities.Command{}, fmt.Errorf("%w: command greet is at version 2", entities.ErrVersionMismatch))
+			},
+			expectedStatusCode:   http.StatusPreconditionFailed,
+			expectedResponseBody: `{"message":"version mismatch: command greet is at version 2","status_code":412}`,
+		},
+		{
+			name:          "Patch_AliasTaken",
+			requestMethod: http.MethodPatch,
+			requestBody:   `{"alias": "hello"}`,
+			mockBehavior: func(r *mock_service.MockCommand) {
+				r.EXPECT().Patch(gomock.Any(), "greet", 0, gomock.Any()).
+					Return(entities.Command{}, fmt.Errorf("%w: Key (alias)=(hello) already exists.", entities.ErrConflict))
+			},
+			expectedStatusCode:   http.StatusConflict,
+			expectedResponseBody: `{"message":"conflict: Key (alias)=(hello) already exists.","status_code":409}`,
+		},
+		{
+			name:          "Delete",
+			requestMethod: http.MethodDelete,
+			ifMatch:       `W/"4"`,
+			mockBehavior: func(r *mock_service.MockCommand) {
+				r.EXPECT().Delete(gomock.Any(), "greet", 4).Return(nil)
+			},
+			expectedStatusCode: http.StatusNoContent,
+		},
+		{
+			name:          "Delete_NotFound",
+			requestMethod: http.MethodDelete,
+			mockBehavior: func(r *mock_service.MockCommand) {
+				r.EXPECT().Delete(gomock.Any(), "greet", 0).Return(entities.ErrNotFound)
+			},
+			expectedStatusCode:   http.StatusNotFound,
+			expectedResponseBody: `{"message":"command not found","status_code":404}`,
+		},
+		{
+			name:                 "WrongIfMatch",
+			requestMethod:        http.MethodDelete,
+			ifMatch:              `"abc"`,
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedResponseBody: `{"message":"wrong If-Match format","status_code":400}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			repo := mock_service.NewMockCommand(c)
+			if test.mockBehavior != nil {
+				test.mockBehavior(repo)
+			}
+
+			srv := &service.Service{Command: repo}
+			mux := http.NewServeMux()
+			handler := &Router{Service: srv, Logger: slogdiscard.NewDiscardLogger(), Mux: mux}
+			mux.HandleFunc("/commands/{alias}", handler.command)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(test.requestMethod, "/commands/greet", bytes.NewBufferString(test.requestBody))
+			if test.ifMatch != "" {
+				req.Header.Set("If-Match", test.ifMatch)
+			}
+			mux.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatusCode, w.Code)
+			assert.Equal(t, test.expectedETag, w.Header().Get("ETag"))
+			assert.Equal(t, test.expectedResponseBody, strings.TrimSpace(w.Body.String()))
+		})
+	}
+}
+
 func TestRouter_executeCommand(t *testing.T) {
 	// Init Test Table
 	type mockBehavior func(r *mock_service.MockCommand, alias string, output int, err error)
@@ -198,11 +331,51 @@ func TestRouter_executeCommand(t *testing.T) {
 			requestBody:   `{"alias": "test_alias"}`,
 			requestAlias:  "test_alias",
 			mockBehavior: func(r *mock_service.MockCommand, alias string, output int, err error) {
-				r.EXPECT().Execute(alias).Return(output, err)
+				r.EXPECT().Execute(gomock.Any(), entities.ExecuteCommandDto{Alias: alias}).Return(output, err)
+			},
+			expectedStatusCode:   http.StatusOK,
+			expectedResponseBody: `{"id":1}`,
+		},
+		{
+			name:          "ExecuteCommand_WithParams",
+			requestMethod: http.MethodPost,
+			requestBody:   `{"alias": "test_alias", "params": {"name": "world", "count": 3}}`,
+			requestAlias:  "test_alias",
+			mockBehavior: func(r *mock_service.MockCommand, alias string, output int, err error) {
+				r.EXPECT().Execute(gomock.Any(), entities.ExecuteCommandDto{
+					Alias:  alias,
+					Params: map[string]any{"name": "world", "count": float64(3)},
+				}).Return(output, err)
 			},
 			expectedStatusCode:   http.StatusOK,
 			expectedResponseBody: `{"id":1}`,
 		},
+		{
+			name:          "ExecuteCommand_InvalidParams",
+			requestMethod: http.MethodPost,
+			requestBody:   `{"alias": "test_alias", "params": {"count": "many"}}`,
+			requestAlias:  "test_alias",
+			mockBehavior: func(r *mock_service.MockCommand, alias string, output int, err error) {
+				r.EXPECT().Execute(gomock.Any(), entities.ExecuteCommandDto{
+					Alias:  alias,
+					Params: map[string]any{"count": "many"},
+				}).Return(-1, fmt.Errorf("%w: param \"count\" must be an integer", entities.ErrInvalidParams))
+			},
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedResponseBody: `{"message":"invalid command params: param \"count\" must be an integer","status_code":400}`,
+		},
+		{
+			name:          "ExecuteCommand_Rejected",
+			requestMethod: http.MethodPost,
+			requestBody:   `{"alias": "test_alias"}`,
+			requestAlias:  "test_alias",
+			mockBehavior: func(r *mock_service.MockCommand, alias string, output int, err error) {
+				r.EXPECT().Execute(gomock.Any(), entities.ExecuteCommandDto{Alias: alias}).
+					Return(-1, fmt.Errorf("%w: execution queue is full", entities.ErrExecutionRejected))
+			},
+			expectedStatusCode:   http.StatusTooManyRequests,
+			expectedResponseBody: `{"message":"execution rejected: execution queue is full","status_code":429}`,
+		},
 		{
 			name:                 "ExecuteCommand_BadRequest",
 			requestMethod:        http.MethodPost,
@@ -216,7 +389,7 @@ func TestRouter_executeCommand(t *testing.T) {
 			requestBody:   `{"alias": "test_alias"}`,
 			requestAlias:  "test_alias",
 			mockBehavior: func(r *mock_service.MockCommand, alias string, output int, err error) {
-				r.EXPECT().Execute(alias).Return(-1, errors.New("failed to execute command"))
+				r.EXPECT().Execute(gomock.Any(), entities.ExecuteCommandDto{Alias: alias}).Return(-1, errors.New("failed to execute command"))
 			},
 			expectedStatusCode:   http.StatusInternalServerError,
 			expectedResponseBody: `{"message":"failed to execute command","status_code":500}`,
@@ -277,16 +450,17 @@ func TestRouter_getAllCommands(t *testing.T) {
 			name:          "GetAllCommands_Success",
 			requestMethod: http.MethodGet,
 			mockBehavior: func(r *mock_service.MockCommand, commands []entities.Command, err error) {
-				r.EXPECT().GetAll().Return(commands, err)
+				r.EXPECT().GetAll(gomock.Any()).Return(commands, err)
 			},
-			expectedStatusCode:   http.StatusOK,
-			expectedResponseBody: `[{"id":1,"alias":"test_alias1","script":"test_script1"},{"id":2,"alias":"test_alias2","script":"test_script2"}]`,
+			expectedStatusCode: http.StatusOK,
+			expectedResponseBody: `[{"id":1,"alias":"test_alias1","script":"test_script1","version":0,"revision_id":0},` +
+				`{"id":2,"alias":"test_alias2","script":"test_script2","version":0,"revision_id":0}]`,
 		},
 		{
 			name:          "GetAllCommands_InternalServerError",
 			requestMethod: http.MethodGet,
 			mockBehavior: func(r *mock_service.MockCommand, commands []entities.Command, err error) {
-				r.EXPECT().GetAll().Return(nil, errors.New("failed to get commands"))
+				r.EXPECT().GetAll(gomock.Any()).Return(nil, errors.New("failed to get commands"))
 			},
 			expectedStatusCode:   http.StatusInternalServerError,
 			expectedResponseBody: `{"message":"failed to get commands","status_code":500}`,
@@ -353,7 +527,18 @@ func TestRouter_stopCommand(t *testing.T) {
 			inputBody:     `{"id": 1}`,
 			inputID:       1,
 			mockBehavior: func(r *mock_service.MockCommand, id int, err error) {
-				r.EXPECT().StopCommand(id).Return(nil)
+				r.EXPECT().StopCommand(gomock.Any(), entities.StopCommandDto{Id: id}).Return(nil)
+			},
+			expectedStatusCode:   http.StatusOK,
+			expectedResponseBody: "",
+		},
+		{
+			name:          "StopCommand_WithSignal",
+			requestMethod: http.MethodPost,
+			inputBody:     `{"id": 1, "signal": "SIGHUP"}`,
+			inputID:       1,
+			mockBehavior: func(r *mock_service.MockCommand, id int, err error) {
+				r.EXPECT().StopCommand(gomock.Any(), entities.StopCommandDto{Id: id, Signal: "SIGHUP"}).Return(nil)
 			},
 			expectedStatusCode:   http.StatusOK,
 			expectedResponseBody: "",
@@ -375,7 +560,7 @@ func TestRouter_stopCommand(t *testing.T) {
 			inputBody:     `{"id": 1}`,
 			inputID:       1,
 			mockBehavior: func(r *mock_service.MockCommand, id int, err error) {
-				r.EXPECT().StopCommand(id).Return(errors.New("failed to stop command"))
+				r.EXPECT().StopCommand(gomock.Any(), entities.StopCommandDto{Id: id}).Return(errors.New("failed to stop command"))
 			},
 			expectedStatusCode:   http.StatusBadRequest,
 			expectedResponseBody: `{"message":"failed to stop command","status_code":400}`,
@@ -429,6 +614,7 @@ func TestRouter_getLogs(t *testing.T) {
 		name                 string
 		requestMethod        string
 		requestID            string
+		requestQuery         string
 		mockBehavior         mockBehavior
 		expectedStatusCode   int
 		expectedResponseBody string
@@ -438,10 +624,37 @@ func TestRouter_getLogs(t *testing.T) {
 			requestMethod: http.MethodGet,
 			requestID:     "1",
 			mockBehavior: func(r *mock_service.MockCommand, id int, logs []entities.Log, err error) {
-				r.EXPECT().GetLogs(id).Return(logs, err)
+				r.EXPECT().GetLogs(gomock.Any(), id, entities.LogFilter{}).Return(logs, err)
 			},
 			expectedStatusCode:   http.StatusOK,
-			expectedResponseBody: `[{"id":1,"executed_command_id":1,"message":"log1","date":"0001-01-01T00:00:00Z"},{"id":2,"executed_command_id":1,"message":"log2","date":"0001-01-01T00:00:00Z"},{"id":3,"executed_command_id":1,"message":"log3","date":"0001-01-01T00:00:00Z"}]`,
+			expectedResponseBody: `[{"id":1,"executed_command_id":1,"seq":1,"stream":"stdout","level":"info","message":"log1","date":"0001-01-01T00:00:00Z"},{"id":2,"executed_command_id":1,"seq":2,"stream":"stderr","level":"error","message":"log2","date":"0001-01-01T00:00:00Z"},{"id":3,"executed_command_id":1,"seq":3,"stream":"stdout","level":"info","message":"log3","date":"0001-01-01T00:00:00Z"}]`,
+		},
+		{
+			name:          "GetLogs_Filtered",
+			requestMethod: http.MethodGet,
+			requestID:     "1",
+			requestQuery:  "?stream=stderr&after_seq=1&limit=10",
+			mockBehavior: func(r *mock_service.MockCommand, id int, logs []entities.Log, err error) {
+				r.EXPECT().GetLogs(gomock.Any(), id, entities.LogFilter{Stream: entities.StreamStderr, AfterSeq: 1, Limit: 10}).Return(logs[1:2], err)
+			},
+			expectedStatusCode:   http.StatusOK,
+			expectedResponseBody: `[{"id":2,"executed_command_id":1,"seq":2,"stream":"stderr","level":"error","message":"log2","date":"0001-01-01T00:00:00Z"}]`,
+		},
+		{
+			name:                 "GetLogs_WrongStream",
+			requestMethod:        http.MethodGet,
+			requestID:            "1",
+			requestQuery:         "?stream=stdin",
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedResponseBody: `{"message":"wrong stream, expected stdout, stderr or system","status_code":400}`,
+		},
+		{
+			name:                 "GetLogs_WrongLimit",
+			requestMethod:        http.MethodGet,
+			requestID:            "1",
+			requestQuery:         "?limit=0",
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedResponseBody: `{"message":"wrong limit, expected a number from 1 to 10000","status_code":400}`,
 		},
 		{
 			name:          "GetLogs_BadRequest",
@@ -458,7 +671,7 @@ func TestRouter_getLogs(t *testing.T) {
 			requestMethod: http.MethodGet,
 			requestID:     "1",
 			mockBehavior: func(r *mock_service.MockCommand, id int, logs []entities.Log, err error) {
-				r.EXPECT().GetLogs(id).Return(nil, errors.New("failed to get logs"))
+				r.EXPECT().GetLogs(gomock.Any(), id, entities.LogFilter{}).Return(nil, errors.New("failed to get logs"))
 			},
 			expectedStatusCode:   http.StatusInternalServerError,
 			expectedResponseBody: `{"message":"failed to get logs","status_code":500}`,
@@ -482,9 +695,9 @@ func TestRouter_getLogs(t *testing.T) {
 			repo := mock_service.NewMockCommand(c)
 			if test.mockBehavior != nil {
 				test.mockBehavior(repo, 1, []entities.Log{
-					{Id: 1, ExecutedCommandId: 1, Message: "log1"},
-					{Id: 2, ExecutedCommandId: 1, Message: "log2"},
-					{Id: 3, ExecutedCommandId: 1, Message: "log3"},
+					{Id: 1, ExecutedCommandId: 1, Seq: 1, Stream: entities.StreamStdout, Level: entities.LevelInfo, Message: "log1"},
+					{Id: 2, ExecutedCommandId: 1, Seq: 2, Stream: entities.StreamStderr, Level: entities.LevelError, Message: "log2"},
+					{Id: 3, ExecutedCommandId: 1, Seq: 3, Stream: entities.StreamStdout, Level: entities.LevelInfo, Message: "log3"},
 				}, nil)
 			}
 			// Init Service and Handler
@@ -492,11 +705,11 @@ func TestRouter_getLogs(t *testing.T) {
 			logger := slogdiscard.NewDiscardLogger()
 			mux := http.NewServeMux()
 			handler := &Router{Service: srv, Logger: logger, Mux: mux}
-			mux.HandleFunc("/commands/logs/{id}", handler.getLogs)
+			mux.HandleFunc("/executions/{id}/logs", handler.getLogs)
 
 			// Create Request
 			w := httptest.NewRecorder()
-			req := httptest.NewRequest(test.requestMethod, "/commands/logs/"+test.requestID, nil)
+			req := httptest.NewRequest(test.requestMethod, "/executions/"+test.requestID+"/logs"+test.requestQuery, nil)
 
 			// Make Request
 			mux.ServeHTTP(w, req)
@@ -507,3 +720,359 @@ func TestRouter_getLogs(t *testing.T) {
 		})
 	}
 }
+
+func TestRouter_searchLogs(t *testing.T) {
+	type mockBehavior func(r *mock_service.MockCommand)
+
+	tests := []struct {
+		name                 string
+		requestQuery         string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:         "Ok",
+			requestQuery: "?q=connection+refused&alias=deploy&stream=stderr&status=failed&limit=10",
+			mockBehavior: func(r *mock_service.MockCommand) {
+				r.EXPECT().SearchLogs(gomock.Any(), entities.LogSearch{
+					Query:  "connection refused",
+					Alias:  "deploy",
+					Stream: entities.StreamStderr,
+					Status: entities.StatusFailed,
+					Limit:  10,
+				}).Return([]entities.LogMatch{{
+					Log: entities.Log{Id: 5, ExecutedCommandId: 3, Seq: 2, Stream: entities.StreamStderr,
+						Level: entities.LevelError, Message: "dial: connection refused"},
+					Alias:   "deploy",
+					Status:  entities.StatusFailed,
+					Snippet: "dial: <mark>connection</mark> <mark>refused</mark>",
+				}}, nil)
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedResponseBody: `[{"id":5,"executed_command_id":3,"seq":2,"stream":"stderr","level":"error",` +
+				`"message":"dial: connection refused","date":"0001-01-01T00:00:00Z","alias":"deploy","status":"failed",` +
+				`"snippet":"dial: \u003cmark\u003econnection\u003c/mark\u003e \u003cmark\u003erefused\u003c/mark\u003e"}]`,
+		},
+		{
+			name:                 "MissingQuery",
+			requestQuery:         "?alias=deploy",
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedResponseBody: `{"message":"missing search query q","status_code":400}`,
+		},
+		{
+			name:                 "WrongStream",
+			requestQuery:         "?q=error&stream=stdin",
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedResponseBody: `{"message":"wrong stream, expected stdout, stderr or system","status_code":400}`,
+		},
+		{
+			name:         "InternalServerError",
+			requestQuery: "?q=error",
+			mockBehavior: func(r *mock_service.MockCommand) {
+				r.EXPECT().SearchLogs(gomock.Any(), entities.LogSearch{Query: "error"}).Return(nil, errors.New("db is down"))
+			},
+			expectedStatusCode:   http.StatusInternalServerError,
+			expectedResponseBody: `{"message":"failed to search logs","status_code":500}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			repo := mock_service.NewMockCommand(c)
+			if test.mockBehavior != nil {
+				test.mockBehavior(repo)
+			}
+
+			srv := &service.Service{Command: repo}
+			mux := http.NewServeMux()
+			handler := &Router{Service: srv, Logger: slogdiscard.NewDiscardLogger(), Mux: mux}
+			mux.HandleFunc("/logs/search", handler.searchLogs)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/logs/search"+test.requestQuery, nil)
+			mux.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatusCode, w.Code)
+			assert.Equal(t, test.expectedResponseBody, strings.TrimSpace(w.Body.String()))
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	type mocks struct {
+		command  *mock_service.MockCommand
+		schedule *mock_service.MockSchedule
+		pipeline *mock_service.MockPipeline
+		secret   *mock_service.MockSecret
+		auth     *mock_service.MockAuth
+		audit    *mock_service.MockAudit
+	}
+	type mockBehavior func(m mocks)
+
+	// One request per route: a wrong route answers 404 or reaches another
+	// handler with a different status.
+	tests := []struct {
+		requestMethod      string
+		requestPath        string
+		mockBehavior       mockBehavior
+		expectedStatusCode int
+	}{
+		{
+			requestMethod:      http.MethodPost,
+			requestPath:        "/commands/execute",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			requestMethod: http.MethodGet,
+			requestPath:   "/commands/build",
+			mockBehavior: func(m mocks) {
+				m.command.EXPECT().GetOne(gomock.Any(), "build").Return(entities.Command{Alias: "build"}, nil)
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			requestMethod: http.MethodGet,
+			requestPath:   "/commands/build/revisions",
+			mockBehavior: func(m mocks) {
+				m.command.EXPECT().GetRevisions(gomock.Any(), "build").Return(nil, nil)
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			requestMethod: http.MethodGet,
+			requestPath:   "/commands/build/executions",
+			mockBehavior: func(m mocks) {
+				m.command.EXPECT().GetCommandExecutions(gomock.Any(), "build", entities.ExecutionFilter{}, "").
+					Return(entities.ExecutionPage{}, nil)
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			requestMethod: http.MethodGet,
+			requestPath:   "/commands",
+			mockBehavior: func(m mocks) {
+				m.command.EXPECT().GetAll(gomock.Any()).Return(nil, nil)
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			requestMethod:      http.MethodPost,
+			requestPath:        "/commands/add",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			requestMethod:      http.MethodPost,
+			requestPath:        "/commands/stop",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			requestMethod: http.MethodGet,
+			requestPath:   "/commands/active",
+			mockBehavior: func(m mocks) {
+				m.command.EXPECT().GetActiveExecutedCommand(gomock.Any()).Return(nil, nil)
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			requestMethod:      http.MethodGet,
+			requestPath:        "/logs/search",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			requestMethod: http.MethodGet,
+			requestPath:   "/executions",
+			mockBehavior: func(m mocks) {
+				m.command.EXPECT().GetExecutions(gomock.Any(), entities.ExecutionFilter{}, "").
+					Return(entities.ExecutionPage{}, nil)
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			requestMethod: http.MethodGet,
+			requestPath:   "/executions/7",
+			mockBehavior: func(m mocks) {
+				m.command.EXPECT().GetExecution(gomock.Any(), 7).Return(entities.ExecutionDetails{}, nil)
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			requestMethod: http.MethodGet,
+			requestPath:   "/executions/7/logs",
+			mockBehavior: func(m mocks) {
+				m.command.EXPECT().GetLogs(gomock.Any(), 7, entities.LogFilter{}).Return(nil, nil)
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			requestMethod: http.MethodGet,
+			requestPath:   "/executions/7/logs/stream",
+			mockBehavior: func(m mocks) {
+				m.command.EXPECT().GetExecutedCommand(gomock.Any(), 7).
+					Return(entities.ExecutedCommand{}, entities.ErrNotFound)
+			},
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			requestMethod: http.MethodGet,
+			requestPath:   "/executions/7/logs/ws",
+			mockBehavior: func(m mocks) {
+				m.command.EXPECT().GetExecutedCommand(gomock.Any(), 7).
+					Return(entities.ExecutedCommand{}, entities.ErrNotFound)
+			},
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			requestMethod: http.MethodGet,
+			requestPath:   "/executions/7/output",
+			mockBehavior: func(m mocks) {
+				m.command.EXPECT().OpenOutput(gomock.Any(), 7, entities.StreamStdout).
+					Return(entities.ExecutionOutput{}, nil, entities.ErrNotFound)
+			},
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			requestMethod: http.MethodGet,
+			requestPath:   "/executions/7/artifacts",
+			mockBehavior: func(m mocks) {
+				m.command.EXPECT().GetArtifacts(gomock.Any(), 7).Return(nil, nil)
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			requestMethod: http.MethodGet,
+			requestPath:   "/executions/7/artifacts/coverage/index.html",
+			mockBehavior: func(m mocks) {
+				m.command.EXPECT().OpenArtifact(gomock.Any(), 7, "coverage/index.html").
+					Return(entities.Artifact{}, nil, entities.ErrNotFound)
+			},
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			requestMethod: http.MethodGet,
+			requestPath:   "/schedules",
+			mockBehavior: func(m mocks) {
+				m.schedule.EXPECT().GetSchedules(gomock.Any()).Return(nil, nil)
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			requestMethod: http.MethodGet,
+			requestPath:   "/schedules/3",
+			mockBehavior: func(m mocks) {
+				m.schedule.EXPECT().GetSchedule(gomock.Any(), 3).Return(entities.Schedule{}, nil)
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			requestMethod: http.MethodGet,
+			requestPath:   "/pipelines",
+			mockBehavior: func(m mocks) {
+				m.pipeline.EXPECT().GetPipelines(gomock.Any()).Return(nil, nil)
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			requestMethod: http.MethodGet,
+			requestPath:   "/pipelines/release",
+			mockBehavior: func(m mocks) {
+				m.pipeline.EXPECT().GetPipeline(gomock.Any(), "release").Return(entities.Pipeline{}, nil)
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			requestMethod:      http.MethodPost,
+			requestPath:        "/pipelines/run",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			requestMethod: http.MethodGet,
+			requestPath:   "/pipelines/runs/5",
+			mockBehavior: func(m mocks) {
+				m.pipeline.EXPECT().GetPipelineRun(gomock.Any(), 5).Return(entities.PipelineRun{}, nil)
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			requestMethod: http.MethodGet,
+			requestPath:   "/secrets",
+			mockBehavior: func(m mocks) {
+				m.secret.EXPECT().GetSecrets().Return(nil, nil)
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			requestMethod: http.MethodDelete,
+			requestPath:   "/secrets/db_password",
+			mockBehavior: func(m mocks) {
+				m.secret.EXPECT().DeleteSecret(gomock.Any(), "db_password").Return(nil)
+			},
+			expectedStatusCode: http.StatusNoContent,
+		},
+		{
+			requestMethod: http.MethodGet,
+			requestPath:   "/auth/keys",
+			mockBehavior: func(m mocks) {
+				m.auth.EXPECT().GetApiKeys().Return(nil, nil)
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			requestMethod: http.MethodDelete,
+			requestPath:   "/auth/keys/2",
+			mockBehavior: func(m mocks) {
+				m.auth.EXPECT().DeleteApiKey(gomock.Any(), 2).Return(nil)
+			},
+			expectedStatusCode: http.StatusNoContent,
+		},
+		{
+			requestMethod: http.MethodGet,
+			requestPath:   "/audit",
+			mockBehavior: func(m mocks) {
+				m.audit.EXPECT().GetAuditEvents(entities.AuditFilter{}).Return(nil, nil)
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.requestMethod+" "+test.requestPath, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			m := mocks{
+				command:  mock_service.NewMockCommand(c),
+				schedule: mock_service.NewMockSchedule(c),
+				pipeline: mock_service.NewMockPipeline(c),
+				secret:   mock_service.NewMockSecret(c),
+				auth:     mock_service.NewMockAuth(c),
+				audit:    mock_service.NewMockAudit(c),
+			}
+			m.auth.EXPECT().Authenticate("tx_key").
+				Return(entities.Principal{Name: "root", Role: entities.RoleAdmin}, nil)
+			if test.mockBehavior != nil {
+				test.mockBehavior(m)
+			}
+
+			srv := &service.Service{
+				Command:  m.command,
+				Schedule: m.schedule,
+				Pipeline: m.pipeline,
+				Secret:   m.secret,
+				Auth:     m.auth,
+				Audit:    m.audit,
+			}
+			router := New(srv, slogdiscard.NewDiscardLogger(), nil)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(test.requestMethod, test.requestPath, nil)
+			req.Header.Set("Authorization", "Bearer tx_key")
+			router.Mux.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatusCode, w.Code)
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"testex/internal/entities"
+	sl "testex/pkg/slog"
+)
+
+func (router Router) schedules(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		schedules, err := router.Service.GetSchedules(r.Context())
+		if err != nil {
+			e := newError("failed to get schedules", http.StatusInternalServerError)
+			http.Error(w, e.ToJson(), e.StatusCode)
+			router.Logger.Error(e.Message, sl.Err(err))
+			return
+		}
+		sendJSONResponse(w, http.StatusOK, schedules)
+	case http.MethodPost:
+		var scheduleDto entities.ScheduleDto
+		if err := json.NewDecoder(r.Body).Decode(&scheduleDto); err != nil {
+			e := newError("failed to parse request body", http.StatusBadRequest)
+			http.Error(w, e.ToJson(), e.StatusCode)
+			router.Logger.Error(e.Message, sl.Err(err))
+			return
+		}
+		defer r.Body.Close()
+		id, err := router.Service.CreateSchedule(r.Context(), scheduleDto)
+		if errors.Is(err, entities.ErrInvalidParams) {
+			e := newError(err.Error(), http.StatusBadRequest)
+			http.Error(w, e.ToJson(), e.StatusCode)
+			return
+		}
+		if err != nil {
+			e := newError("failed to add new schedule", http.StatusInternalServerError)
+			http.Error(w, e.ToJson(), e.StatusCode)
+			router.Logger.Error(e.Message, sl.Err(err))
+			return
+		}
+		sendJSONResponse(w, http.StatusCreated, entities.CommandIDResponse{Id: id})
+	case http.MethodOptions:
+		w.Header().Set("Allow", "GET, POST, OPTIONS")
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		methodNotAllowed(w)
+	}
+}
+
+func (router Router) schedule(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		e := newError("wrong id format", http.StatusBadRequest)
+		http.Error(w, e.ToJson(), e.StatusCode)
+		router.Logger.Error(e.Message, sl.Err(err))
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		schedule, err := router.Service.GetSchedule(r.Context(), id)
+		if err != nil {
+			router.serviceError(w, "schedule", "failed to get schedule", err)
+			return
+		}
+		sendJSONResponse(w, http.StatusOK, schedule)
+	case http.MethodPut:
+		var scheduleDto entities.ScheduleDto
+		if err := json.NewDecoder(r.Body).Decode(&scheduleDto); err != nil {
+			e := newError("failed to parse request body", http.StatusBadRequest)
+			http.Error(w, e.ToJson(), e.StatusCode)
+			router.Logger.Error(e.Message, sl.Err(err))
+			return
+		}
+		defer r.Body.Close()
+		if err := router.Service.UpdateSchedule(r.Context(), id, scheduleDto); err != nil {
+			router.serviceError(w, "schedule", "failed to update schedule", err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodDelete:
+		if err := router.Service.DeleteSchedule(r.Context(), id); err != nil {
+			router.serviceError(w, "schedule", "failed to delete schedule", err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodOptions:
+		w.Header().Set("Allow", "GET, PUT, DELETE, OPTIONS")
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		methodNotAllowed(w)
+	}
+}
@@ -0,0 +1,191 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testex/internal/entities"
+	"testex/internal/service"
+	mock_service "testex/internal/service/mocks"
+	"testex/pkg/slog/slogdiscard"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRouter_schedules(t *testing.T) {
+	type mockBehavior func(r *mock_service.MockSchedule)
+
+	tests := []struct {
+		name                 string
+		requestMethod        string
+		requestBody          string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:          "Create",
+			requestMethod: http.MethodPost,
+			requestBody:   `{"alias": "backup", "cron": "0 3 * * *", "timezone": "Europe/Moscow", "skip_if_running": true}`,
+			mockBehavior: func(r *mock_service.MockSchedule) {
+				r.EXPECT().CreateSchedule(gomock.Any(), entities.ScheduleDto{
+					Alias:         "backup",
+					Cron:          "0 3 * * *",
+					Timezone:      "Europe/Moscow",
+					SkipIfRunning: true,
+				}).Return(1, nil)
+			},
+			expectedStatusCode:   http.StatusCreated,
+			expectedResponseBody: `{"id":1}`,
+		},
+		{
+			name:          "Create_InvalidCron",
+			requestMethod: http.MethodPost,
+			requestBody:   `{"alias": "backup", "cron": "every day"}`,
+			mockBehavior: func(r *mock_service.MockSchedule) {
+				r.EXPECT().CreateSchedule(gomock.Any(), entities.ScheduleDto{Alias: "backup", Cron: "every day"}).
+					Return(-1, fmt.Errorf("%w: expected exactly 5 fields", entities.ErrInvalidParams))
+			},
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedResponseBody: `{"message":"invalid command params: expected exactly 5 fields","status_code":400}`,
+		},
+		{
+			name:          "GetAll",
+			requestMethod: http.MethodGet,
+			mockBehavior: func(r *mock_service.MockSchedule) {
+				r.EXPECT().GetSchedules(gomock.Any()).Return([]entities.Schedule{}, nil)
+			},
+			expectedStatusCode:   http.StatusOK,
+			expectedResponseBody: `[]`,
+		},
+		{
+			name:          "GetAll_Error",
+			requestMethod: http.MethodGet,
+			mockBehavior: func(r *mock_service.MockSchedule) {
+				r.EXPECT().GetSchedules(gomock.Any()).Return(nil, errors.New("db is down"))
+			},
+			expectedStatusCode:   http.StatusInternalServerError,
+			expectedResponseBody: `{"message":"failed to get schedules","status_code":500}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			repo := mock_service.NewMockSchedule(c)
+			if test.mockBehavior != nil {
+				test.mockBehavior(repo)
+			}
+
+			srv := &service.Service{Schedule: repo}
+			mux := http.NewServeMux()
+			handler := &Router{Service: srv, Logger: slogdiscard.NewDiscardLogger(), Mux: mux}
+			mux.HandleFunc("/schedules", handler.schedules)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(test.requestMethod, "/schedules", bytes.NewBufferString(test.requestBody))
+			mux.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatusCode, w.Code)
+			assert.Equal(t, test.expectedResponseBody, strings.TrimSpace(w.Body.String()))
+		})
+	}
+}
+
+func TestRouter_schedule(t *testing.T) {
+	type mockBehavior func(r *mock_service.MockSchedule)
+
+	tests := []struct {
+		name                 string
+		requestMethod        string
+		requestId            string
+		requestBody          string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:          "Get",
+			requestMethod: http.MethodGet,
+			requestId:     "1",
+			mockBehavior: func(r *mock_service.MockSchedule) {
+				r.EXPECT().GetSchedule(gomock.Any(), 1).Return(entities.Schedule{
+					Id: 1, Alias: "backup", Cron: "@daily", Timezone: "UTC", Enabled: true,
+				}, nil)
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedResponseBody: `{"id":1,"alias":"backup","cron":"@daily","timezone":"UTC","enabled":true,` +
+				`"skip_if_running":false,"catch_up":false,"created_at":"0001-01-01T00:00:00Z"}`,
+		},
+		{
+			name:          "Get_NotFound",
+			requestMethod: http.MethodGet,
+			requestId:     "2",
+			mockBehavior: func(r *mock_service.MockSchedule) {
+				r.EXPECT().GetSchedule(gomock.Any(), 2).Return(entities.Schedule{}, entities.ErrNotFound)
+			},
+			expectedStatusCode:   http.StatusNotFound,
+			expectedResponseBody: `{"message":"schedule not found","status_code":404}`,
+		},
+		{
+			name:          "Update",
+			requestMethod: http.MethodPut,
+			requestId:     "1",
+			requestBody:   `{"alias": "backup", "cron": "@hourly", "enabled": false}`,
+			mockBehavior: func(r *mock_service.MockSchedule) {
+				enabled := false
+				r.EXPECT().UpdateSchedule(gomock.Any(), 1,
+					entities.ScheduleDto{Alias: "backup", Cron: "@hourly", Enabled: &enabled}).
+					Return(nil)
+			},
+			expectedStatusCode: http.StatusNoContent,
+		},
+		{
+			name:          "Delete",
+			requestMethod: http.MethodDelete,
+			requestId:     "1",
+			mockBehavior: func(r *mock_service.MockSchedule) {
+				r.EXPECT().DeleteSchedule(gomock.Any(), 1).Return(nil)
+			},
+			expectedStatusCode: http.StatusNoContent,
+		},
+		{
+			name:                 "WrongId",
+			requestMethod:        http.MethodGet,
+			requestId:            "abc",
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedResponseBody: `{"message":"wrong id format","status_code":400}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			repo := mock_service.NewMockSchedule(c)
+			if test.mockBehavior != nil {
+				test.mockBehavior(repo)
+			}
+
+			srv := &service.Service{Schedule: repo}
+			mux := http.NewServeMux()
+			handler := &Router{Service: srv, Logger: slogdiscard.NewDiscardLogger(), Mux: mux}
+			mux.HandleFunc("/schedules/{id}", handler.schedule)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(test.requestMethod, "/schedules/"+test.requestId, bytes.NewBufferString(test.requestBody))
+			mux.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatusCode, w.Code)
+			assert.Equal(t, test.expectedResponseBody, strings.TrimSpace(w.Body.String()))
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"testex/internal/entities"
+	sl "testex/pkg/slog"
+)
+
+// secrets lists secrets. Values are write-only and never sent back.
+func (router Router) secrets(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		secrets, err := router.Service.GetSecrets()
+		if err != nil {
+			router.serviceError(w, "secret", "failed to get secrets", err)
+			return
+		}
+		sendJSONResponse(w, http.StatusOK, secrets)
+	case http.MethodOptions:
+		w.Header().Set("Allow", "GET, OPTIONS")
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		methodNotAllowed(w)
+	}
+}
+
+func (router Router) secret(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	switch r.Method {
+	case http.MethodPut:
+		var secretDto entities.SecretDto
+		if err := json.NewDecoder(r.Body).Decode(&secretDto); err != nil {
+			e := newError("failed to parse request body", http.StatusBadRequest)
+			http.Error(w, e.ToJson(), e.StatusCode)
+			router.Logger.Error(e.Message, sl.Err(err))
+			return
+		}
+		defer r.Body.Close()
+		if err := router.Service.PutSecret(r.Context(), name, secretDto); err != nil {
+			router.serviceError(w, "secret", "failed to save secret", err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodDelete:
+		if err := router.Service.DeleteSecret(r.Context(), name); err != nil {
+			router.serviceError(w, "secret", "failed to delete secret", err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodOptions:
+		w.Header().Set("Allow", "PUT, DELETE, OPTIONS")
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		methodNotAllowed(w)
+	}
+}
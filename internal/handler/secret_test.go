@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testex/internal/entities"
+	"testex/internal/service"
+	mock_service "testex/internal/service/mocks"
+	"testex/pkg/slog/slogdiscard"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRouter_secrets(t *testing.T) {
+	type mockBehavior func(r *mock_service.MockSecret)
+	created := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name                 string
+		requestMethod        string
+		requestPath          string
+		requestBody          string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:          "GetAll",
+			requestMethod: http.MethodGet,
+			requestPath:   "/secrets",
+			mockBehavior: func(r *mock_service.MockSecret) {
+				r.EXPECT().GetSecrets().Return([]entities.Secret{
+					{Id: 1, Name: "github_token", Value: []byte("sealed"), CreatedAt: created, UpdatedAt: created},
+				}, nil)
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedResponseBody: `[{"id":1,"name":"github_token","created_at":"2024-05-01T10:00:00Z",` +
+				`"updated_at":"2024-05-01T10:00:00Z"}]`,
+		},
+		{
+			name:          "Put",
+			requestMethod: http.MethodPut,
+			requestPath:   "/secrets/github_token",
+			requestBody:   `{"value": "ghp_123"}`,
+			mockBehavior: func(r *mock_service.MockSecret) {
+				r.EXPECT().PutSecret(gomock.Any(), "github_token", entities.SecretDto{Value: "ghp_123"}).Return(nil)
+			},
+			expectedStatusCode: http.StatusNoContent,
+		},
+		{
+			name:          "Put_Invalid",
+			requestMethod: http.MethodPut,
+			requestPath:   "/secrets/github_token",
+			requestBody:   `{"value": ""}`,
+			mockBehavior: func(r *mock_service.MockSecret) {
+				r.EXPECT().PutSecret(gomock.Any(), "github_token", entities.SecretDto{}).
+					Return(fmt.Errorf("%w: secret value must be from 1 to 65536 bytes long", entities.ErrInvalidParams))
+			},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponseBody: `{"message":"invalid command params: secret value must be from 1 to 65536 bytes long",` +
+				`"status_code":400}`,
+		},
+		{
+			name:          "Delete_NotFound",
+			requestMethod: http.MethodDelete,
+			requestPath:   "/secrets/missing",
+			mockBehavior: func(r *mock_service.MockSecret) {
+				r.EXPECT().DeleteSecret(gomock.Any(), "missing").Return(entities.ErrNotFound)
+			},
+			expectedStatusCode:   http.StatusNotFound,
+			expectedResponseBody: `{"message":"secret not found","status_code":404}`,
+		},
+		{
+			name:                 "Get_NotAllowed",
+			requestMethod:        http.MethodGet,
+			requestPath:          "/secrets/github_token",
+			expectedStatusCode:   http.StatusMethodNotAllowed,
+			expectedResponseBody: `{"message":"method not allowed","status_code":405}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			repo := mock_service.NewMockSecret(c)
+			if test.mockBehavior != nil {
+				test.mockBehavior(repo)
+			}
+
+			srv := &service.Service{Secret: repo}
+			mux := http.NewServeMux()
+			handler := &Router{Service: srv, Logger: slogdiscard.NewDiscardLogger(), Mux: mux}
+			mux.HandleFunc("/secrets", handler.secrets)
+			mux.HandleFunc("/secrets/{name}", handler.secret)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(test.requestMethod, test.requestPath, bytes.NewBufferString(test.requestBody))
+			mux.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatusCode, w.Code)
+			assert.Equal(t, test.expectedResponseBody, strings.TrimSpace(w.Body.String()))
+		})
+	}
+}
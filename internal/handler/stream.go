@@ -0,0 +1,246 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"testex/internal/entities"
+	sl "testex/pkg/slog"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	keepAliveInterval = 15 * time.Second
+	resubscribeDelay  = time.Second
+)
+
+type streamMessage struct {
+	Type      string                    `json:"type"`
+	Log       *entities.Log             `json:"log,omitempty"`
+	Execution *entities.ExecutedCommand `json:"execution,omitempty"`
+}
+
+// streamLogs follows the logs of an execution as Server-Sent Events. Each log
+// is sent as a "log" event with its seq as the event id, so reconnecting
+// clients resume with Last-Event-ID. The stream ends with an "exit" event
+// carrying the execution.
+func (router Router) streamLogs(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		id, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil {
+			e := newError("wrong id format", http.StatusBadRequest)
+			http.Error(w, e.ToJson(), e.StatusCode)
+			router.Logger.Error(e.Message, sl.Err(err))
+			return
+		}
+		lastSeq, err := lastEventId(r)
+		if err != nil {
+			e := newError("wrong last event id format", http.StatusBadRequest)
+			http.Error(w, e.ToJson(), e.StatusCode)
+			return
+		}
+		// Errors can't be reported once the stream has started.
+		if _, err = router.Service.GetExecutedCommand(r.Context(), id); err != nil {
+			router.serviceError(w, "execution", "failed to get execution", err)
+			return
+		}
+
+		// Streams outlive the server write timeout.
+		rc := http.NewResponseController(w)
+		_ = rc.SetWriteDeadline(time.Time{})
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.WriteHeader(http.StatusOK)
+		_ = rc.Flush()
+
+		execution, err := router.followLogs(r.Context(), id, lastSeq, func(log entities.Log) error {
+			data, _ := json.Marshal(log)
+			if _, err := fmt.Fprintf(w, "id: %d\nevent: log\ndata: %s\n\n", log.Seq, data); err != nil {
+				return err
+			}
+			return rc.Flush()
+		}, func() error {
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return err
+			}
+			return rc.Flush()
+		})
+		if err != nil {
+			router.Logger.Error("failed to stream logs", sl.Err(err))
+			return
+		}
+		data, _ := json.Marshal(execution)
+		fmt.Fprintf(w, "event: exit\ndata: %s\n\n", data)
+		_ = rc.Flush()
+	case http.MethodOptions:
+		w.Header().Set("Allow", "GET, POST, OPTIONS")
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		methodNotAllowed(w)
+	}
+}
+
+// streamLogsWS is the WebSocket variant of streamLogs. Messages are JSON
+// objects of type "log" and a final "exit"; clients resume with ?after=<seq>.
+func (router Router) streamLogsWS(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		e := newError("wrong id format", http.StatusBadRequest)
+		http.Error(w, e.ToJson(), e.StatusCode)
+		router.Logger.Error(e.Message, sl.Err(err))
+		return
+	}
+	var lastSeq int64
+	if after := r.URL.Query().Get("after"); after != "" {
+		if lastSeq, err = strconv.ParseInt(after, 10, 64); err != nil {
+			e := newError("wrong after format", http.StatusBadRequest)
+			http.Error(w, e.ToJson(), e.StatusCode)
+			return
+		}
+	}
+
+	if _, err = router.Service.GetExecutedCommand(r.Context(), id); err != nil {
+		router.serviceError(w, "execution", "failed to get execution", err)
+		return
+	}
+
+	upgrader := websocket.Upgrader{CheckOrigin: router.checkOrigin}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		router.Logger.Error("failed to upgrade connection", sl.Err(err))
+		return
+	}
+	defer conn.Close()
+
+	// Reading is needed to notice the client going away.
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	go func() {
+		defer cancel()
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	execution, err := router.followLogs(ctx, id, lastSeq, func(log entities.Log) error {
+		return conn.WriteJSON(streamMessage{Type: "log", Log: &log})
+	}, func() error {
+		return conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(keepAliveInterval))
+	})
+	if err != nil {
+		router.Logger.Error("failed to stream logs", sl.Err(err))
+		return
+	}
+	_ = conn.WriteJSON(streamMessage{Type: "exit", Execution: &execution})
+	_ = conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+}
+
+// checkOrigin lets pages open log websockets only from the origin of the API
+// itself or from the allowed ones. The token may be passed in the URL, so any
+// page holding such a link could read the logs otherwise. Clients other than
+// browsers send no Origin.
+func (router Router) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if slices.Contains(router.AllowedOrigins, origin) {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
+
+// followLogs replays the stored logs of an execution after lastSeq and then
+// emits new ones as they are produced, until the execution finishes.
+func (router Router) followLogs(ctx context.Context, id int, lastSeq int64, emit func(entities.Log) error, ping func() error) (entities.ExecutedCommand, error) {
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+
+	send := func(log entities.Log) error {
+		if log.Seq <= lastSeq {
+			return nil
+		}
+		lastSeq = log.Seq
+		return emit(log)
+	}
+	replay := func() error {
+		stored, err := router.Service.GetLogs(ctx, id, entities.LogFilter{AfterSeq: lastSeq})
+		if err != nil {
+			return err
+		}
+		for _, log := range stored {
+			if err = send(log); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for {
+		// Subscribe before reading stored logs, so that nothing produced in
+		// between is missed. Lines seen twice are skipped by seq.
+		live, unsubscribe, err := router.Service.FollowLogs(ctx, id)
+		if err != nil {
+			return entities.ExecutedCommand{}, err
+		}
+		err = replay()
+	follow:
+		for err == nil {
+			select {
+			case <-ctx.Done():
+				err = ctx.Err()
+			case <-keepAlive.C:
+				err = ping()
+			case log, ok := <-live:
+				if !ok {
+					break follow
+				}
+				err = send(log)
+			}
+		}
+		unsubscribe()
+		if err != nil {
+			return entities.ExecutedCommand{}, err
+		}
+
+		execution, err := router.Service.GetExecutedCommand(ctx, id)
+		if err != nil {
+			return entities.ExecutedCommand{}, err
+		}
+		if !execution.IsActive {
+			return execution, nil
+		}
+
+		// The follower fell behind, or the execution runs elsewhere: catch up
+		// from the stored logs.
+		select {
+		case <-ctx.Done():
+			return entities.ExecutedCommand{}, ctx.Err()
+		case <-time.After(resubscribeDelay):
+		}
+	}
+}
+
+func lastEventId(r *http.Request) (int64, error) {
+	value := r.Header.Get("Last-Event-ID")
+	if value == "" {
+		value = r.URL.Query().Get("last_event_id")
+	}
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.ParseInt(value, 10, 64)
+}
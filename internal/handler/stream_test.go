@@ -0,0 +1,162 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testex/internal/entities"
+	"testex/internal/service"
+	mock_service "testex/internal/service/mocks"
+	"testex/pkg/slog/slogdiscard"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRouter_streamLogs(t *testing.T) {
+	// Init Test Table
+	type mockBehavior func(r *mock_service.MockCommand, id int)
+
+	code := 0
+	storedLogs := []entities.Log{
+		{Id: 1, ExecutedCommandId: 1, Seq: 1, Stream: entities.StreamStdout, Level: entities.LevelInfo, Message: "log1"},
+		{Id: 2, ExecutedCommandId: 1, Seq: 2, Stream: entities.StreamStdout, Level: entities.LevelInfo, Message: "log2"},
+	}
+
+	tests := []struct {
+		name                 string
+		requestID            string
+		lastEventID          string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:      "StreamLogs_FinishedExecution",
+			requestID: "1",
+			mockBehavior: func(r *mock_service.MockCommand, id int) {
+				live := make(chan entities.Log)
+				close(live)
+				r.EXPECT().FollowLogs(gomock.Any(), id).Return((<-chan entities.Log)(live), func() {}, nil)
+				r.EXPECT().GetLogs(gomock.Any(), id, entities.LogFilter{}).Return(storedLogs, nil)
+				r.EXPECT().GetExecutedCommand(gomock.Any(), id).Return(entities.ExecutedCommand{
+					Id: 1, CommandId: 1, Status: entities.StatusSucceeded, ExitCode: &code,
+				}, nil).Times(2)
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedResponseBody: "id: 1\nevent: log\ndata: {\"id\":1,\"executed_command_id\":1,\"seq\":1,\"stream\":\"stdout\",\"level\":\"info\",\"message\":\"log1\",\"date\":\"0001-01-01T00:00:00Z\"}\n\n" +
+				"id: 2\nevent: log\ndata: {\"id\":2,\"executed_command_id\":1,\"seq\":2,\"stream\":\"stdout\",\"level\":\"info\",\"message\":\"log2\",\"date\":\"0001-01-01T00:00:00Z\"}\n\n" +
+				"event: exit\ndata: {\"id\":1,\"command_id\":1,\"pid\":0,\"is_active\":false,\"status\":\"succeeded\",\"started_at\":\"0001-01-01T00:00:00Z\",\"exit_code\":0}\n\n",
+		},
+		{
+			name:        "StreamLogs_LiveAndResume",
+			requestID:   "1",
+			lastEventID: "1",
+			mockBehavior: func(r *mock_service.MockCommand, id int) {
+				live := make(chan entities.Log, 2)
+				live <- storedLogs[1]
+				live <- entities.Log{Id: 3, ExecutedCommandId: 1, Seq: 3, Stream: entities.StreamStderr, Level: entities.LevelError, Message: "log3"}
+				close(live)
+				r.EXPECT().FollowLogs(gomock.Any(), id).Return((<-chan entities.Log)(live), func() {}, nil)
+				r.EXPECT().GetLogs(gomock.Any(), id, entities.LogFilter{AfterSeq: 1}).Return(storedLogs[1:], nil)
+				r.EXPECT().GetExecutedCommand(gomock.Any(), id).Return(entities.ExecutedCommand{
+					Id: 1, CommandId: 1, Status: entities.StatusSucceeded, ExitCode: &code,
+				}, nil).Times(2)
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedResponseBody: "id: 2\nevent: log\ndata: {\"id\":2,\"executed_command_id\":1,\"seq\":2,\"stream\":\"stdout\",\"level\":\"info\",\"message\":\"log2\",\"date\":\"0001-01-01T00:00:00Z\"}\n\n" +
+				"id: 3\nevent: log\ndata: {\"id\":3,\"executed_command_id\":1,\"seq\":3,\"stream\":\"stderr\",\"level\":\"error\",\"message\":\"log3\",\"date\":\"0001-01-01T00:00:00Z\"}\n\n" +
+				"event: exit\ndata: {\"id\":1,\"command_id\":1,\"pid\":0,\"is_active\":false,\"status\":\"succeeded\",\"started_at\":\"0001-01-01T00:00:00Z\",\"exit_code\":0}\n\n",
+		},
+		{
+			name:      "StreamLogs_FollowFailed",
+			requestID: "1",
+			mockBehavior: func(r *mock_service.MockCommand, id int) {
+				r.EXPECT().GetExecutedCommand(gomock.Any(), id).Return(entities.ExecutedCommand{Id: 1, IsActive: true}, nil)
+				r.EXPECT().FollowLogs(gomock.Any(), id).Return(nil, nil, errors.New("failed to follow logs"))
+			},
+			expectedStatusCode:   http.StatusOK,
+			expectedResponseBody: "",
+		},
+		{
+			name:      "StreamLogs_Forbidden",
+			requestID: "1",
+			mockBehavior: func(r *mock_service.MockCommand, id int) {
+				r.EXPECT().GetExecutedCommand(gomock.Any(), id).Return(entities.ExecutedCommand{},
+					fmt.Errorf("%w: view_logs of command drop-db is not permitted", entities.ErrForbidden))
+			},
+			expectedStatusCode: http.StatusForbidden,
+			expectedResponseBody: "{\"message\":\"forbidden: view_logs of command drop-db is not permitted\"," +
+				"\"status_code\":403}\n",
+		},
+		{
+			name:                 "StreamLogs_BadRequest",
+			requestID:            "invalid",
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedResponseBody: "{\"message\":\"wrong id format\",\"status_code\":400}\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Init Controller
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			// Init Mock Service
+			repo := mock_service.NewMockCommand(c)
+			if test.mockBehavior != nil {
+				test.mockBehavior(repo, 1)
+			}
+			// Init Service and Handler
+			srv := &service.Service{Command: repo}
+			logger := slogdiscard.NewDiscardLogger()
+			mux := http.NewServeMux()
+			handler := &Router{Service: srv, Logger: logger, Mux: mux}
+			mux.HandleFunc("/executions/{id}/logs/stream", handler.streamLogs)
+
+			// Create Request
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/executions/"+test.requestID+"/logs/stream", nil)
+			if test.lastEventID != "" {
+				req.Header.Set("Last-Event-ID", test.lastEventID)
+			}
+
+			// Make Request
+			mux.ServeHTTP(w, req)
+
+			// Assert
+			assert.Equal(t, test.expectedStatusCode, w.Code)
+			assert.Equal(t, test.expectedResponseBody, w.Body.String())
+		})
+	}
+}
+
+func TestRouter_checkOrigin(t *testing.T) {
+	tests := []struct {
+		name     string
+		origin   string
+		expected bool
+	}{
+		{name: "NoOrigin", expected: true},
+		{name: "SameOrigin", origin: "http://testex.local:8080", expected: true},
+		{name: "SameOriginCase", origin: "http://TESTEX.local:8080", expected: true},
+		{name: "Allowed", origin: "https://dashboard.example.com", expected: true},
+		{name: "OtherPort", origin: "http://testex.local:9090"},
+		{name: "OtherSite", origin: "https://evil.example.com"},
+		{name: "Malformed", origin: "http://%zz"},
+	}
+
+	handler := &Router{AllowedOrigins: []string{"https://dashboard.example.com"}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "http://testex.local:8080/executions/1/logs/ws", nil)
+			if test.origin != "" {
+				req.Header.Set("Origin", test.origin)
+			}
+			assert.Equal(t, test.expected, handler.checkOrigin(req))
+		})
+	}
+}
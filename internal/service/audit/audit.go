@@ -0,0 +1,136 @@
+package audit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"testex/internal/entities"
+	"testex/internal/storage"
+	sl "testex/pkg/slog"
+)
+
+const (
+	defaultLimit = 100
+	maxLimit     = 1000
+	// exportBatch is the number of events read at once by Export.
+	exportBatch = 1000
+	// system is the actor of actions the server takes by itself.
+	system = "system"
+)
+
+// Recorder writes actions to the audit trail.
+type Recorder interface {
+	Record(ctx context.Context, action, target string, payload any, err error)
+}
+
+// Discard is a Recorder that records nothing.
+var Discard Recorder = discard{}
+
+type discard struct{}
+
+func (discard) Record(context.Context, string, string, any, error) {}
+
+type Service struct {
+	Storage *storage.Storage
+	Logger  *slog.Logger
+}
+
+func NewService(storage *storage.Storage, logger *slog.Logger) *Service {
+	return &Service{
+		Storage: storage,
+		Logger:  logger,
+	}
+}
+
+// Record appends an event for an action of the principal of ctx. The action
+// has already happened, so failing to record it is only logged.
+func (s *Service) Record(ctx context.Context, action, target string, payload any, err error) {
+	event := entities.AuditEvent{
+		Actor:    system,
+		SourceIp: entities.SourceIpFromContext(ctx),
+		Action:   action,
+		Target:   target,
+		Outcome:  outcome(err),
+	}
+	if principal, ok := entities.PrincipalFromContext(ctx); ok {
+		event.Actor = principal.Name
+		event.Role = principal.Role
+	}
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			s.Logger.Error("failed to hash audit payload", slog.String("action", action), sl.Err(err))
+		} else {
+			sum := sha256.Sum256(data)
+			event.PayloadHash = hex.EncodeToString(sum[:])
+		}
+	}
+	if err != nil {
+		event.Error = err.Error()
+	}
+	if err := s.Storage.SaveAuditEvent(event); err != nil {
+		s.Logger.Error("failed to record audit event", slog.String("action", action), slog.String("target", target),
+			slog.String("actor", event.Actor), sl.Err(err))
+	}
+}
+
+// Target names an entity by its collection and id, or the collection alone
+// when the entity wasn't created.
+func Target(collection string, id int) string {
+	if id <= 0 {
+		return collection
+	}
+	return collection + "/" + strconv.Itoa(id)
+}
+
+func outcome(err error) entities.AuditOutcome {
+	switch {
+	case err == nil:
+		return entities.AuditSuccess
+	case errors.Is(err, entities.ErrForbidden), errors.Is(err, entities.ErrUnauthorized):
+		return entities.AuditDenied
+	default:
+		return entities.AuditFailure
+	}
+}
+
+// GetAuditEvents returns a page of events matching the filter, newest first.
+func (s *Service) GetAuditEvents(filter entities.AuditFilter) ([]entities.AuditEvent, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = defaultLimit
+	}
+	if filter.Limit > maxLimit {
+		return nil, fmt.Errorf("%w: limit must not exceed %d", entities.ErrInvalidParams, maxLimit)
+	}
+	filter.Ascending = false
+	return s.Storage.GetAuditEvents(filter)
+}
+
+// ExportAuditEvents writes all events matching the filter to w as JSON Lines,
+// oldest first.
+func (s *Service) ExportAuditEvents(filter entities.AuditFilter, w io.Writer) error {
+	filter.Limit = exportBatch
+	filter.Ascending = true
+	encoder := json.NewEncoder(w)
+	for {
+		events, err := s.Storage.GetAuditEvents(filter)
+		if err != nil {
+			return err
+		}
+		for _, event := range events {
+			if err = encoder.Encode(event); err != nil {
+				return err
+			}
+		}
+		if len(events) < exportBatch {
+			return nil
+		}
+		filter.AfterId = events[len(events)-1].Id
+	}
+}
@@ -0,0 +1,92 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testex/internal/entities"
+	"testex/internal/storage"
+	"testex/pkg/slog/slogdiscard"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeEvents keeps audit events in memory, ordered by id.
+type fakeEvents struct {
+	storage.AuditRepository
+	events []entities.AuditEvent
+}
+
+func (f *fakeEvents) SaveAuditEvent(event entities.AuditEvent) error {
+	event.Id = int64(len(f.events) + 1)
+	f.events = append(f.events, event)
+	return nil
+}
+
+func (f *fakeEvents) GetAuditEvents(filter entities.AuditFilter) ([]entities.AuditEvent, error) {
+	var events []entities.AuditEvent
+	for _, event := range f.events {
+		if event.Id > filter.AfterId && len(events) < filter.Limit {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+func TestService_Record(t *testing.T) {
+	repo := &fakeEvents{}
+	s := NewService(&storage.Storage{AuditRepository: repo}, slogdiscard.NewDiscardLogger())
+
+	ctx := entities.ContextWithPrincipal(entities.ContextWithSourceIp(context.Background(), "192.0.2.1"),
+		entities.Principal{Name: "ci", Role: entities.RoleOperator})
+	s.Record(ctx, "command.execute", "commands/deploy", entities.ExecuteCommandDto{Alias: "deploy"}, nil)
+	s.Record(ctx, "command.stop", "executions/7", nil,
+		fmt.Errorf("%w: stop of command deploy is not permitted", entities.ErrForbidden))
+	s.Record(context.Background(), "command.execute", "commands/backup", nil, errors.New("queue is full"))
+
+	assert.Len(t, repo.events, 3)
+	first := repo.events[0]
+	assert.Equal(t, "ci", first.Actor)
+	assert.Equal(t, entities.RoleOperator, first.Role)
+	assert.Equal(t, "192.0.2.1", first.SourceIp)
+	assert.Equal(t, entities.AuditSuccess, first.Outcome)
+	assert.Len(t, first.PayloadHash, 64)
+	assert.Empty(t, first.Error)
+
+	assert.Equal(t, entities.AuditDenied, repo.events[1].Outcome)
+	assert.Empty(t, repo.events[1].PayloadHash)
+
+	third := repo.events[2]
+	assert.Equal(t, "system", third.Actor)
+	assert.Empty(t, third.SourceIp)
+	assert.Equal(t, entities.AuditFailure, third.Outcome)
+	assert.Equal(t, "queue is full", third.Error)
+}
+
+func TestService_ExportAuditEvents(t *testing.T) {
+	repo := &fakeEvents{}
+	s := NewService(&storage.Storage{AuditRepository: repo}, slogdiscard.NewDiscardLogger())
+	for i := 0; i < exportBatch+1; i++ {
+		s.Record(context.Background(), "schedule.delete", Target("schedules", i+1), nil, nil)
+	}
+
+	var buf bytes.Buffer
+	assert.NoError(t, s.ExportAuditEvents(entities.AuditFilter{}, &buf))
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	assert.Len(t, lines, exportBatch+1)
+
+	var last entities.AuditEvent
+	assert.NoError(t, json.Unmarshal([]byte(lines[len(lines)-1]), &last))
+	assert.Equal(t, int64(exportBatch+1), last.Id)
+	assert.Equal(t, "schedules/1001", last.Target)
+}
+
+func TestService_GetAuditEvents(t *testing.T) {
+	s := NewService(&storage.Storage{AuditRepository: &fakeEvents{}}, slogdiscard.NewDiscardLogger())
+	_, err := s.GetAuditEvents(entities.AuditFilter{Limit: maxLimit + 1})
+	assert.ErrorIs(t, err, entities.ErrInvalidParams)
+}
@@ -0,0 +1,159 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"testex/internal/config"
+	"testex/internal/entities"
+	"testex/internal/service/audit"
+	"testex/internal/storage"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// keyPrefix starts every API key, to tell them from JWTs and to make them
+	// easy to find in leaked files.
+	keyPrefix = "tx_"
+	// shownPrefixLength is the length of the beginning of a key kept to tell
+	// keys apart.
+	shownPrefixLength = 11
+	maxKeyNameLength  = 255
+)
+
+// claims are the claims of a bearer JWT, the subject names the principal.
+type claims struct {
+	Role   entities.Role   `json:"role"`
+	Groups entities.Groups `json:"groups,omitempty"`
+	jwt.RegisteredClaims
+}
+
+type Service struct {
+	Storage *storage.Storage
+	Logger  *slog.Logger
+	Config  config.Auth
+	Audit   audit.Recorder
+}
+
+func NewService(storage *storage.Storage, logger *slog.Logger, cfg config.Auth, recorder audit.Recorder) *Service {
+	return &Service{
+		Storage: storage,
+		Logger:  logger,
+		Config:  cfg,
+		Audit:   recorder,
+	}
+}
+
+// Authenticate returns the principal of a bearer token, which is either an
+// API key or a JWT. With auth disabled every request is made by an admin.
+func (s *Service) Authenticate(token string) (entities.Principal, error) {
+	if !s.Config.Enabled {
+		return entities.Principal{Name: "anonymous", Role: entities.RoleAdmin}, nil
+	}
+	if token == "" {
+		return entities.Principal{}, fmt.Errorf("%w: no token", entities.ErrUnauthorized)
+	}
+	if strings.Count(token, ".") == 2 {
+		return s.authenticateJWT(token)
+	}
+	return s.authenticateKey(token)
+}
+
+func (s *Service) authenticateKey(key string) (entities.Principal, error) {
+	bootstrap := s.Config.BootstrapKey
+	if bootstrap != "" && subtle.ConstantTimeCompare([]byte(key), []byte(bootstrap)) == 1 {
+		return entities.Principal{Name: "bootstrap", Role: entities.RoleAdmin}, nil
+	}
+	if !strings.HasPrefix(key, keyPrefix) {
+		return entities.Principal{}, fmt.Errorf("%w: malformed key", entities.ErrUnauthorized)
+	}
+	apiKey, err := s.Storage.GetApiKeyByHash(hashKey(key))
+	if errors.Is(err, entities.ErrNotFound) {
+		return entities.Principal{}, fmt.Errorf("%w: unknown key", entities.ErrUnauthorized)
+	}
+	if err != nil {
+		return entities.Principal{}, err
+	}
+	return entities.Principal{Name: apiKey.Name, Role: apiKey.Role, Groups: apiKey.Groups}, nil
+}
+
+func (s *Service) authenticateJWT(token string) (entities.Principal, error) {
+	if s.Config.JWTSecret == "" {
+		return entities.Principal{}, fmt.Errorf("%w: tokens are not accepted", entities.ErrUnauthorized)
+	}
+	var c claims
+	_, err := jwt.ParseWithClaims(token, &c, func(*jwt.Token) (any, error) {
+		return []byte(s.Config.JWTSecret), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil {
+		return entities.Principal{}, fmt.Errorf("%w: %v", entities.ErrUnauthorized, err)
+	}
+	if c.Subject == "" || !c.Role.Valid() {
+		return entities.Principal{}, fmt.Errorf("%w: token has no subject or role", entities.ErrUnauthorized)
+	}
+	return entities.Principal{Name: c.Subject, Role: c.Role, Groups: c.Groups}, nil
+}
+
+// CreateApiKey generates a key. The key is returned once, only its hash is
+// stored.
+func (s *Service) CreateApiKey(ctx context.Context,
+	dto entities.ApiKeyDto) (created entities.CreatedApiKey, err error) {
+	defer func() { s.Audit.Record(ctx, "api_key.create", audit.Target("auth/keys", created.Id), dto, err) }()
+	if dto.Name == "" || len(dto.Name) > maxKeyNameLength {
+		return entities.CreatedApiKey{}, fmt.Errorf("%w: key name must be from 1 to %d bytes long",
+			entities.ErrInvalidParams, maxKeyNameLength)
+	}
+	if !dto.Role.Valid() {
+		return entities.CreatedApiKey{}, fmt.Errorf("%w: unknown role %q", entities.ErrInvalidParams, dto.Role)
+	}
+	if slices.Contains(dto.Groups, "") {
+		return entities.CreatedApiKey{}, fmt.Errorf("%w: group names must not be empty", entities.ErrInvalidParams)
+	}
+	key, err := generateKey()
+	if err != nil {
+		return entities.CreatedApiKey{}, err
+	}
+	apiKey, err := s.Storage.SaveApiKey(entities.ApiKey{
+		Name:   dto.Name,
+		Role:   dto.Role,
+		Prefix: key[:shownPrefixLength],
+		Hash:   hashKey(key),
+		Groups: dto.Groups,
+	})
+	if err != nil {
+		return entities.CreatedApiKey{}, err
+	}
+	return entities.CreatedApiKey{ApiKey: apiKey, Key: key}, nil
+}
+
+func (s *Service) GetApiKeys() ([]entities.ApiKey, error) {
+	return s.Storage.GetApiKeys()
+}
+
+func (s *Service) DeleteApiKey(ctx context.Context, id int) (err error) {
+	defer func() { s.Audit.Record(ctx, "api_key.delete", audit.Target("auth/keys", id), nil, err) }()
+	return s.Storage.DeleteApiKey(id)
+}
+
+func generateKey() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return keyPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashKey hashes a key for lookup. Keys are random, so a plain hash is enough.
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,120 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testex/internal/config"
+	"testex/internal/entities"
+	"testex/internal/service/audit"
+	"testex/internal/storage"
+	"testex/pkg/slog/slogdiscard"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeKeys keeps API keys in memory.
+type fakeKeys struct {
+	storage.AuthRepository
+	keys []entities.ApiKey
+}
+
+func (f *fakeKeys) SaveApiKey(key entities.ApiKey) (entities.ApiKey, error) {
+	key.Id = len(f.keys) + 1
+	f.keys = append(f.keys, key)
+	return key, nil
+}
+
+func (f *fakeKeys) GetApiKeyByHash(hash string) (entities.ApiKey, error) {
+	for _, key := range f.keys {
+		if key.Hash == hash {
+			return key, nil
+		}
+	}
+	return entities.ApiKey{}, entities.ErrNotFound
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, key any, c jwt.Claims) string {
+	token, err := jwt.NewWithClaims(method, c).SignedString(key)
+	assert.NoError(t, err)
+	return token
+}
+
+func TestService_ApiKeys(t *testing.T) {
+	repo := &fakeKeys{}
+	cfg := config.Auth{Enabled: true, BootstrapKey: "bootstrap-key"}
+	s := NewService(&storage.Storage{AuthRepository: repo}, slogdiscard.NewDiscardLogger(), cfg, audit.Discard)
+
+	created, err := s.CreateApiKey(context.Background(), entities.ApiKeyDto{Name: "ci", Role: entities.RoleOperator})
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(created.Key, keyPrefix))
+	assert.Equal(t, created.Key[:shownPrefixLength], created.Prefix)
+	assert.NotContains(t, repo.keys[0].Hash, created.Key)
+
+	principal, err := s.Authenticate(created.Key)
+	assert.NoError(t, err)
+	assert.Equal(t, entities.Principal{Name: "ci", Role: entities.RoleOperator}, principal)
+
+	principal, err = s.Authenticate("bootstrap-key")
+	assert.NoError(t, err)
+	assert.Equal(t, entities.RoleAdmin, principal.Role)
+
+	for _, token := range []string{"", "tx_unknown", "bootstrap"} {
+		_, err = s.Authenticate(token)
+		assert.True(t, errors.Is(err, entities.ErrUnauthorized), token)
+	}
+
+	_, err = s.CreateApiKey(context.Background(), entities.ApiKeyDto{Name: "ci", Role: "root"})
+	assert.True(t, errors.Is(err, entities.ErrInvalidParams), err)
+	_, err = s.CreateApiKey(context.Background(), entities.ApiKeyDto{Role: entities.RoleViewer})
+	assert.True(t, errors.Is(err, entities.ErrInvalidParams), err)
+
+	disabled := NewService(&storage.Storage{AuthRepository: repo}, slogdiscard.NewDiscardLogger(), config.Auth{},
+		audit.Discard)
+	principal, err = disabled.Authenticate("")
+	assert.NoError(t, err)
+	assert.Equal(t, entities.RoleAdmin, principal.Role)
+}
+
+func TestService_JWT(t *testing.T) {
+	secret := []byte("jwt-secret")
+	cfg := config.Auth{Enabled: true, JWTSecret: "jwt-secret"}
+	s := NewService(&storage.Storage{}, slogdiscard.NewDiscardLogger(), cfg, audit.Discard)
+	expires := jwt.NewNumericDate(time.Now().Add(time.Hour))
+
+	token := sign(t, jwt.SigningMethodHS256, secret, claims{Role: entities.RoleViewer, Groups: entities.Groups{"ops"},
+		RegisteredClaims: jwt.RegisteredClaims{Subject: "grafana", ExpiresAt: expires}})
+	principal, err := s.Authenticate(token)
+	assert.NoError(t, err)
+	assert.Equal(t, entities.Principal{Name: "grafana", Role: entities.RoleViewer, Groups: entities.Groups{"ops"}},
+		principal)
+
+	tests := map[string]string{
+		"WrongSecret": sign(t, jwt.SigningMethodHS256, []byte("other"),
+			claims{Role: entities.RoleViewer, RegisteredClaims: jwt.RegisteredClaims{Subject: "x", ExpiresAt: expires}}),
+		"WrongMethod": sign(t, jwt.SigningMethodHS512, secret,
+			claims{Role: entities.RoleViewer, RegisteredClaims: jwt.RegisteredClaims{Subject: "x", ExpiresAt: expires}}),
+		"NoExpiry": sign(t, jwt.SigningMethodHS256, secret,
+			claims{Role: entities.RoleViewer, RegisteredClaims: jwt.RegisteredClaims{Subject: "x"}}),
+		"Expired": sign(t, jwt.SigningMethodHS256, secret, claims{Role: entities.RoleViewer,
+			RegisteredClaims: jwt.RegisteredClaims{Subject: "x", ExpiresAt: jwt.NewNumericDate(time.Now().Add(-time.Hour))}}),
+		"UnknownRole": sign(t, jwt.SigningMethodHS256, secret,
+			claims{Role: "root", RegisteredClaims: jwt.RegisteredClaims{Subject: "x", ExpiresAt: expires}}),
+		"NoSubject": sign(t, jwt.SigningMethodHS256, secret,
+			claims{Role: entities.RoleViewer, RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: expires}}),
+	}
+	for name, token := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := s.Authenticate(token)
+			assert.True(t, errors.Is(err, entities.ErrUnauthorized), err)
+		})
+	}
+
+	noSecret := NewService(&storage.Storage{}, slogdiscard.NewDiscardLogger(), config.Auth{Enabled: true},
+		audit.Discard)
+	_, err = noSecret.Authenticate(token)
+	assert.True(t, errors.Is(err, entities.ErrUnauthorized), err)
+}
@@ -0,0 +1,75 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testex/internal/entities"
+)
+
+// validateAcl checks that every entry names either a principal or a group and
+// grants known permissions.
+func validateAcl(acl entities.Acl) error {
+	for _, entry := range acl {
+		if (entry.Principal == "") == (entry.Group == "") {
+			return fmt.Errorf("%w: acl entry must name either a principal or a group", entities.ErrInvalidParams)
+		}
+		if len(entry.Permissions) == 0 {
+			return fmt.Errorf("%w: acl entry grants no permissions", entities.ErrInvalidParams)
+		}
+		for _, permission := range entry.Permissions {
+			if !permission.Valid() {
+				return fmt.Errorf("%w: unknown permission %q", entities.ErrInvalidParams, permission)
+			}
+		}
+	}
+	return nil
+}
+
+// restricted returns the principal of ctx if ACLs apply to it, that is for
+// requests of non-admins.
+func restricted(ctx context.Context) *entities.Principal {
+	principal, ok := entities.PrincipalFromContext(ctx)
+	if !ok || principal.Role == entities.RoleAdmin {
+		return nil
+	}
+	return &principal
+}
+
+// authorizeExecution checks a permission on the command of an execution.
+func (c *Service) authorizeExecution(ctx context.Context, id int, permission entities.Permission) error {
+	if restricted(ctx) == nil {
+		return nil
+	}
+	executed, err := c.Storage.GetExecutedCommandById(id)
+	if err != nil {
+		return err
+	}
+	return entities.Authorize(ctx, executed.Acl, executed.Alias, permission)
+}
+
+// checkAdminFields lets only admins change the ACL, the secrets and the user
+// of a command, and only admins edit a command that has secrets or runs as
+// another user. Otherwise a holder of edit could grant themselves more, or
+// change the script, env or workdir to print secrets they weren't given or
+// run code as another user.
+func checkAdminFields(ctx context.Context, current, updated entities.Command) error {
+	if restricted(ctx) == nil {
+		return nil
+	}
+	if len(current.Secrets) > 0 || current.RunAs != "" {
+		return fmt.Errorf("%w: only admins edit commands with secrets or run_as", entities.ErrForbidden)
+	}
+	if !slices.EqualFunc(current.Acl, updated.Acl, func(a, b entities.AclEntry) bool {
+		return a.Principal == b.Principal && a.Group == b.Group && slices.Equal(a.Permissions, b.Permissions)
+	}) {
+		return fmt.Errorf("%w: only admins change the acl", entities.ErrForbidden)
+	}
+	if !slices.Equal(current.Secrets, updated.Secrets) {
+		return fmt.Errorf("%w: only admins change the secrets", entities.ErrForbidden)
+	}
+	if current.RunAs != updated.RunAs {
+		return fmt.Errorf("%w: only admins change run_as", entities.ErrForbidden)
+	}
+	return nil
+}
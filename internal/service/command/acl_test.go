@@ -0,0 +1,167 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"testex/internal/config"
+	"testex/internal/entities"
+	"testex/internal/service/audit"
+	"testex/internal/storage"
+	"testex/pkg/slog/slogdiscard"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeCommands serves commands and their executions from memory.
+type fakeCommands struct {
+	storage.CommandRepository
+	commands []entities.Command
+}
+
+func (f *fakeCommands) GetAllCommands() ([]entities.Command, error) {
+	return append([]entities.Command(nil), f.commands...), nil
+}
+
+func (f *fakeCommands) GetCommand(alias string) (entities.Command, error) {
+	for _, command := range f.commands {
+		if command.Alias == alias {
+			return command, nil
+		}
+	}
+	return entities.Command{}, entities.ErrNotFound
+}
+
+func (f *fakeCommands) UpdateCommand(_ string, _ int, command entities.Command) (entities.Command, error) {
+	return command, nil
+}
+
+// GetExecutedCommandById returns an execution of the command with the same id.
+func (f *fakeCommands) GetExecutedCommandById(id int) (entities.ExecutedCommand, error) {
+	for _, command := range f.commands {
+		if command.Id == id {
+			return entities.ExecutedCommand{Id: id, CommandId: id, Alias: command.Alias, Acl: command.Acl}, nil
+		}
+	}
+	return entities.ExecutedCommand{}, entities.ErrNotFound
+}
+
+func (f *fakeCommands) GetLogsByExecutedCommand(int, entities.LogFilter) ([]entities.Log, error) {
+	return []entities.Log{{Message: "line"}}, nil
+}
+
+func TestValidateAcl(t *testing.T) {
+	tests := []struct {
+		name  string
+		acl   entities.Acl
+		valid bool
+	}{
+		{name: "Empty", valid: true},
+		{name: "Entries", acl: entities.Acl{
+			{Principal: "alice", Permissions: []entities.Permission{entities.PermissionEdit}},
+			{Group: "on-call", Permissions: []entities.Permission{entities.PermissionExecute, entities.PermissionStop}},
+		}, valid: true},
+		{name: "NoSubject", acl: entities.Acl{{Permissions: []entities.Permission{entities.PermissionExecute}}}},
+		{name: "BothSubjects", acl: entities.Acl{
+			{Principal: "alice", Group: "on-call", Permissions: []entities.Permission{entities.PermissionExecute}},
+		}},
+		{name: "NoPermissions", acl: entities.Acl{{Group: "on-call"}}},
+		{name: "UnknownPermission", acl: entities.Acl{{Group: "on-call", Permissions: []entities.Permission{"run"}}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := validateAcl(test.acl)
+			if test.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.True(t, errors.Is(err, entities.ErrInvalidParams), err)
+			}
+		})
+	}
+}
+
+func TestService_Acl(t *testing.T) {
+	onCall := entities.Acl{{Group: "on-call", Permissions: []entities.Permission{
+		entities.PermissionExecute, entities.PermissionViewLogs,
+	}}}
+	repo := &fakeCommands{commands: []entities.Command{
+		{Id: 1, Alias: "restart-nginx", Script: "systemctl restart nginx", Acl: onCall},
+		{Id: 2, Alias: "drop-db", Script: "dropdb app", Acl: entities.Acl{
+			{Principal: "dba", Permissions: []entities.Permission{entities.PermissionExecute}},
+		}},
+		{Id: 3, Alias: "uptime", Script: "uptime"},
+	}}
+	s := NewService(&storage.Storage{CommandRepository: repo}, slogdiscard.NewDiscardLogger(),
+		config.Config{RunAs: config.RunAs{Allowed: []string{"root"}}}, nil, audit.Discard)
+
+	operator := entities.ContextWithPrincipal(context.Background(),
+		entities.Principal{Name: "bob", Role: entities.RoleOperator, Groups: entities.Groups{"on-call"}})
+	admin := entities.ContextWithPrincipal(context.Background(),
+		entities.Principal{Name: "root", Role: entities.RoleAdmin})
+
+	commands, err := s.GetAll(operator)
+	assert.NoError(t, err)
+	var aliases []string
+	for _, command := range commands {
+		aliases = append(aliases, command.Alias)
+	}
+	assert.Equal(t, []string{"restart-nginx", "uptime"}, aliases)
+	commands, err = s.GetAll(admin)
+	assert.NoError(t, err)
+	assert.Len(t, commands, 3)
+	commands, err = s.GetAll(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, commands, 3, "calls of the server itself are not restricted")
+
+	_, err = s.GetOne(operator, "drop-db")
+	assert.ErrorIs(t, err, entities.ErrNotFound, "hidden commands are not found")
+	_, err = s.Execute(operator, entities.ExecuteCommandDto{Alias: "drop-db"})
+	assert.ErrorIs(t, err, entities.ErrNotFound)
+
+	_, err = s.GetLogs(operator, 1, entities.LogFilter{})
+	assert.NoError(t, err)
+	_, err = s.GetLogs(operator, 2, entities.LogFilter{})
+	assert.ErrorIs(t, err, entities.ErrNotFound)
+
+	_, err = s.Update(operator, "restart-nginx", 0, entities.CommandDto{Alias: "restart-nginx", Script: "reboot"})
+	assert.ErrorIs(t, err, entities.ErrForbidden, "edit is not granted")
+	_, err = s.Update(operator, "uptime", 0, entities.CommandDto{Alias: "uptime", Script: "reboot"})
+	assert.ErrorIs(t, err, entities.ErrForbidden, "without an acl only admins edit")
+	assert.ErrorIs(t, s.Delete(operator, "uptime", 0), entities.ErrForbidden)
+
+	repo.commands[0].Acl = append(onCall, entities.AclEntry{
+		Principal: "bob", Permissions: []entities.Permission{entities.PermissionEdit},
+	})
+	script := "nginx -s reload"
+	_, err = s.Patch(operator, "restart-nginx", 0, entities.CommandPatchDto{Acl: &entities.Acl{}, Script: &script})
+	assert.ErrorIs(t, err, entities.ErrForbidden, "only admins change the acl")
+	updated, err := s.Patch(operator, "restart-nginx", 0, entities.CommandPatchDto{Script: &script})
+	assert.NoError(t, err)
+	assert.Equal(t, script, updated.Script)
+	secrets := entities.SecretRefs{{Name: "db-password", Env: "PGPASSWORD"}}
+	_, err = s.Patch(operator, "restart-nginx", 0, entities.CommandPatchDto{Secrets: &secrets})
+	assert.ErrorIs(t, err, entities.ErrForbidden, "only admins change the secrets")
+	runAs := "root"
+	_, err = s.Patch(operator, "restart-nginx", 0, entities.CommandPatchDto{RunAs: &runAs})
+	assert.ErrorIs(t, err, entities.ErrForbidden, "only admins change run_as")
+	_, err = s.Update(operator, "restart-nginx", 0, entities.CommandDto{Alias: "restart-nginx", Script: script,
+		Acl: repo.commands[0].Acl, Secrets: secrets})
+	assert.ErrorIs(t, err, entities.ErrForbidden, "updates keep the secrets too")
+	_, err = s.Patch(admin, "restart-nginx", 0, entities.CommandPatchDto{Secrets: &secrets, RunAs: &runAs})
+	assert.NoError(t, err)
+	repo.commands[0].Secrets, repo.commands[0].RunAs = secrets, runAs
+	leak := "env"
+	_, err = s.Patch(operator, "restart-nginx", 0, entities.CommandPatchDto{Script: &leak})
+	assert.ErrorIs(t, err, entities.ErrForbidden, "the script of a command with secrets would print them")
+	env := entities.EnvVars{"BASH_ENV": "/tmp/payload"}
+	_, err = s.Patch(operator, "restart-nginx", 0, entities.CommandPatchDto{Env: &env})
+	assert.ErrorIs(t, err, entities.ErrForbidden, "the env of a command with secrets is admin only")
+	mode, workdir := entities.WorkdirFixed, "/tmp"
+	_, err = s.Patch(operator, "restart-nginx", 0, entities.CommandPatchDto{WorkdirMode: &mode, Workdir: &workdir})
+	assert.ErrorIs(t, err, entities.ErrForbidden, "the workdir of a command with secrets is admin only")
+	_, err = s.Patch(admin, "restart-nginx", 0, entities.CommandPatchDto{Script: &leak})
+	assert.NoError(t, err)
+	_, err = s.Patch(admin, "restart-nginx", 0, entities.CommandPatchDto{Acl: &entities.Acl{}})
+	assert.NoError(t, err)
+}
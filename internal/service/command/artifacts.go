@@ -0,0 +1,161 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testex/internal/entities"
+	sl "testex/pkg/slog"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+// validateArtifacts checks that artifact patterns are valid and can't reach
+// outside of the working directory.
+func validateArtifacts(patterns entities.ArtifactPatterns) error {
+	for _, pattern := range patterns {
+		if pattern == "" || !doublestar.ValidatePattern(pattern) {
+			return fmt.Errorf("%w: invalid artifact pattern %q", entities.ErrInvalidParams, pattern)
+		}
+		if path.IsAbs(pattern) || filepath.IsAbs(pattern) || strings.Contains(pattern, "\\") {
+			return fmt.Errorf("%w: artifact pattern %q must be a relative path with forward slashes",
+				entities.ErrInvalidParams, pattern)
+		}
+		for _, part := range strings.Split(pattern, "/") {
+			if part == ".." {
+				return fmt.Errorf("%w: artifact pattern %q must not refer to parent directories",
+					entities.ErrInvalidParams, pattern)
+			}
+		}
+	}
+	return nil
+}
+
+// matchArtifacts lists regular files of dir matching any of the patterns,
+// sorted and without duplicates. Paths going through a symbolic link, in any
+// component, are left out, so nothing outside of dir is matched.
+func matchArtifacts(dir string, patterns entities.ArtifactPatterns) ([]string, error) {
+	fsys := os.DirFS(dir)
+	seen := make(map[string]bool)
+	var paths []string
+	for _, pattern := range patterns {
+		matches, err := doublestar.Glob(fsys, pattern, doublestar.WithFilesOnly(), doublestar.WithNoFollow())
+		if err != nil {
+			return nil, err
+		}
+		for _, match := range matches {
+			if seen[match] {
+				continue
+			}
+			seen[match] = true
+			if !regularInside(dir, match) {
+				continue
+			}
+			paths = append(paths, match)
+		}
+	}
+	slices.Sort(paths)
+	return paths, nil
+}
+
+// regularInside reports whether name is a regular file reached from dir
+// without passing through symbolic links.
+func regularInside(dir, name string) bool {
+	path := dir
+	var info fs.FileInfo
+	for _, part := range strings.Split(name, "/") {
+		path = filepath.Join(path, part)
+		var err error
+		if info, err = os.Lstat(path); err != nil || info.Mode()&fs.ModeSymlink != 0 {
+			return false
+		}
+	}
+	return info != nil && info.Mode().IsRegular()
+}
+
+// collect stores files matching the artifact patterns of the command once the
+// process has exited. Files over the configured limits are skipped and noted
+// in the log of the execution.
+func (c *Service) collect(id int, exe *execution) {
+	if len(exe.artifacts) == 0 {
+		return
+	}
+	dir := exe.cmd.Dir
+	if dir == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			c.Logger.Error("failed to get working directory", slog.Int("id", id), sl.Err(err))
+			return
+		}
+		dir = wd
+	}
+	paths, err := matchArtifacts(dir, exe.artifacts)
+	if err != nil {
+		c.Logger.Error("failed to match artifacts", slog.Int("id", id), sl.Err(err))
+		c.saveLog(id, exe, entities.StreamSystem, entities.LevelError, "failed to match artifacts")
+		return
+	}
+
+	limits := c.Config.Artifacts
+	var (
+		files int
+		total int64
+	)
+	for i, name := range paths {
+		if limits.MaxFiles > 0 && files >= limits.MaxFiles {
+			c.saveLog(id, exe, entities.StreamSystem, entities.LevelWarn, fmt.Sprintf(
+				"artifact limit of %d files reached, %d files are not collected", limits.MaxFiles, len(paths)-i))
+			break
+		}
+		artifact, err := c.storeArtifact(id, dir, name, limits.MaxBytes-total)
+		if err != nil {
+			c.Logger.Warn("failed to collect artifact", slog.Int("id", id), slog.String("path", name), sl.Err(err))
+			c.saveLog(id, exe, entities.StreamSystem, entities.LevelWarn,
+				fmt.Sprintf("artifact %s is not collected: %v", name, err))
+			continue
+		}
+		files++
+		total += artifact.Size
+	}
+	if files > 0 {
+		c.saveLog(id, exe, entities.StreamSystem, entities.LevelInfo,
+			fmt.Sprintf("collected %d artifacts, %d bytes", files, total))
+	}
+}
+
+// storeArtifact puts a file into the blob store if it fits into the given
+// number of bytes, which is not limited when the limits aren't set. The
+// script may still run, so the file is opened without following symbolic
+// links, whatever matchArtifacts saw.
+func (c *Service) storeArtifact(id int, dir, name string, remaining int64) (entities.Artifact, error) {
+	f, err := openInside(dir, name)
+	if err != nil {
+		return entities.Artifact{}, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return entities.Artifact{}, err
+	}
+	if !info.Mode().IsRegular() {
+		return entities.Artifact{}, errors.New("not a regular file")
+	}
+	if c.Config.Artifacts.MaxBytes > 0 && info.Size() > remaining {
+		return entities.Artifact{}, fmt.Errorf("%d bytes exceed the size limit", info.Size())
+	}
+	// The file is read up to its size at this point, in case something still
+	// writes to it.
+	key, size, err := c.Storage.PutBlob(io.LimitReader(f, info.Size()))
+	if err != nil {
+		return entities.Artifact{}, err
+	}
+	artifact := entities.Artifact{ExecutedCommandId: id, Path: name, BlobKey: key, Size: size}
+	return artifact, c.Storage.SaveArtifact(artifact)
+}
@@ -0,0 +1,86 @@
+package command
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testex/internal/entities"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateArtifacts(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns entities.ArtifactPatterns
+		valid    bool
+	}{
+		{name: "Empty", valid: true},
+		{name: "Globs", patterns: entities.ArtifactPatterns{"coverage/**/*.html", "report.{xml,json}"}, valid: true},
+		{name: "EmptyPattern", patterns: entities.ArtifactPatterns{""}},
+		{name: "Malformed", patterns: entities.ArtifactPatterns{"report[.xml"}},
+		{name: "Absolute", patterns: entities.ArtifactPatterns{"/etc/passwd"}},
+		{name: "Parent", patterns: entities.ArtifactPatterns{"build/../../secret"}},
+		{name: "Backslash", patterns: entities.ArtifactPatterns{`coverage\index.html`}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := validateArtifacts(test.patterns)
+			if test.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.True(t, errors.Is(err, entities.ErrInvalidParams), err)
+			}
+		})
+	}
+}
+
+func TestMatchArtifacts(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"report.xml", "coverage/index.html", "coverage/pkg/a.html", "coverage/pkg/a.txt"} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		assert.NoError(t, os.WriteFile(path, []byte(name), 0o644))
+	}
+
+	paths, err := matchArtifacts(dir, entities.ArtifactPatterns{"coverage/**/*.html", "*.xml", "coverage/index.html"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"coverage/index.html", "coverage/pkg/a.html", "report.xml"}, paths)
+
+	paths, err = matchArtifacts(dir, entities.ArtifactPatterns{"missing/*"})
+	assert.NoError(t, err)
+	assert.Empty(t, paths)
+}
+
+func TestArtifactSymlinks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symbolic links need privileges on Windows")
+	}
+	outside := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(outside, "shadow"), []byte("root:x"), 0o600))
+	dir := t.TempDir()
+	assert.NoError(t, os.Symlink(outside, filepath.Join(dir, "reports")))
+	assert.NoError(t, os.Symlink(filepath.Join(outside, "shadow"), filepath.Join(dir, "shadow.txt")))
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "logs"), 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "logs", "app.log"), []byte("ok"), 0o644))
+
+	paths, err := matchArtifacts(dir, entities.ArtifactPatterns{"reports/*", "*.txt", "**/*.log"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"logs/app.log"}, paths)
+
+	// The link may be swapped in after matching.
+	_, err = openInside(dir, "reports/shadow")
+	assert.Error(t, err)
+	_, err = openInside(dir, "shadow.txt")
+	assert.Error(t, err)
+	f, err := openInside(dir, "logs/app.log")
+	assert.NoError(t, err)
+	data, err := io.ReadAll(f)
+	assert.NoError(t, err)
+	assert.Equal(t, "ok", string(data))
+	assert.NoError(t, f.Close())
+}
@@ -0,0 +1,85 @@
+package command
+
+import (
+	"sync"
+	"testex/internal/entities"
+)
+
+// subscriberBuffer is how many lines a follower may fall behind before it is
+// dropped. Dropped followers catch up from the stored logs.
+const subscriberBuffer = 1024
+
+// broker fans out log lines of running executions to live followers.
+type broker struct {
+	mu   sync.Mutex
+	subs map[int]map[chan entities.Log]struct{}
+}
+
+func newBroker() *broker {
+	return &broker{subs: make(map[int]map[chan entities.Log]struct{})}
+}
+
+func (b *broker) subscribe(id int) (chan entities.Log, func()) {
+	ch := make(chan entities.Log, subscriberBuffer)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.subs[id] == nil {
+		b.subs[id] = make(map[chan entities.Log]struct{})
+	}
+	b.subs[id][ch] = struct{}{}
+
+	return ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		b.remove(id, ch)
+	}
+}
+
+// publish passes a batch of stored lines of one execution to its followers.
+func (b *broker) publish(logs []entities.Log) {
+	if len(logs) == 0 {
+		return
+	}
+	id := logs[0].ExecutedCommandId
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subs[id] {
+		if !send(ch, logs) {
+			b.remove(id, ch)
+		}
+	}
+}
+
+// send reports false if the follower has no room left for the batch.
+func send(ch chan entities.Log, logs []entities.Log) bool {
+	for _, log := range logs {
+		select {
+		case ch <- log:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// close ends all follows of a finished execution.
+func (b *broker) close(id int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subs[id] {
+		b.remove(id, ch)
+	}
+}
+
+func (b *broker) remove(id int, ch chan entities.Log) {
+	if _, ok := b.subs[id][ch]; !ok {
+		return
+	}
+	delete(b.subs[id], ch)
+	if len(b.subs[id]) == 0 {
+		delete(b.subs, id)
+	}
+	close(ch)
+}
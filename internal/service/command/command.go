@@ -1,52 +1,463 @@
 package command
 
 import (
-	"bufio"
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"io"
 	"log/slog"
 	"os"
 	"os/exec"
+	"slices"
+	"strconv"
 	"sync"
+	"syscall"
 	"testex/internal/config"
 	"testex/internal/entities"
+	"testex/internal/service/audit"
+	"testex/internal/service/logwriter"
 	"testex/internal/storage"
+	sl "testex/pkg/slog"
+	"time"
 )
 
 type Service struct {
 	Storage *storage.Storage
 	Logger  *slog.Logger
 	Config  config.Config
+	Secrets SecretResolver
+	Audit   audit.Recorder
 	mutex   sync.Mutex
+	running map[int]*execution
+	queue   []*queued
+	// waiters are closed when executions finish, see Wait.
+	waiters map[int]chan struct{}
+	logs    *broker
+	writer  *logwriter.Writer
 }
 
-func NewService(storage *storage.Storage, logger *slog.Logger, cfg config.Config) *Service {
+// execution tracks a process started by this instance of the service, or one
+// adopted from a previous run, which has no cmd and is known by processKey.
+type execution struct {
+	commandId   int
+	cmd         *exec.Cmd
+	process     *os.Process
+	processKey  string
+	ctx         context.Context
+	startedAt   time.Time
+	stopSignal  string
+	gracePeriod time.Duration
+	outputMode  entities.OutputMode
+	artifacts   entities.ArtifactPatterns
+	workdirMode entities.WorkdirMode
+	stopped     bool
+	// done is closed once the process has been waited for.
+	done chan struct{}
+	// redactor hides values of secrets in output, nil without secrets.
+	redactor *redactor
+
+	// logMu keeps lines in the buffer ordered by seq and guards the output
+	// counters below.
+	logMu     sync.Mutex
+	seq       int64
+	buffer    *logwriter.Buffer
+	limits    outputLimits
+	lines     int
+	bytes     int64
+	capped    bool
+	truncated bool
+}
+
+func NewService(storage *storage.Storage, logger *slog.Logger, cfg config.Config, secrets SecretResolver,
+	recorder audit.Recorder) *Service {
 	return &Service{
 		Storage: storage,
 		Logger:  logger,
 		Config:  cfg,
+		Secrets: secrets,
+		Audit:   recorder,
+		running: make(map[int]*execution),
+		waiters: make(map[int]chan struct{}),
+		logs:    newBroker(),
+		writer:  logwriter.New(storage, logger, cfg.Logs),
+	}
+}
+
+func (c *Service) Create(ctx context.Context, dto entities.CommandDto) (id int, err error) {
+	defer func() { c.Audit.Record(ctx, "command.create", "commands/"+dto.Alias, dto, err) }()
+	command, err := commandFromDto(dto)
+	if err != nil {
+		return -1, err
+	}
+	if err = validateRunAs(command.RunAs, c.Config.RunAs); err != nil {
+		return -1, err
+	}
+	return c.Storage.SaveCommand(command)
+}
+
+// Update replaces a command, keeping the previous script as a revision. A
+// non-zero version must match the current version of the command.
+func (c *Service) Update(ctx context.Context, alias string, version int,
+	dto entities.CommandDto) (command entities.Command, err error) {
+	defer func() { c.Audit.Record(ctx, "command.update", "commands/"+alias, dto, err) }()
+	return c.update(ctx, alias, version, dto)
+}
+
+func (c *Service) update(ctx context.Context, alias string, version int,
+	dto entities.CommandDto) (entities.Command, error) {
+	command, err := commandFromDto(dto)
+	if err != nil {
+		return entities.Command{}, err
+	}
+	if err = validateRunAs(command.RunAs, c.Config.RunAs); err != nil {
+		return entities.Command{}, err
+	}
+	if restricted(ctx) != nil {
+		current, err := c.GetOne(ctx, alias)
+		if err != nil {
+			return entities.Command{}, err
+		}
+		if err = entities.Authorize(ctx, current.Acl, alias, entities.PermissionEdit); err != nil {
+			return entities.Command{}, err
+		}
+		if err = checkAdminFields(ctx, current, command); err != nil {
+			return entities.Command{}, err
+		}
+	}
+	return c.Storage.UpdateCommand(alias, version, command)
+}
+
+// Patch changes the given fields of a command, see Update.
+func (c *Service) Patch(ctx context.Context, alias string, version int,
+	dto entities.CommandPatchDto) (command entities.Command, err error) {
+	defer func() { c.Audit.Record(ctx, "command.patch", "commands/"+alias, dto, err) }()
+	current, err := c.GetOne(ctx, alias)
+	if err != nil {
+		return current, err
+	}
+	// The patch is applied to the version read here, so it must not be
+	// stored over a concurrent edit.
+	if version == 0 {
+		version = current.Version
+	}
+
+	merged := entities.CommandDto{
+		Alias:          current.Alias,
+		Script:         current.Script,
+		Params:         current.Params,
+		Timeout:        current.Timeout,
+		StopSignal:     current.StopSignal,
+		GracePeriod:    current.GracePeriod,
+		MaxConcurrency: current.MaxConcurrency,
+		Overflow:       current.Overflow,
+		OutputMode:     current.OutputMode,
+		Artifacts:      current.Artifacts,
+		WorkdirMode:    current.WorkdirMode,
+		Workdir:        current.Workdir,
+		Env:            current.Env,
+		EnvInherit:     current.EnvInherit,
+		Secrets:        current.Secrets,
+		Acl:            current.Acl,
+		RunAs:          current.RunAs,
+		MaxLineLength:  current.MaxLineLength,
+		MaxOutputBytes: current.MaxOutputBytes,
+		MaxOutputLines: current.MaxOutputLines,
+		RetentionDays:  current.RetentionDays,
+		KeepExecutions: current.KeepExecutions,
+		MaxLogBytes:    current.MaxLogBytes,
+	}
+	if dto.Alias != nil {
+		merged.Alias = *dto.Alias
+	}
+	if dto.Script != nil {
+		merged.Script = *dto.Script
+	}
+	if dto.Params != nil {
+		merged.Params = *dto.Params
+	}
+	if dto.Timeout != nil {
+		merged.Timeout = *dto.Timeout
+	}
+	if dto.StopSignal != nil {
+		merged.StopSignal = *dto.StopSignal
+	}
+	if dto.GracePeriod != nil {
+		merged.GracePeriod = *dto.GracePeriod
+	}
+	if dto.MaxConcurrency != nil {
+		merged.MaxConcurrency = *dto.MaxConcurrency
+	}
+	if dto.Overflow != nil {
+		merged.Overflow = *dto.Overflow
+	}
+	if dto.OutputMode != nil {
+		merged.OutputMode = *dto.OutputMode
+	}
+	if dto.Artifacts != nil {
+		merged.Artifacts = *dto.Artifacts
+	}
+	if dto.WorkdirMode != nil {
+		merged.WorkdirMode = *dto.WorkdirMode
+	}
+	if dto.Workdir != nil {
+		merged.Workdir = *dto.Workdir
+	}
+	if dto.Env != nil {
+		merged.Env = *dto.Env
+	}
+	if dto.EnvInherit != nil {
+		merged.EnvInherit = *dto.EnvInherit
+	}
+	if dto.Secrets != nil {
+		merged.Secrets = *dto.Secrets
+	}
+	if dto.Acl != nil {
+		merged.Acl = *dto.Acl
+	}
+	if dto.RunAs != nil {
+		merged.RunAs = *dto.RunAs
+	}
+	if dto.MaxLineLength != nil {
+		merged.MaxLineLength = *dto.MaxLineLength
+	}
+	if dto.MaxOutputBytes != nil {
+		merged.MaxOutputBytes = *dto.MaxOutputBytes
+	}
+	if dto.MaxOutputLines != nil {
+		merged.MaxOutputLines = *dto.MaxOutputLines
+	}
+	if dto.RetentionDays != nil {
+		merged.RetentionDays = *dto.RetentionDays
+	}
+	if dto.KeepExecutions != nil {
+		merged.KeepExecutions = *dto.KeepExecutions
+	}
+	if dto.MaxLogBytes != nil {
+		merged.MaxLogBytes = *dto.MaxLogBytes
+	}
+	return c.update(ctx, alias, version, merged)
+}
+
+// Delete soft-deletes a command: it can't be executed anymore, but its
+// executions and revisions stay readable.
+func (c *Service) Delete(ctx context.Context, alias string, version int) (err error) {
+	defer func() { c.Audit.Record(ctx, "command.delete", "commands/"+alias, nil, err) }()
+	if restricted(ctx) != nil {
+		current, err := c.GetOne(ctx, alias)
+		if err != nil {
+			return err
+		}
+		if err = entities.Authorize(ctx, current.Acl, alias, entities.PermissionEdit); err != nil {
+			return err
+		}
+	}
+	return c.Storage.DeleteCommand(alias, version)
+}
+
+func (c *Service) GetRevisions(ctx context.Context, alias string) ([]entities.CommandRevision, error) {
+	command, err := c.GetOne(ctx, alias)
+	if err != nil {
+		return nil, err
+	}
+	return c.Storage.GetCommandRevisions(command.Id)
+}
+
+// commandFromDto validates a command definition, filling in defaults.
+func commandFromDto(dto entities.CommandDto) (entities.Command, error) {
+	if dto.Alias == "" || dto.Script == "" {
+		return entities.Command{}, fmt.Errorf("%w: alias and script are required", entities.ErrInvalidParams)
+	}
+	if err := validateParams(dto.Params); err != nil {
+		return entities.Command{}, err
+	}
+	if dto.Timeout < 0 || dto.GracePeriod < 0 {
+		return entities.Command{}, fmt.Errorf("%w: timeout and grace period must not be negative", entities.ErrInvalidParams)
+	}
+	if dto.StopSignal != "" {
+		if _, err := parseSignal(dto.StopSignal); err != nil {
+			return entities.Command{}, fmt.Errorf("%w: %v", entities.ErrInvalidParams, err)
+		}
+	}
+	if dto.MaxConcurrency < 0 {
+		return entities.Command{}, fmt.Errorf("%w: max concurrency must not be negative", entities.ErrInvalidParams)
+	}
+	switch dto.OutputMode {
+	case "":
+		dto.OutputMode = entities.OutputLines
+	case entities.OutputLines, entities.OutputRaw, entities.OutputBoth:
+	default:
+		return entities.Command{}, fmt.Errorf("%w: unknown output mode %q", entities.ErrInvalidParams, dto.OutputMode)
+	}
+	if err := validateArtifacts(dto.Artifacts); err != nil {
+		return entities.Command{}, err
+	}
+	if err := validateWorkdir(&dto); err != nil {
+		return entities.Command{}, err
+	}
+	if err := validateEnv(&dto); err != nil {
+		return entities.Command{}, err
+	}
+	if err := validateSecrets(dto.Secrets); err != nil {
+		return entities.Command{}, err
+	}
+	if err := checkParamEnv(dto); err != nil {
+		return entities.Command{}, err
+	}
+	if err := validateAcl(dto.Acl); err != nil {
+		return entities.Command{}, err
+	}
+	if dto.MaxLineLength < 0 || dto.MaxOutputBytes < 0 || dto.MaxOutputLines < 0 {
+		return entities.Command{}, fmt.Errorf("%w: output limits must not be negative", entities.ErrInvalidParams)
+	}
+	if dto.RetentionDays < 0 || dto.KeepExecutions < 0 || dto.MaxLogBytes < 0 {
+		return entities.Command{}, fmt.Errorf("%w: retention limits must not be negative", entities.ErrInvalidParams)
+	}
+	switch dto.Overflow {
+	case "":
+		dto.Overflow = entities.OverflowQueue
+	case entities.OverflowQueue, entities.OverflowReject, entities.OverflowReplace:
+	default:
+		return entities.Command{}, fmt.Errorf("%w: unknown overflow policy %q", entities.ErrInvalidParams, dto.Overflow)
+	}
+	return entities.Command{
+		Alias:          dto.Alias,
+		Script:         dto.Script,
+		Params:         dto.Params,
+		Timeout:        dto.Timeout,
+		StopSignal:     dto.StopSignal,
+		GracePeriod:    dto.GracePeriod,
+		MaxConcurrency: dto.MaxConcurrency,
+		Overflow:       dto.Overflow,
+		OutputMode:     dto.OutputMode,
+		Artifacts:      dto.Artifacts,
+		WorkdirMode:    dto.WorkdirMode,
+		Workdir:        dto.Workdir,
+		Env:            dto.Env,
+		EnvInherit:     dto.EnvInherit,
+		Secrets:        dto.Secrets,
+		Acl:            dto.Acl,
+		RunAs:          dto.RunAs,
+		MaxLineLength:  dto.MaxLineLength,
+		MaxOutputBytes: dto.MaxOutputBytes,
+		MaxOutputLines: dto.MaxOutputLines,
+		RetentionDays:  dto.RetentionDays,
+		KeepExecutions: dto.KeepExecutions,
+		MaxLogBytes:    dto.MaxLogBytes,
+	}, nil
+}
+
+// GetAll lists the commands the principal of ctx can see.
+func (c *Service) GetAll(ctx context.Context) ([]entities.Command, error) {
+	commands, err := c.Storage.GetAllCommands()
+	if err != nil {
+		return nil, err
 	}
+	if principal := restricted(ctx); principal != nil {
+		commands = slices.DeleteFunc(commands, func(command entities.Command) bool {
+			return !command.Acl.Shows(*principal)
+		})
+	}
+	return commands, nil
 }
 
-func (c *Service) Create(alias string, script string) (int, error) {
-	return c.Storage.SaveCommand(entities.Command{Alias: alias, Script: script})
+func (c *Service) GetOne(ctx context.Context, alias string) (entities.Command, error) {
+	command, err := c.Storage.GetCommand(alias)
+	if err != nil {
+		return command, err
+	}
+	if principal := restricted(ctx); principal != nil && !command.Acl.Shows(*principal) {
+		return entities.Command{}, entities.ErrNotFound
+	}
+	return command, nil
+}
+
+func (c *Service) GetActiveExecutedCommand(ctx context.Context) ([]entities.ExecutedCommand, error) {
+	active, err := c.Storage.GetActiveExecutedCommands()
+	if err != nil {
+		return nil, err
+	}
+	if principal := restricted(ctx); principal != nil {
+		active = slices.DeleteFunc(active, func(executed entities.ExecutedCommand) bool {
+			return !executed.Acl.Permits(*principal, entities.PermissionViewLogs)
+		})
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for i := range active {
+		active[i].QueuePosition = c.queuePosition(active[i].Id)
+	}
+	return active, nil
 }
 
-func (c *Service) GetAll() ([]entities.Command, error) {
-	return c.Storage.GetAllCommands()
+// timeout picks the timeout of an execution: the request overrides the
+// command, and both are capped by the server-wide maximum.
+func (c *Service) timeout(command entities.Command, dto entities.ExecuteCommandDto) (time.Duration, error) {
+	if dto.Timeout < 0 {
+		return 0, fmt.Errorf("%w: timeout must not be negative", entities.ErrInvalidParams)
+	}
+	timeout := time.Duration(command.Timeout) * time.Second
+	if dto.Timeout > 0 {
+		timeout = time.Duration(dto.Timeout) * time.Second
+	}
+	maxTimeout := c.Config.Execution.MaxTimeout
+	if maxTimeout > 0 && (timeout == 0 || timeout > maxTimeout) {
+		timeout = maxTimeout
+	}
+	return timeout, nil
 }
 
-func (c *Service) GetOne(alias string) (entities.Command, error) {
-	return c.Storage.GetCommand(alias)
+// request is an execution admitted by Execute, waiting to be started.
+type request struct {
+	command     entities.Command
+	params      entities.ParamValues
+	timeout     time.Duration
+	scheduleId  *int
+	triggeredBy entities.TriggerSource
+	// actor is the principal that requested the execution, nil for the
+	// server itself.
+	actor *string
 }
 
-func (c *Service) GetActiveExecutedCommand() ([]entities.ExecutedCommand, error) {
-	return c.Storage.GetActiveExecutedCommands()
+func (c *Service) Execute(ctx context.Context, dto entities.ExecuteCommandDto) (id int, err error) {
+	defer func() { c.Audit.Record(ctx, "command.execute", "commands/"+dto.Alias, dto, err) }()
+	command, err := c.GetOne(ctx, dto.Alias)
+	if err != nil {
+		return -1, err
+	}
+	if err = entities.Authorize(ctx, command.Acl, command.Alias, entities.PermissionExecute); err != nil {
+		return -1, err
+	}
+
+	params, err := resolveParams(command.Params, dto.Params)
+	if err != nil {
+		return -1, err
+	}
+
+	timeout, err := c.timeout(command, dto)
+	if err != nil {
+		return -1, err
+	}
+
+	triggeredBy := dto.TriggeredBy
+	if triggeredBy == "" {
+		triggeredBy = entities.TriggeredByApi
+	}
+	req := &request{
+		command:     command,
+		params:      params,
+		timeout:     timeout,
+		scheduleId:  dto.ScheduleId,
+		triggeredBy: triggeredBy,
+	}
+	if principal, ok := entities.PrincipalFromContext(ctx); ok {
+		req.actor = &principal.Name
+	}
+	return c.enqueue(req)
 }
 
-func (c *Service) Execute(alias string) (int, error) {
-	ctx := context.Background()
+// start launches the process of an admitted execution. It is called with the
+// mutex held.
+func (c *Service) start(id int, req *request) error {
 	var (
 		name = "bash"
 		arg  = "-c"
@@ -57,98 +468,432 @@ func (c *Service) Execute(alias string) (int, error) {
 		arg = "/C"
 	}
 
-	command, err := c.GetOne(alias)
+	who, err := c.identityOf(req.command)
 	if err != nil {
-		return -1, err
+		return fmt.Errorf("failed to resolve the user to run as: %w", err)
+	}
+	dir, err := c.prepareWorkdir(id, req.command, who)
+	if err != nil {
+		return fmt.Errorf("failed to prepare working directory: %w", err)
+	}
+	secrets, redactor, err := c.injectSecrets(id, req.command.Secrets, who)
+	if err != nil {
+		c.removeSecrets(id)
+		return fmt.Errorf("failed to inject secrets: %w", err)
+	}
+	// Secret files are removed once the process exits, or right away if it
+	// doesn't start.
+	started := false
+	defer func() {
+		if !started {
+			c.removeSecrets(id)
+		}
+	}()
+
+	// The timeout is derived from the context of the run, cancel releases both.
+	base, cancelBase := context.WithCancel(context.Background())
+	ctx, cancel := base, cancelBase
+	if req.timeout > 0 {
+		var cancelTimeout context.CancelFunc
+		ctx, cancelTimeout = context.WithTimeout(base, req.timeout)
+		cancel = func() {
+			cancelTimeout()
+			cancelBase()
+		}
 	}
 
-	cmd := exec.CommandContext(ctx, name, arg, command.Script)
+	cmd := exec.CommandContext(ctx, name, arg, req.command.Script)
+	cmd.Dir = dir
+	cmd.Env = c.environ(id, req, dir, secrets, who)
+	setProcessGroup(cmd)
+	if who != nil {
+		if err = setCredential(cmd, who); err != nil {
+			cancel()
+			return err
+		}
+	}
+	cmd.Cancel = func() error {
+		return killProcessTree(cmd.Process)
+	}
 
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
-		return -1, err
+		cancel()
+		return err
 	}
 	stderr, err := cmd.StderrPipe()
 	if err != nil {
-		return -1, err
+		cancel()
+		return err
 	}
 
+	startedAt := time.Now()
 	err = cmd.Start()
 	if err != nil {
-		return -1, err
+		cancel()
+		return err
+	}
+	key, err := processKey(cmd.Process.Pid)
+	if err != nil {
+		c.Logger.Warn("failed to read process start time", slog.Int("pid", cmd.Process.Pid), sl.Err(err))
 	}
 
-	c.mutex.Lock()
-	defer c.mutex.Unlock()
-
-	id, err := c.Storage.SaveExecutedCommand(entities.ExecutedCommand{CommandId: command.Id, PID: cmd.Process.Pid})
+	err = c.Storage.StartExecutedCommand(entities.ExecutedCommand{
+		Id:         id,
+		PID:        cmd.Process.Pid,
+		Status:     entities.StatusRunning,
+		StartedAt:  startedAt,
+		ProcessKey: key,
+		Workdir:    dir,
+		Uid:        effectiveUid(who),
+	})
 	if err != nil {
-		return -1, err
+		cancel()
+		_ = cmd.Wait()
+		return err
 	}
+	exe := &execution{
+		commandId:   req.command.Id,
+		cmd:         cmd,
+		process:     cmd.Process,
+		ctx:         ctx,
+		startedAt:   startedAt,
+		stopSignal:  req.command.StopSignal,
+		gracePeriod: time.Duration(req.command.GracePeriod) * time.Second,
+		done:        make(chan struct{}),
+		buffer:      c.writer.Open(id, c.logs.publish),
+		limits:      limitsOf(req.command, c.Config.Execution),
+		outputMode:  req.command.OutputMode,
+		artifacts:   req.command.Artifacts,
+		workdirMode: req.command.WorkdirMode,
+		redactor:    redactor,
+	}
+	c.running[id] = exe
+	started = true
 
 	go func() {
-		stdoutScanner := bufio.NewScanner(stdout)
-		stderrScanner := bufio.NewScanner(stderr)
-
+		var wg sync.WaitGroup
+		wg.Add(2)
 		go func() {
-			for stdoutScanner.Scan() {
-				msg := fmt.Sprintf("[%d - STDOUT] %s\n", id, stdoutScanner.Text())
-				c.Logger.Info(stdoutScanner.Text())
-				_, err = c.Storage.SaveLog(entities.Log{Message: msg, ExecutedCommandId: id})
-				if err != nil {
-					c.Logger.Error(err.Error())
-				}
-			}
+			defer wg.Done()
+			c.consume(id, exe, entities.StreamStdout, stdout)
 		}()
-
 		go func() {
-			for stderrScanner.Scan() {
-				msg := fmt.Sprintf("[%d - STDERR] %s\n", id, stderrScanner.Text())
-				c.Logger.Error(msg)
-				c.Storage.SaveLog(entities.Log{Message: msg, ExecutedCommandId: id})
-			}
+			defer wg.Done()
+			c.consume(id, exe, entities.StreamStderr, stderr)
 		}()
 
+		// Pipes must be drained before Wait closes them.
+		wg.Wait()
 		err := cmd.Wait()
+		close(exe.done)
+		if len(req.command.Secrets) > 0 {
+			c.removeSecrets(id)
+		}
+		defer cancel()
 		if err != nil {
-			log.Printf("Command failed: %v", err)
+			c.Logger.Info("command exited with error", slog.Int("id", id), sl.Err(err))
 		}
-
-		c.mutex.Lock()
-		defer c.mutex.Unlock()
-		_ = c.Storage.FinishCommand(id)
+		// Artifacts are collected before a temporary directory is removed.
+		c.collect(id, exe)
+		c.cleanWorkdir(id, exe)
+		c.finish(id, exe)
 	}()
 
-	return id, err
+	return nil
+}
+
+// finish records the outcome of a process that has been waited for.
+func (c *Service) finish(id int, exe *execution) {
+	now := time.Now()
+	elapsed := now.Sub(exe.startedAt).Milliseconds()
+	result := entities.ExecutedCommand{Id: id, FinishedAt: &now, DurationMs: &elapsed}
+	result.ExitCode, result.Signal = exitStatus(exe.cmd.ProcessState)
+
+	c.mutex.Lock()
+	stopped := exe.stopped
+	c.mutex.Unlock()
+
+	switch {
+	case stopped:
+		result.Status = entities.StatusStopped
+	case errors.Is(exe.ctx.Err(), context.DeadlineExceeded):
+		result.Status = entities.StatusTimedOut
+	case result.ExitCode != nil && *result.ExitCode == 0:
+		result.Status = entities.StatusSucceeded
+	default:
+		result.Status = entities.StatusFailed
+	}
+
+	c.saveLog(id, exe, entities.StreamSystem, outcomeLevel(result.Status), outcomeMessage(result))
+	exe.logMu.Lock()
+	result.Truncated = exe.truncated
+	exe.logMu.Unlock()
+	// Flushing happens outside of the lock, it may take a while with a slow DB.
+	result.DroppedLines = exe.buffer.Close()
+	if result.DroppedLines > 0 {
+		c.Logger.Warn("output lines were dropped", slog.Int("id", id), slog.Int("lines", result.DroppedLines))
+		exe.seq++
+		log := entities.Log{
+			ExecutedCommandId: id,
+			Seq:               exe.seq,
+			Stream:            entities.StreamSystem,
+			Level:             entities.LevelWarn,
+			Message:           fmt.Sprintf("%d lines of output were dropped", result.DroppedLines),
+			Date:              time.Now(),
+		}
+		// The buffer is closed, so the line is passed to followers here.
+		var err error
+		if log.Id, err = c.Storage.SaveLog(log); err != nil {
+			c.Logger.Error("failed to save log", slog.Int("id", id), sl.Err(err))
+		} else {
+			c.logs.publish([]entities.Log{log})
+		}
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	delete(c.running, id)
+	if err := c.Storage.FinishExecutedCommand(result); err != nil {
+		c.Logger.Error("failed to save execution result", slog.Int("id", id), sl.Err(err))
+	}
+	c.logs.close(id)
+	c.release(id)
+	c.dispatch()
+}
+
+// scan saves lines of a stream of the process within the output limits, and
+// discards the rest, so that the process never blocks on a full pipe.
+func (c *Service) scan(id int, exe *execution, stream entities.LogStream, r io.Reader) {
+	level := entities.LevelInfo
+	if stream == entities.StreamStderr {
+		level = entities.LevelError
+	}
+	lines := newLineReader(r, exe.limits.lineLength)
+	for {
+		line, cut, err := lines.next()
+		if err != nil {
+			return
+		}
+		if cut > 0 {
+			line = fmt.Sprintf("%s [%d bytes truncated]", line, cut)
+			exe.logMu.Lock()
+			exe.truncated = true
+			exe.logMu.Unlock()
+		}
+		if !c.saveOutput(id, exe, stream, level, line) {
+			lines.drain()
+			return
+		}
+		if stream == entities.StreamStderr {
+			c.Logger.Error(line, slog.Int("id", id))
+		} else {
+			c.Logger.Info(line, slog.Int("id", id))
+		}
+	}
+}
+
+// saveLog queues a line for storing. Live followers get it once its batch is
+// stored.
+func (c *Service) saveLog(id int, exe *execution, stream entities.LogStream, level entities.LogLevel, message string) {
+	exe.logMu.Lock()
+	defer exe.logMu.Unlock()
+	c.writeLog(id, exe, stream, level, message)
+}
+
+// writeLog is saveLog with logMu held.
+func (c *Service) writeLog(id int, exe *execution, stream entities.LogStream, level entities.LogLevel, message string) {
+	exe.seq++
+	exe.buffer.Write(entities.Log{
+		ExecutedCommandId: id,
+		Seq:               exe.seq,
+		Stream:            stream,
+		Level:             level,
+		Message:           message,
+		Date:              time.Now(),
+	})
+}
+
+func outcomeLevel(status entities.ExecutionStatus) entities.LogLevel {
+	switch status {
+	case entities.StatusSucceeded:
+		return entities.LevelInfo
+	case entities.StatusStopped:
+		return entities.LevelWarn
+	default:
+		return entities.LevelError
+	}
 }
 
-func (c *Service) StopCommand(id int) error {
+func outcomeMessage(result entities.ExecutedCommand) string {
+	var exit string
+	switch {
+	case result.Signal != nil:
+		exit = "killed by " + *result.Signal
+	case result.ExitCode != nil:
+		exit = fmt.Sprintf("exit code %d", *result.ExitCode)
+	}
+	if result.Status == entities.StatusTimedOut && result.DurationMs != nil {
+		return fmt.Sprintf("timed out after %s, %s", time.Duration(*result.DurationMs)*time.Millisecond, exit)
+	}
+	return fmt.Sprintf("%s, %s", result.Status, exit)
+}
+
+func (c *Service) StopCommand(ctx context.Context, dto entities.StopCommandDto) (err error) {
+	defer func() { c.Audit.Record(ctx, "command.stop", "executions/"+strconv.Itoa(dto.Id), dto, err) }()
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
-	cmd, err := c.Storage.GetExecutedCommandById(id)
+	cmd, err := c.Storage.GetExecutedCommandById(dto.Id)
 	if err != nil {
 		return err
 	}
+	if err = entities.Authorize(ctx, cmd.Acl, cmd.Alias, entities.PermissionStop); err != nil {
+		return err
+	}
 
 	if !cmd.IsActive {
 		return fmt.Errorf("command is not active")
 	}
 
-	cmdInfo, err := os.FindProcess(cmd.PID)
+	if c.cancelQueued(dto.Id) {
+		return nil
+	}
+
+	exe, ok := c.running[dto.Id]
+	if !ok {
+		// The PID may belong to an unrelated process by now.
+		return fmt.Errorf("execution %d is not tracked by this server, refusing to signal pid %d", cmd.Id, cmd.PID)
+	}
+	return c.stop(dto.Id, exe, dto.Signal)
+}
+
+// stop sends the stop signal to a running execution and kills it after the
+// grace period. An explicit signal overrides the one of the command. It is
+// called with the mutex held.
+func (c *Service) stop(id int, exe *execution, signal string) error {
+	name := signal
+	if name == "" {
+		name = exe.stopSignal
+	}
+	if name == "" {
+		name = c.Config.Execution.StopSignal
+	}
+	if name == "" {
+		name = "SIGTERM"
+	}
+	sig, err := parseSignal(name)
 	if err != nil {
 		return err
 	}
 
-	err = cmdInfo.Kill()
-	if err != nil {
+	// Reload-style signals leave the script running, so they neither mark the
+	// execution as stopped nor escalate to SIGKILL.
+	if signal != "" && !isTerminating(sig) {
+		return exe.signal(sig)
+	}
+
+	// The goroutine waiting for the process records the final status.
+	exe.stopped = true
+	if err = exe.signal(sig); err != nil {
 		return err
 	}
 
-	err = c.Storage.FinishCommand(id)
+	grace := exe.gracePeriod
+	if grace == 0 {
+		grace = c.Config.Execution.GracePeriod
+	}
+	go c.escalate(id, exe, grace)
+	return nil
+}
+
+// signal sends sig to the process group of the execution. Adopted processes
+// are checked again right before, as they may have exited in the meantime.
+func (exe *execution) signal(sig syscall.Signal) error {
+	if exe.processKey != "" {
+		key, err := processKey(exe.process.Pid)
+		if err != nil || key != exe.processKey {
+			return os.ErrProcessDone
+		}
+	}
+	return signalProcessTree(exe.process, sig)
+}
+
+// escalate kills the process group if it outlives the grace period.
+func (c *Service) escalate(id int, exe *execution, grace time.Duration) {
+	timer := time.NewTimer(grace)
+	defer timer.Stop()
+	select {
+	case <-exe.done:
+	case <-timer.C:
+		c.Logger.Info("grace period is over, killing command", slog.Int("id", id))
+		if err := exe.signal(syscall.SIGKILL); err != nil && !errors.Is(err, os.ErrProcessDone) {
+			c.Logger.Error("failed to kill command", slog.Int("id", id), sl.Err(err))
+		}
+	}
+}
+
+func (c *Service) GetLogs(ctx context.Context, executedCommandId int,
+	filter entities.LogFilter) ([]entities.Log, error) {
+	if err := c.authorizeExecution(ctx, executedCommandId, entities.PermissionViewLogs); err != nil {
+		return nil, err
+	}
+	return c.Storage.GetLogsByExecutedCommand(executedCommandId, filter)
+}
+
+func (c *Service) GetExecutedCommand(ctx context.Context, id int) (entities.ExecutedCommand, error) {
+	ec, err := c.Storage.GetExecutedCommandById(id)
+	if err != nil {
+		return ec, err
+	}
+	if err = entities.Authorize(ctx, ec.Acl, ec.Alias, entities.PermissionViewLogs); err != nil {
+		return entities.ExecutedCommand{}, err
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	ec.QueuePosition = c.queuePosition(id)
+	return ec, nil
+}
+
+// Wait blocks until an execution started by this instance finishes and
+// returns its final state. Other executions are returned as they are.
+func (c *Service) Wait(ctx context.Context, id int) (entities.ExecutedCommand, error) {
+	c.mutex.Lock()
+	done, ok := c.waiters[id]
+	c.mutex.Unlock()
+	if ok {
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return entities.ExecutedCommand{}, ctx.Err()
+		}
+	}
+	return c.Storage.GetExecutedCommandById(id)
+}
 
-	return err
+// release wakes up the waiters of a finished execution. It is called with the
+// mutex held.
+func (c *Service) release(id int) {
+	if done, ok := c.waiters[id]; ok {
+		close(done)
+		delete(c.waiters, id)
+	}
 }
 
-func (c *Service) GetLogs(executedCommandId int) ([]entities.Log, error) {
-	return c.Storage.GetLogsByExecutedCommand(executedCommandId)
+// FollowLogs subscribes to new output of an execution. The channel is closed
+// when the execution finishes or the follower falls too far behind; it is
+// closed right away for executions that are neither running nor queued in
+// this instance.
+func (c *Service) FollowLogs(ctx context.Context, executedCommandId int) (<-chan entities.Log, func(), error) {
+	if err := c.authorizeExecution(ctx, executedCommandId, entities.PermissionViewLogs); err != nil {
+		return nil, nil, err
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if _, ok := c.running[executedCommandId]; !ok && c.queuePosition(executedCommandId) == 0 {
+		ch := make(chan entities.Log)
+		close(ch)
+		return ch, func() {}, nil
+	}
+	ch, unsubscribe := c.logs.subscribe(executedCommandId)
+	return ch, unsubscribe, nil
 }
@@ -0,0 +1,171 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"syscall"
+	"testex/internal/config"
+	"testex/internal/entities"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSignal(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected syscall.Signal
+		wantErr  bool
+	}{
+		{name: "SIGTERM", expected: syscall.SIGTERM},
+		{name: "kill", expected: syscall.SIGKILL},
+		{name: "SigInt", expected: syscall.SIGINT},
+		{name: "SIGNOPE", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sig, err := parseSignal(test.name)
+			if test.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, sig)
+		})
+	}
+}
+
+func TestService_Outcome(t *testing.T) {
+	s, store := newTestService(t, config.Config{},
+		entities.Command{Alias: "ok", Script: "true"},
+		entities.Command{Alias: "fail", Script: "exit 3"},
+		entities.Command{Alias: "crash", Script: "kill -KILL $$"},
+		entities.Command{Alias: "sleep", Script: "echo ready; sleep 30"},
+	)
+	code := func(n int) *int { return &n }
+	signal := func(name string) *string { return &name }
+
+	tests := []struct {
+		alias    string
+		status   entities.ExecutionStatus
+		exitCode *int
+		signal   *string
+		message  string
+	}{
+		{alias: "ok", status: entities.StatusSucceeded, exitCode: code(0), message: "succeeded, exit code 0"},
+		{alias: "fail", status: entities.StatusFailed, exitCode: code(3), message: "failed, exit code 3"},
+		{alias: "crash", status: entities.StatusFailed, signal: signal("SIGKILL"),
+			message: "failed, killed by SIGKILL"},
+		{alias: "sleep", status: entities.StatusStopped, signal: signal("SIGTERM"),
+			message: "stopped, killed by SIGTERM"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.alias, func(t *testing.T) {
+			id := submit(t, s, test.alias)
+			if test.status == entities.StatusStopped {
+				logged(t, store, id, "ready")
+				assert.NoError(t, s.StopCommand(context.Background(), entities.StopCommandDto{Id: id}))
+			}
+			ec := wait(t, s, id)
+			assert.Equal(t, test.status, ec.Status)
+			assert.Equal(t, test.exitCode, ec.ExitCode)
+			assert.Equal(t, test.signal, ec.Signal)
+			assert.False(t, ec.IsActive)
+			assert.NotNil(t, ec.FinishedAt)
+			messages := store.messages(id)
+			assert.Equal(t, test.message, messages[len(messages)-1])
+		})
+	}
+}
+
+func TestService_timeout(t *testing.T) {
+	tests := []struct {
+		name       string
+		maxTimeout time.Duration
+		command    int
+		requested  int
+		expected   time.Duration
+		wantErr    bool
+	}{
+		{name: "None"},
+		{name: "Command", command: 30, expected: 30 * time.Second},
+		{name: "Requested", command: 30, requested: 10, expected: 10 * time.Second},
+		{name: "DefaultsToMax", maxTimeout: time.Minute, expected: time.Minute},
+		{name: "Capped", maxTimeout: time.Minute, command: 30, requested: 120, expected: time.Minute},
+		{name: "BelowMax", maxTimeout: time.Minute, requested: 20, expected: 20 * time.Second},
+		{name: "Negative", requested: -1, wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := &Service{Config: config.Config{Execution: config.Execution{MaxTimeout: test.maxTimeout}}}
+			timeout, err := c.timeout(entities.Command{Timeout: test.command},
+				entities.ExecuteCommandDto{Timeout: test.requested})
+			if test.wantErr {
+				assert.ErrorIs(t, err, entities.ErrInvalidParams)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, timeout)
+		})
+	}
+}
+
+func TestService_TimedOut(t *testing.T) {
+	cfg := config.Config{Execution: config.Execution{MaxTimeout: 200 * time.Millisecond}}
+	s, _ := newTestService(t, cfg, entities.Command{Alias: "sleep", Script: "sleep 30"})
+
+	started := time.Now()
+	ec := execute(t, s, "sleep")
+	assert.Equal(t, entities.StatusTimedOut, ec.Status)
+	assert.Less(t, time.Since(started), 10*time.Second, "the script is killed when the timeout expires")
+}
+
+// logged waits for a line of an execution to be saved.
+func logged(t *testing.T, store *memStore, id int, message string) {
+	t.Helper()
+	assert.Eventually(t, func() bool {
+		return slices.Contains(store.messages(id), message)
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestService_StopEscalates(t *testing.T) {
+	cfg := config.Config{Execution: config.Execution{GracePeriod: 200 * time.Millisecond}}
+	s, store := newTestService(t, cfg,
+		entities.Command{Alias: "stubborn", Script: "trap '' TERM; echo ready; sleep 30"})
+
+	id := submit(t, s, "stubborn")
+	logged(t, store, id, "ready")
+	stoppedAt := time.Now()
+	assert.NoError(t, s.StopCommand(context.Background(), entities.StopCommandDto{Id: id}))
+	ec := wait(t, s, id)
+	assert.GreaterOrEqual(t, time.Since(stoppedAt), 200*time.Millisecond, "SIGTERM is ignored until the grace period")
+	assert.Equal(t, entities.StatusStopped, ec.Status)
+	if assert.NotNil(t, ec.Signal) {
+		assert.Equal(t, "SIGKILL", *ec.Signal)
+	}
+	assert.Nil(t, ec.ExitCode)
+}
+
+func TestService_FollowDroppedLines(t *testing.T) {
+	cfg := config.Config{Logs: config.Logs{BufferSize: 1}}
+	s, _ := newTestService(t, cfg, entities.Command{Alias: "flood", Script: "sleep 0.2; seq 100000"})
+
+	id := submit(t, s, "flood")
+	logs, unsubscribe, err := s.FollowLogs(context.Background(), id)
+	assert.NoError(t, err)
+	defer unsubscribe()
+	var last entities.Log
+	for log := range logs {
+		last = log
+	}
+	ec := wait(t, s, id)
+	if assert.Positive(t, ec.DroppedLines) {
+		assert.Equal(t, fmt.Sprintf("%d lines of output were dropped", ec.DroppedLines), last.Message,
+			"followers see the last line written after the buffer is closed")
+	}
+}
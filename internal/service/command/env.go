@@ -0,0 +1,103 @@
+package command
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"testex/internal/entities"
+)
+
+// Variables set by testex for every execution, the reserved prefix can't be
+// used by commands.
+const (
+	reservedEnvPrefix = "TESTEX_"
+	executionIdEnv    = "TESTEX_EXECUTION_ID"
+	aliasEnv          = "TESTEX_ALIAS"
+	workdirEnv        = "TESTEX_WORKDIR"
+)
+
+// validateEnv checks the environment settings of a command, filling in the
+// default policy.
+func validateEnv(dto *entities.CommandDto) error {
+	switch dto.EnvInherit {
+	case "":
+		dto.EnvInherit = entities.EnvInheritAllowlist
+	case entities.EnvInheritNone, entities.EnvInheritAllowlist, entities.EnvInheritAll:
+	default:
+		return fmt.Errorf("%w: unknown env inherit policy %q", entities.ErrInvalidParams, dto.EnvInherit)
+	}
+	for name, value := range dto.Env {
+		if !paramNameRegexp.MatchString(name) {
+			return fmt.Errorf("%w: bad env name %q", entities.ErrInvalidParams, name)
+		}
+		if strings.HasPrefix(strings.ToUpper(name), reservedEnvPrefix) {
+			return fmt.Errorf("%w: env name %q uses the reserved prefix %s", entities.ErrInvalidParams, name,
+				reservedEnvPrefix)
+		}
+		if strings.ContainsRune(value, 0) {
+			return fmt.Errorf("%w: env %q contains a NUL byte", entities.ErrInvalidParams, name)
+		}
+	}
+	return nil
+}
+
+// environ builds the environment of an execution. Later entries win, so
+// params override variables of the command, which override inherited ones and
+// those describing the user. Secrets and the variables of testex are set last,
+// params never replace them.
+func (c *Service) environ(id int, req *request, dir string, secrets []string, who *identity) []string {
+	env := c.inherited(req.command.EnvInherit, os.Environ())
+	env = append(env, identityEnv(who)...)
+	names := make([]string, 0, len(req.command.Env))
+	for name := range req.command.Env {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		env = append(env, name+"="+req.command.Env[name])
+	}
+	env = append(env, paramsEnv(req.params)...)
+	env = append(env, secrets...)
+	return append(env,
+		executionIdEnv+"="+strconv.Itoa(id),
+		aliasEnv+"="+req.command.Alias,
+		workdirEnv+"="+dir,
+	)
+}
+
+// inherited filters the environment of testex by the policy of a command.
+// Denied variables and ones holding secrets of the configuration are always
+// left out.
+func (c *Service) inherited(policy entities.EnvInherit, environ []string) []string {
+	if policy == entities.EnvInheritNone {
+		return nil
+	}
+	secrets := c.Config.SecretValues()
+	env := make([]string, 0, len(environ))
+	for _, kv := range environ {
+		name, value, ok := strings.Cut(kv, "=")
+		if !ok || name == "" {
+			continue
+		}
+		if c.envListed(c.Config.ScriptEnv.Deny, name) || slices.Contains(secrets, value) {
+			continue
+		}
+		if policy == entities.EnvInheritAll || c.envListed(c.Config.ScriptEnv.Allowlist, name) {
+			env = append(env, kv)
+		}
+	}
+	return env
+}
+
+// envListed reports whether name is in the list. Names are case-insensitive
+// on Windows.
+func (c *Service) envListed(list []string, name string) bool {
+	return slices.ContainsFunc(list, func(listed string) bool {
+		if c.Config.Os == "win" {
+			return strings.EqualFold(listed, name)
+		}
+		return listed == name
+	})
+}
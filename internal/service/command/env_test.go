@@ -0,0 +1,72 @@
+package command
+
+import (
+	"errors"
+	"testex/internal/config"
+	"testex/internal/entities"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateEnv(t *testing.T) {
+	tests := []struct {
+		name  string
+		env   entities.EnvVars
+		valid bool
+	}{
+		{name: "Empty", valid: true},
+		{name: "Vars", env: entities.EnvVars{"GOFLAGS": "-mod=mod", "_x1": ""}, valid: true},
+		{name: "BadName", env: entities.EnvVars{"1X": "y"}},
+		{name: "Equals", env: entities.EnvVars{"A=B": "y"}},
+		{name: "Reserved", env: entities.EnvVars{"TESTEX_ALIAS": "y"}},
+		{name: "ReservedLowercase", env: entities.EnvVars{"testex_alias": "y"}},
+		{name: "Nul", env: entities.EnvVars{"A": "x\x00y"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dto := entities.CommandDto{Env: test.env}
+			err := validateEnv(&dto)
+			if test.valid {
+				assert.NoError(t, err)
+				assert.Equal(t, entities.EnvInheritAllowlist, dto.EnvInherit)
+			} else {
+				assert.True(t, errors.Is(err, entities.ErrInvalidParams), err)
+			}
+		})
+	}
+}
+
+func TestInherited(t *testing.T) {
+	environ := []string{"PATH=/usr/bin", "HOME=/root", "DB_PASSWORD=qwerty", "PGPASS=qwerty", "EDITOR=vi", "broken"}
+	cfg := config.Config{
+		Postgres:  config.PostgresDatabase{Password: "qwerty"},
+		ScriptEnv: config.ScriptEnv{Allowlist: []string{"PATH", "HOME", "PGPASS"}, Deny: []string{"DB_PASSWORD"}},
+	}
+	c := &Service{Config: cfg}
+
+	assert.Empty(t, c.inherited(entities.EnvInheritNone, environ))
+	assert.Equal(t, []string{"PATH=/usr/bin", "HOME=/root"}, c.inherited(entities.EnvInheritAllowlist, environ))
+	assert.Equal(t, []string{"PATH=/usr/bin", "HOME=/root", "EDITOR=vi"}, c.inherited(entities.EnvInheritAll, environ))
+
+	c.Config.Os = "win"
+	c.Config.ScriptEnv.Allowlist = []string{"Path"}
+	assert.Equal(t, []string{"PATH=/usr/bin"}, c.inherited(entities.EnvInheritAllowlist, environ))
+}
+
+func TestEnviron(t *testing.T) {
+	c := &Service{}
+	req := &request{
+		command: entities.Command{Alias: "build", EnvInherit: entities.EnvInheritNone,
+			Env: entities.EnvVars{"B": "2", "A": "1", "branch": "dev"}},
+		params: entities.ParamValues{"branch": "main", "TOKEN": "stolen"},
+	}
+
+	assert.Equal(t, []string{
+		"A=1", "B=2", "branch=dev",
+		"TOKEN=stolen", "branch=main",
+		"TOKEN=t0ken",
+		"TESTEX_EXECUTION_ID=7", "TESTEX_ALIAS=build", "TESTEX_WORKDIR=/tmp/7",
+	}, c.environ(7, req, "/tmp/7", []string{"TOKEN=t0ken"}, nil))
+}
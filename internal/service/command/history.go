@@ -0,0 +1,177 @@
+package command
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"testex/internal/entities"
+	"time"
+)
+
+const (
+	defaultExecutionsLimit = 50
+	defaultSearchLimit     = 100
+)
+
+// GetExecutions returns a page of executions matching the filter. cursor is
+// the NextCursor of the previous page, empty for the first one.
+func (c *Service) GetExecutions(ctx context.Context, filter entities.ExecutionFilter,
+	cursor string) (entities.ExecutionPage, error) {
+	if cursor != "" {
+		after, err := decodeCursor(cursor)
+		if err != nil {
+			return entities.ExecutionPage{}, fmt.Errorf("%w: wrong cursor", entities.ErrInvalidParams)
+		}
+		filter.After = &after
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = defaultExecutionsLimit
+	}
+	filter.ViewableBy = restricted(ctx)
+
+	// One more row tells whether there is a next page.
+	limit := filter.Limit
+	filter.Limit++
+	executions, err := c.Storage.GetExecutedCommands(filter)
+	if err != nil {
+		return entities.ExecutionPage{}, err
+	}
+
+	page := entities.ExecutionPage{Executions: executions}
+	if len(executions) > limit {
+		page.Executions = executions[:limit]
+		last := page.Executions[limit-1]
+		page.NextCursor = encodeCursor(entities.ExecutionCursor{StartedAt: last.StartedAt, Id: last.Id})
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for i := range page.Executions {
+		page.Executions[i].QueuePosition = c.queuePosition(page.Executions[i].Id)
+	}
+	return page, nil
+}
+
+// GetCommandExecutions is GetExecutions limited to one command.
+func (c *Service) GetCommandExecutions(ctx context.Context, alias string, filter entities.ExecutionFilter,
+	cursor string) (entities.ExecutionPage, error) {
+	command, err := c.GetOne(ctx, alias)
+	if err != nil {
+		return entities.ExecutionPage{}, err
+	}
+	if err = entities.Authorize(ctx, command.Acl, alias, entities.PermissionViewLogs); err != nil {
+		return entities.ExecutionPage{}, err
+	}
+	filter.CommandId = command.Id
+	return c.GetExecutions(ctx, filter, cursor)
+}
+
+// GetExecution returns the full record of an execution.
+func (c *Service) GetExecution(ctx context.Context, id int) (entities.ExecutionDetails, error) {
+	executed, err := c.GetExecutedCommand(ctx, id)
+	if err != nil {
+		return entities.ExecutionDetails{}, err
+	}
+	details := entities.ExecutionDetails{ExecutedCommand: executed}
+
+	// Executions started before revisions were kept have no snapshot.
+	if executed.RevisionId != nil {
+		revision, err := c.Storage.GetCommandRevision(*executed.RevisionId)
+		if err != nil {
+			return details, err
+		}
+		details.Command = &revision
+	}
+	if details.LogLines, err = c.Storage.CountLogs(id); err != nil {
+		return details, err
+	}
+	if details.Outputs, err = c.Storage.GetExecutionOutputs(id); err != nil {
+		return details, err
+	}
+	if details.Artifacts, err = c.Storage.GetArtifacts(id); err != nil {
+		return details, err
+	}
+	return details, nil
+}
+
+// OpenOutput opens the raw output of a stream of an execution. The caller
+// closes the returned reader.
+func (c *Service) OpenOutput(ctx context.Context, id int,
+	stream entities.LogStream) (entities.ExecutionOutput, io.ReadSeekCloser, error) {
+	if err := c.authorizeExecution(ctx, id, entities.PermissionViewLogs); err != nil {
+		return entities.ExecutionOutput{}, nil, err
+	}
+	output, err := c.Storage.GetExecutionOutput(id, stream)
+	if err != nil {
+		return output, nil, err
+	}
+	blob, err := c.Storage.OpenBlob(output.BlobKey)
+	if err != nil {
+		return output, nil, err
+	}
+	return output, blob, nil
+}
+
+// GetArtifacts lists files collected after an execution.
+func (c *Service) GetArtifacts(ctx context.Context, id int) ([]entities.Artifact, error) {
+	if _, err := c.GetExecutedCommand(ctx, id); err != nil {
+		return nil, err
+	}
+	return c.Storage.GetArtifacts(id)
+}
+
+// OpenArtifact opens a file collected after an execution by its path. The
+// caller closes the returned reader.
+func (c *Service) OpenArtifact(ctx context.Context, id int, path string) (entities.Artifact, io.ReadSeekCloser, error) {
+	if err := c.authorizeExecution(ctx, id, entities.PermissionViewLogs); err != nil {
+		return entities.Artifact{}, nil, err
+	}
+	artifact, err := c.Storage.GetArtifact(id, path)
+	if err != nil {
+		return artifact, nil, err
+	}
+	blob, err := c.Storage.OpenBlob(artifact.BlobKey)
+	if err != nil {
+		return artifact, nil, err
+	}
+	return artifact, blob, nil
+}
+
+// SearchLogs finds log lines of all executions matching a full-text query.
+func (c *Service) SearchLogs(ctx context.Context, search entities.LogSearch) ([]entities.LogMatch, error) {
+	if strings.TrimSpace(search.Query) == "" {
+		return nil, fmt.Errorf("%w: empty search query", entities.ErrInvalidParams)
+	}
+	if search.Limit <= 0 {
+		search.Limit = defaultSearchLimit
+	}
+	search.ViewableBy = restricted(ctx)
+	return c.Storage.SearchLogs(search)
+}
+
+func encodeCursor(cursor entities.ExecutionCursor) string {
+	raw := fmt.Sprintf("%d:%d", cursor.StartedAt.UnixNano(), cursor.Id)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(value string) (entities.ExecutionCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return entities.ExecutionCursor{}, err
+	}
+	startedAt, id, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return entities.ExecutionCursor{}, fmt.Errorf("malformed cursor")
+	}
+	nanos, err := strconv.ParseInt(startedAt, 10, 64)
+	if err != nil {
+		return entities.ExecutionCursor{}, err
+	}
+	cursor := entities.ExecutionCursor{StartedAt: time.Unix(0, nanos)}
+	if cursor.Id, err = strconv.Atoi(id); err != nil {
+		return entities.ExecutionCursor{}, err
+	}
+	return cursor, nil
+}
@@ -0,0 +1,23 @@
+package command
+
+import (
+	"testex/internal/entities"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCursor(t *testing.T) {
+	cursor := entities.ExecutionCursor{StartedAt: time.Date(2024, 5, 1, 12, 30, 0, 123456000, time.UTC), Id: 42}
+
+	decoded, err := decodeCursor(encodeCursor(cursor))
+	assert.NoError(t, err)
+	assert.True(t, cursor.StartedAt.Equal(decoded.StartedAt))
+	assert.Equal(t, cursor.Id, decoded.Id)
+
+	for _, value := range []string{"not base64!", "MTIz", "YWJjOjE"} {
+		_, err = decodeCursor(value)
+		assert.Error(t, err, value)
+	}
+}
@@ -0,0 +1,183 @@
+package command
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log/slog"
+	"testex/internal/config"
+	"testex/internal/entities"
+	sl "testex/pkg/slog"
+	"unicode/utf8"
+)
+
+const defaultMaxLineLength = 64 * 1024
+
+// outputLimits caps the output kept for an execution, zero means no limit.
+type outputLimits struct {
+	lineLength int
+	bytes      int64
+	lines      int
+}
+
+// limitsOf resolves the output limits of a command, which override the
+// server-wide ones.
+func limitsOf(command entities.Command, cfg config.Execution) outputLimits {
+	limits := outputLimits{
+		lineLength: cfg.MaxLineLength,
+		bytes:      cfg.MaxOutputBytes,
+		lines:      cfg.MaxOutputLines,
+	}
+	if command.MaxLineLength > 0 {
+		limits.lineLength = command.MaxLineLength
+	}
+	if command.MaxOutputBytes > 0 {
+		limits.bytes = command.MaxOutputBytes
+	}
+	if command.MaxOutputLines > 0 {
+		limits.lines = command.MaxOutputLines
+	}
+	if limits.lineLength <= 0 {
+		limits.lineLength = defaultMaxLineLength
+	}
+	return limits
+}
+
+// lineReader splits output into lines. Unlike bufio.Scanner it never stops on
+// a long line: the part beyond max is read and thrown away, so memory stays
+// bounded and the pipe keeps being drained.
+type lineReader struct {
+	r   *bufio.Reader
+	max int
+}
+
+func newLineReader(r io.Reader, max int) *lineReader {
+	return &lineReader{r: bufio.NewReader(r), max: max}
+}
+
+// next returns the next line without its line ending and the number of bytes
+// cut from its end. A final line without a newline is returned as well, the
+// error is returned by the call after it.
+func (l *lineReader) next() (string, int, error) {
+	var (
+		line []byte
+		cut  int
+	)
+	for {
+		chunk, err := l.r.ReadSlice('\n')
+		if err == nil {
+			chunk = chunk[:len(chunk)-1]
+		}
+		take := min(len(chunk), l.max-len(line))
+		line = append(line, chunk[:take]...)
+		cut += len(chunk) - take
+
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err != nil && len(line) == 0 && cut == 0 {
+			return "", 0, err
+		}
+		if cut > 0 {
+			// Don't leave half of a multibyte character behind.
+			i := max(len(line)-1, 0)
+			for i > 0 && !utf8.RuneStart(line[i]) {
+				i--
+			}
+			if !utf8.FullRune(line[i:]) {
+				cut += len(line) - i
+				line = line[:i]
+			}
+		} else if n := len(line); n > 0 && line[n-1] == '\r' {
+			line = line[:n-1]
+		}
+		return string(line), cut, nil
+	}
+}
+
+// drain discards the rest of the output.
+func (l *lineReader) drain() {
+	_, _ = io.Copy(io.Discard, l.r)
+}
+
+// saveOutput queues a line of output for storing, unless the output limits of
+// the execution are reached. Once they are, it leaves a system line about it
+// and returns false for this and all later lines.
+func (c *Service) saveOutput(id int, exe *execution, stream entities.LogStream, level entities.LogLevel,
+	message string) bool {
+	exe.logMu.Lock()
+	defer exe.logMu.Unlock()
+	if exe.capped {
+		return false
+	}
+	limits := exe.limits
+	if limits.lines > 0 && exe.lines >= limits.lines || limits.bytes > 0 && exe.bytes+int64(len(message)) > limits.bytes {
+		exe.capped = true
+		exe.truncated = true
+		c.writeLog(id, exe, entities.StreamSystem, entities.LevelWarn, fmt.Sprintf(
+			"output limit reached after %d lines and %d bytes, the rest of the output is discarded", exe.lines, exe.bytes))
+		return false
+	}
+	exe.lines++
+	exe.bytes += int64(len(message))
+	c.writeLog(id, exe, stream, level, message)
+	return true
+}
+
+// consume reads a stream of the process, storing it as the output mode of the
+// command says. Secrets are redacted from the stream before it is split into
+// lines or captured.
+func (c *Service) consume(id int, exe *execution, stream entities.LogStream, r io.Reader) {
+	if exe.redactor != nil {
+		r = exe.redactor.Reader(r)
+	}
+	switch exe.outputMode {
+	case entities.OutputRaw:
+		c.capture(id, exe, stream, r)
+	case entities.OutputBoth:
+		pr, pw := io.Pipe()
+		captured := make(chan struct{})
+		go func() {
+			defer close(captured)
+			c.capture(id, exe, stream, pr)
+		}()
+		c.scan(id, exe, stream, io.TeeReader(r, pw))
+		pw.Close()
+		<-captured
+	default:
+		c.scan(id, exe, stream, r)
+	}
+}
+
+// capture stores a stream of the process as is in the blob store, up to the
+// output byte limit of the execution. The rest of the stream, or all of it if
+// storing fails, is discarded, so the process never blocks.
+func (c *Service) capture(id int, exe *execution, stream entities.LogStream, r io.Reader) {
+	limited := r
+	if exe.limits.bytes > 0 {
+		limited = io.LimitReader(r, exe.limits.bytes)
+	}
+	key, size, err := c.Storage.PutBlob(limited)
+	if err != nil {
+		c.Logger.Error("failed to store raw output", slog.Int("id", id), slog.String("stream", string(stream)), sl.Err(err))
+		c.saveLog(id, exe, entities.StreamSystem, entities.LevelError, fmt.Sprintf("failed to store raw %s", stream))
+		_, _ = io.Copy(io.Discard, r)
+		return
+	}
+	if discarded, _ := io.Copy(io.Discard, r); discarded > 0 {
+		exe.logMu.Lock()
+		exe.truncated = true
+		c.writeLog(id, exe, entities.StreamSystem, entities.LevelWarn, fmt.Sprintf(
+			"raw %s limit reached after %d bytes, %d more bytes are discarded", stream, size, discarded))
+		exe.logMu.Unlock()
+	}
+	err = c.Storage.SaveExecutionOutput(entities.ExecutionOutput{
+		ExecutedCommandId: id,
+		Stream:            stream,
+		BlobKey:           key,
+		Size:              size,
+	})
+	if err != nil {
+		c.Logger.Error("failed to save raw output", slog.Int("id", id), slog.String("stream", string(stream)), sl.Err(err))
+	}
+}
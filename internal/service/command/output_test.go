@@ -0,0 +1,96 @@
+package command
+
+import (
+	"io"
+	"strings"
+	"testex/internal/config"
+	"testex/internal/entities"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLineReader(t *testing.T) {
+	type line struct {
+		text string
+		cut  int
+	}
+
+	tests := []struct {
+		name     string
+		input    string
+		max      int
+		expected []line
+	}{
+		{
+			name:     "Lines",
+			input:    "one\ntwo\r\n\nlast",
+			max:      10,
+			expected: []line{{"one", 0}, {"two", 0}, {"", 0}, {"last", 0}},
+		},
+		{
+			name:     "LongLine",
+			input:    "0123456789abcdef\nnext\n",
+			max:      10,
+			expected: []line{{"0123456789", 6}, {"next", 0}},
+		},
+		{
+			name:     "LongerThanBuffer",
+			input:    strings.Repeat("x", 10000) + "\n" + strings.Repeat("y", 5000) + "\n",
+			max:      6000,
+			expected: []line{{strings.Repeat("x", 6000), 4000}, {strings.Repeat("y", 5000), 0}},
+		},
+		{
+			name:     "NoNewline",
+			input:    strings.Repeat("z", 100000),
+			max:      16,
+			expected: []line{{strings.Repeat("z", 16), 99984}},
+		},
+		{
+			name:     "MultibyteBoundary",
+			input:    "абв\n",
+			max:      5,
+			expected: []line{{"аб", 2}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := newLineReader(strings.NewReader(test.input), test.max)
+			var lines []line
+			for {
+				text, cut, err := r.next()
+				if err != nil {
+					assert.ErrorIs(t, err, io.EOF)
+					break
+				}
+				lines = append(lines, line{text, cut})
+			}
+			assert.Equal(t, test.expected, lines)
+		})
+	}
+}
+
+func TestLimitsOf(t *testing.T) {
+	cfg := config.Execution{MaxOutputBytes: 1000, MaxOutputLines: 10}
+
+	assert.Equal(t, outputLimits{lineLength: defaultMaxLineLength, bytes: 1000, lines: 10},
+		limitsOf(entities.Command{}, cfg))
+	assert.Equal(t, outputLimits{lineLength: 80, bytes: 1000, lines: 5},
+		limitsOf(entities.Command{MaxLineLength: 80, MaxOutputLines: 5}, cfg))
+}
+
+func TestService_CaptureLimit(t *testing.T) {
+	s, store := newTestService(t, config.Config{},
+		entities.Command{Alias: "chatty", Script: "head -c 5000 /dev/zero | tr '\\0' x", OutputMode: entities.OutputRaw,
+			MaxOutputBytes: 1000})
+
+	ec := execute(t, s, "chatty")
+	assert.Equal(t, entities.StatusSucceeded, ec.Status)
+	assert.True(t, ec.Truncated)
+	output, err := store.GetExecutionOutput(ec.Id, entities.StreamStdout)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1000), output.Size)
+	assert.Equal(t, strings.Repeat("x", 1000), string(store.blobs[output.BlobKey]))
+	assert.Contains(t, store.messages(ec.Id), "raw stdout limit reached after 1000 bytes, 4000 more bytes are discarded")
+}
@@ -0,0 +1,180 @@
+package command
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"testex/internal/entities"
+)
+
+var paramNameRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Params are passed to the script as environment variables, so a caller who
+// may only execute a command chooses their values. Variables that change how
+// the shell, the dynamic linker or common interpreters run the script can't be
+// params, a caller would take over the script through them.
+var (
+	unsafeParamNames = []string{
+		"PATH", "IFS", "ENV", "BASH_ENV", "CDPATH", "GLOBIGNORE", "SHELLOPTS", "BASHOPTS", "PS4",
+		"PROMPT_COMMAND", "HOME", "USER", "LOGNAME", "SHELL", "TMPDIR", "GCONV_PATH", "HOSTALIASES",
+		"PYTHONPATH", "PYTHONSTARTUP", "PERL5LIB", "PERL5OPT", "NODE_OPTIONS", "RUBYOPT", "RUBYLIB",
+	}
+	unsafeParamPrefixes = []string{reservedEnvPrefix, "LD_", "DYLD_", "BASH_FUNC_"}
+)
+
+// validateParams checks parameter declarations of a command before it is saved.
+func validateParams(params entities.CommandParams) error {
+	seen := make(map[string]bool, len(params))
+	for _, p := range params {
+		if !paramNameRegexp.MatchString(p.Name) {
+			return fmt.Errorf("%w: bad param name %q", entities.ErrInvalidParams, p.Name)
+		}
+		if slices.Contains(unsafeParamNames, p.Name) || slices.ContainsFunc(unsafeParamPrefixes, func(prefix string) bool {
+			return strings.HasPrefix(strings.ToUpper(p.Name), prefix)
+		}) {
+			return fmt.Errorf("%w: param name %q is reserved", entities.ErrInvalidParams, p.Name)
+		}
+		if seen[p.Name] {
+			return fmt.Errorf("%w: duplicate param %q", entities.ErrInvalidParams, p.Name)
+		}
+		seen[p.Name] = true
+
+		switch p.Type {
+		case entities.ParamString, entities.ParamInt, entities.ParamBool:
+		case entities.ParamEnum:
+			if len(p.Values) == 0 {
+				return fmt.Errorf("%w: enum param %q has no values", entities.ErrInvalidParams, p.Name)
+			}
+		default:
+			return fmt.Errorf("%w: param %q has unknown type %q", entities.ErrInvalidParams, p.Name, p.Type)
+		}
+		if p.Pattern != "" {
+			if _, err := regexp.Compile(p.Pattern); err != nil {
+				return fmt.Errorf("%w: param %q has bad pattern: %v", entities.ErrInvalidParams, p.Name, err)
+			}
+		}
+		if p.Default != nil {
+			if _, err := checkParam(p, *p.Default); err != nil {
+				return fmt.Errorf("default value: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// checkParamEnv rejects params named like a variable the command sets itself,
+// a caller would replace it, or the value of a secret, through the param.
+func checkParamEnv(dto entities.CommandDto) error {
+	for _, p := range dto.Params {
+		if _, ok := dto.Env[p.Name]; ok {
+			return fmt.Errorf("%w: param %q is also set in env", entities.ErrInvalidParams, p.Name)
+		}
+		if slices.ContainsFunc(dto.Secrets, func(ref entities.SecretRef) bool { return ref.Env == p.Name }) {
+			return fmt.Errorf("%w: param %q is also the env of a secret", entities.ErrInvalidParams, p.Name)
+		}
+	}
+	return nil
+}
+
+// CheckParams validates values kept for later runs of a command, such as the
+// params of a schedule, the same way Execute does.
+func (c *Service) CheckParams(command entities.Command, input entities.ParamInput) error {
+	_, err := resolveParams(command.Params, input)
+	return err
+}
+
+// resolveParams validates request values against the declarations and
+// returns their string form, filling in defaults for omitted params.
+func resolveParams(params entities.CommandParams, input map[string]any) (entities.ParamValues, error) {
+	for name := range input {
+		if !slices.ContainsFunc(params, func(p entities.CommandParam) bool { return p.Name == name }) {
+			return nil, fmt.Errorf("%w: unknown param %q", entities.ErrInvalidParams, name)
+		}
+	}
+
+	values := make(entities.ParamValues, len(params))
+	for _, p := range params {
+		raw, ok := input[p.Name]
+		if !ok || raw == nil {
+			switch {
+			case p.Default != nil:
+				values[p.Name] = *p.Default
+			case p.Required:
+				return nil, fmt.Errorf("%w: param %q is required", entities.ErrInvalidParams, p.Name)
+			}
+			continue
+		}
+		value, err := checkParam(p, raw)
+		if err != nil {
+			return nil, err
+		}
+		values[p.Name] = value
+	}
+	return values, nil
+}
+
+func checkParam(p entities.CommandParam, raw any) (string, error) {
+	var value string
+	switch p.Type {
+	case entities.ParamString, entities.ParamEnum:
+		s, ok := raw.(string)
+		if !ok {
+			return "", fmt.Errorf("%w: param %q must be a string", entities.ErrInvalidParams, p.Name)
+		}
+		if p.Type == entities.ParamEnum && !slices.Contains(p.Values, s) {
+			return "", fmt.Errorf("%w: param %q must be one of %v", entities.ErrInvalidParams, p.Name, p.Values)
+		}
+		value = s
+	case entities.ParamInt:
+		switch v := raw.(type) {
+		case float64:
+			if v != math.Trunc(v) {
+				return "", fmt.Errorf("%w: param %q must be an integer", entities.ErrInvalidParams, p.Name)
+			}
+			value = strconv.FormatInt(int64(v), 10)
+		case string:
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return "", fmt.Errorf("%w: param %q must be an integer", entities.ErrInvalidParams, p.Name)
+			}
+			value = strconv.FormatInt(n, 10)
+		default:
+			return "", fmt.Errorf("%w: param %q must be an integer", entities.ErrInvalidParams, p.Name)
+		}
+	case entities.ParamBool:
+		switch v := raw.(type) {
+		case bool:
+			value = strconv.FormatBool(v)
+		case string:
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return "", fmt.Errorf("%w: param %q must be a boolean", entities.ErrInvalidParams, p.Name)
+			}
+			value = strconv.FormatBool(b)
+		default:
+			return "", fmt.Errorf("%w: param %q must be a boolean", entities.ErrInvalidParams, p.Name)
+		}
+	}
+
+	if p.Pattern != "" {
+		// Patterns are validated on create, so MustCompile can't panic here.
+		if !regexp.MustCompile(p.Pattern).MatchString(value) {
+			return "", fmt.Errorf("%w: param %q does not match %s", entities.ErrInvalidParams, p.Name, p.Pattern)
+		}
+	}
+	return value, nil
+}
+
+// paramsEnv exposes resolved params to the script as environment variables,
+// so values never end up inside the script text itself.
+func paramsEnv(values entities.ParamValues) []string {
+	env := make([]string, 0, len(values))
+	for name, value := range values {
+		env = append(env, name+"="+value)
+	}
+	slices.Sort(env)
+	return env
+}
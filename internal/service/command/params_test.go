@@ -0,0 +1,111 @@
+package command
+
+import (
+	"errors"
+	"testex/internal/entities"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveParams(t *testing.T) {
+	def := "prod"
+	params := entities.CommandParams{
+		{Name: "name", Type: entities.ParamString, Required: true, Pattern: `^[a-z]+$`},
+		{Name: "count", Type: entities.ParamInt},
+		{Name: "force", Type: entities.ParamBool},
+		{Name: "env", Type: entities.ParamEnum, Values: []string{"dev", "prod"}, Default: &def},
+	}
+
+	tests := []struct {
+		name           string
+		input          map[string]any
+		expectedValues entities.ParamValues
+		expectedErr    bool
+	}{
+		{
+			name:           "Ok",
+			input:          map[string]any{"name": "web", "count": float64(3), "force": true},
+			expectedValues: entities.ParamValues{"name": "web", "count": "3", "force": "true", "env": "prod"},
+		},
+		{
+			name:           "Ok_StringValues",
+			input:          map[string]any{"name": "web", "count": "7", "force": "false", "env": "dev"},
+			expectedValues: entities.ParamValues{"name": "web", "count": "7", "force": "false", "env": "dev"},
+		},
+		{
+			name:        "MissingRequired",
+			input:       map[string]any{"count": float64(1)},
+			expectedErr: true,
+		},
+		{
+			name:        "PatternMismatch",
+			input:       map[string]any{"name": "web; rm -rf /"},
+			expectedErr: true,
+		},
+		{
+			name:        "NotAnInteger",
+			input:       map[string]any{"name": "web", "count": 1.5},
+			expectedErr: true,
+		},
+		{
+			name:        "EnumOutOfRange",
+			input:       map[string]any{"name": "web", "env": "staging"},
+			expectedErr: true,
+		},
+		{
+			name:        "UnknownParam",
+			input:       map[string]any{"name": "web", "extra": "x"},
+			expectedErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			values, err := resolveParams(params, test.input)
+			if test.expectedErr {
+				assert.ErrorIs(t, err, entities.ErrInvalidParams)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expectedValues, values)
+		})
+	}
+}
+
+func TestValidateParamNames(t *testing.T) {
+	tests := []struct {
+		name  string
+		dto   entities.CommandDto
+		valid bool
+	}{
+		{name: "Ok", dto: entities.CommandDto{Params: entities.CommandParams{{Name: "branch"}, {Name: "env"}}},
+			valid: true},
+		{name: "Path", dto: entities.CommandDto{Params: entities.CommandParams{{Name: "PATH"}}}},
+		{name: "BashEnv", dto: entities.CommandDto{Params: entities.CommandParams{{Name: "BASH_ENV"}}}},
+		{name: "Ifs", dto: entities.CommandDto{Params: entities.CommandParams{{Name: "IFS"}}}},
+		{name: "LdPreload", dto: entities.CommandDto{Params: entities.CommandParams{{Name: "LD_PRELOAD"}}}},
+		{name: "Reserved", dto: entities.CommandDto{Params: entities.CommandParams{{Name: "testex_alias"}}}},
+		{name: "SetInEnv", dto: entities.CommandDto{Params: entities.CommandParams{{Name: "GOFLAGS"}},
+			Env: entities.EnvVars{"GOFLAGS": "-mod=mod"}}},
+		{name: "SecretEnv", dto: entities.CommandDto{Params: entities.CommandParams{{Name: "TOKEN"}},
+			Secrets: entities.SecretRefs{{Name: "token", Env: "TOKEN"}}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for i := range test.dto.Params {
+				test.dto.Params[i].Type = entities.ParamString
+			}
+			err := validateParams(test.dto.Params)
+			if err == nil {
+				err = checkParamEnv(test.dto)
+			}
+			if test.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.True(t, errors.Is(err, entities.ErrInvalidParams), err)
+			}
+		})
+	}
+}
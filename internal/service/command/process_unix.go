@@ -0,0 +1,98 @@
+//go:build !windows
+
+package command
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// setProcessGroup starts the script in its own process group, so that
+// everything it spawns can be signalled at once.
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// killProcessTree kills the process group led by p.
+func killProcessTree(p *os.Process) error {
+	return signalProcessTree(p, syscall.SIGKILL)
+}
+
+// exitStatus extracts the exit code, or the terminating signal name if the
+// process was killed by a signal.
+func exitStatus(state *os.ProcessState) (*int, *string) {
+	if ws, ok := state.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+		name := unix.SignalName(ws.Signal())
+		return nil, &name
+	}
+	code := state.ExitCode()
+	return &code, nil
+}
+
+// parseSignal resolves a signal name such as "SIGINT" or "int".
+func parseSignal(name string) (syscall.Signal, error) {
+	name = strings.ToUpper(name)
+	if !strings.HasPrefix(name, "SIG") {
+		name = "SIG" + name
+	}
+	sig := unix.SignalNum(name)
+	if sig == 0 {
+		return 0, fmt.Errorf("unknown signal %q", name)
+	}
+	return sig, nil
+}
+
+// signalProcessTree sends sig to the process group led by p.
+func signalProcessTree(p *os.Process, sig syscall.Signal) error {
+	err := syscall.Kill(-p.Pid, sig)
+	if errors.Is(err, syscall.ESRCH) {
+		return os.ErrProcessDone
+	}
+	return err
+}
+
+// isTerminating reports whether sig is meant to end the process, as opposed
+// to signals that scripts commonly handle to reload or report state.
+func isTerminating(sig syscall.Signal) bool {
+	switch sig {
+	case syscall.SIGHUP, syscall.SIGUSR1, syscall.SIGUSR2, syscall.SIGCONT, syscall.SIGWINCH:
+		return false
+	}
+	return true
+}
+
+// openInside opens the file name, a slash-separated path relative to dir,
+// one component at a time without following symbolic links, so that it can't
+// be swapped for a link leading out of dir. Special files are opened without
+// blocking.
+func openInside(dir, name string) (*os.File, error) {
+	fd, err := unix.Open(dir, unix.O_RDONLY|unix.O_DIRECTORY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: dir, Err: err}
+	}
+	parts := strings.Split(name, "/")
+	for i, part := range parts {
+		if part == "" || part == "." || part == ".." {
+			unix.Close(fd)
+			return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrInvalid}
+		}
+		flags := unix.O_RDONLY | unix.O_NOFOLLOW | unix.O_CLOEXEC | unix.O_NONBLOCK
+		if i < len(parts)-1 {
+			flags |= unix.O_DIRECTORY
+		}
+		next, err := unix.Openat(fd, part, flags, 0)
+		unix.Close(fd)
+		if err != nil {
+			return nil, &os.PathError{Op: "open", Path: name, Err: err}
+		}
+		fd = next
+	}
+	return os.NewFile(uintptr(fd), filepath.Join(dir, filepath.FromSlash(name))), nil
+}
@@ -0,0 +1,69 @@
+//go:build windows
+
+package command
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP}
+}
+
+// killProcessTree kills p together with all of its descendants.
+func killProcessTree(p *os.Process) error {
+	return exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(p.Pid)).Run()
+}
+
+// exitStatus extracts the exit code of the process. Windows has no signals,
+// so the signal is always nil.
+func exitStatus(state *os.ProcessState) (*int, *string) {
+	code := state.ExitCode()
+	return &code, nil
+}
+
+var signals = map[string]syscall.Signal{
+	"SIGHUP":  syscall.SIGHUP,
+	"SIGINT":  syscall.SIGINT,
+	"SIGQUIT": syscall.SIGQUIT,
+	"SIGKILL": syscall.SIGKILL,
+	"SIGTERM": syscall.SIGTERM,
+}
+
+func parseSignal(name string) (syscall.Signal, error) {
+	name = strings.ToUpper(name)
+	if !strings.HasPrefix(name, "SIG") {
+		name = "SIG" + name
+	}
+	sig, ok := signals[name]
+	if !ok {
+		return 0, fmt.Errorf("unknown signal %q", name)
+	}
+	return sig, nil
+}
+
+// signalProcessTree terminates the process tree of p. Windows can't deliver
+// signals to console processes, so every signal ends up as a forced kill.
+func signalProcessTree(p *os.Process, _ syscall.Signal) error {
+	return killProcessTree(p)
+}
+
+func isTerminating(_ syscall.Signal) bool {
+	return true
+}
+
+// openInside opens the file name, a slash-separated path relative to dir,
+// refusing paths that go through symbolic links. Windows has no openat, so
+// a link created between the check and the open isn't noticed.
+func openInside(dir, name string) (*os.File, error) {
+	if !regularInside(dir, name) {
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrInvalid}
+	}
+	return os.Open(filepath.Join(dir, filepath.FromSlash(name)))
+}
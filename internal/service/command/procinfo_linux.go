@@ -0,0 +1,38 @@
+//go:build linux
+
+package command
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// processKey identifies a process across PID reuse: the boot it runs in and
+// its start time in clock ticks since boot.
+func processKey(pid int) (string, error) {
+	bootId, err := os.ReadFile("/proc/sys/kernel/random/boot_id")
+	if err != nil {
+		return "", err
+	}
+	stat, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+	if err != nil {
+		return "", err
+	}
+	// The command name may contain spaces, fields are counted after it.
+	i := strings.LastIndexByte(string(stat), ')')
+	if i < 0 {
+		return "", fmt.Errorf("unexpected stat format of pid %d", pid)
+	}
+	fields := strings.Fields(string(stat[i+1:]))
+	// starttime is field 22, the fields here start with field 3.
+	if len(fields) < 20 {
+		return "", fmt.Errorf("unexpected stat format of pid %d", pid)
+	}
+	// A zombie has already exited, it just hasn't been reaped yet.
+	if fields[0] == "Z" {
+		return "", fmt.Errorf("pid %d has exited", pid)
+	}
+	return strings.TrimSpace(string(bootId)) + "/" + fields[19], nil
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package command
+
+// processKey is not supported outside of Linux, so executions left over from
+// a previous run are never adopted there.
+func processKey(pid int) (string, error) {
+	return "", nil
+}
@@ -0,0 +1,176 @@
+package command
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"testex/internal/entities"
+	sl "testex/pkg/slog"
+	"time"
+)
+
+// queued is an execution waiting for a free worker or for a run of its
+// command to finish.
+type queued struct {
+	id       int
+	req      *request
+	queuedAt time.Time
+}
+
+// enqueue admits an execution: it is started right away when the global and
+// per-command limits allow, otherwise it waits in the queue.
+func (c *Service) enqueue(req *request) (int, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	command := req.command
+	replaced, replacedRunning := -1, false
+	if command.MaxConcurrency > 0 && c.active(command.Id) >= command.MaxConcurrency {
+		switch command.Overflow {
+		case entities.OverflowReject:
+			return -1, fmt.Errorf("%w: command %s is at its concurrency limit of %d", entities.ErrExecutionRejected,
+				command.Alias, command.MaxConcurrency)
+		case entities.OverflowReplace:
+			replaced, replacedRunning = c.oldestRun(command.Id)
+		}
+	}
+
+	// Capacity is checked before anything is replaced, a rejected run must
+	// not stop another one. A replaced queued run frees its place.
+	startNow := c.canStart(command)
+	waiting := len(c.queue)
+	if replaced != -1 && !replacedRunning {
+		waiting--
+	}
+	if !startNow && c.Config.Execution.QueueSize > 0 && waiting >= c.Config.Execution.QueueSize {
+		return -1, fmt.Errorf("%w: execution queue is full", entities.ErrExecutionRejected)
+	}
+	if replaced != -1 {
+		c.Logger.Info("replacing execution", slog.Int("id", replaced))
+		if !replacedRunning {
+			c.cancelQueued(replaced)
+		} else if err := c.stop(replaced, c.running[replaced], ""); err != nil {
+			return -1, err
+		}
+	}
+
+	now := time.Now()
+	id, err := c.Storage.SaveExecutedCommand(entities.ExecutedCommand{
+		CommandId:   command.Id,
+		Params:      req.params,
+		Status:      entities.StatusQueued,
+		StartedAt:   now,
+		ScheduleId:  req.scheduleId,
+		RevisionId:  &command.RevisionId,
+		TriggeredBy: req.triggeredBy,
+		Actor:       req.actor,
+	})
+	if err != nil {
+		return -1, err
+	}
+	c.waiters[id] = make(chan struct{})
+
+	if !startNow {
+		c.queue = append(c.queue, &queued{id: id, req: req, queuedAt: now})
+		c.Logger.Info("execution queued", slog.Int("id", id), slog.Int("position", len(c.queue)))
+		return id, nil
+	}
+	if err = c.start(id, req); err != nil {
+		c.settle(id, now, entities.StatusFailed, "failed to start: "+err.Error())
+		return -1, err
+	}
+	return id, nil
+}
+
+// dispatch starts queued executions in order, as far as the limits allow.
+// It is called with the mutex held whenever a slot may have been freed.
+func (c *Service) dispatch() {
+	for i := 0; i < len(c.queue); {
+		if c.Config.Execution.Workers > 0 && len(c.running) >= c.Config.Execution.Workers {
+			return
+		}
+		q := c.queue[i]
+		if !c.canStart(q.req.command) {
+			// Runs of other commands may still fit.
+			i++
+			continue
+		}
+		c.queue = slices.Delete(c.queue, i, i+1)
+		if err := c.start(q.id, q.req); err != nil {
+			c.Logger.Error("failed to start queued execution", slog.Int("id", q.id), sl.Err(err))
+			c.settle(q.id, q.queuedAt, entities.StatusFailed, "failed to start: "+err.Error())
+		}
+	}
+}
+
+// canStart reports whether a run of command fits into the limits right now.
+func (c *Service) canStart(command entities.Command) bool {
+	if c.Config.Execution.Workers > 0 && len(c.running) >= c.Config.Execution.Workers {
+		return false
+	}
+	return command.MaxConcurrency == 0 || c.runningOf(command.Id) < command.MaxConcurrency
+}
+
+func (c *Service) runningOf(commandId int) int {
+	n := 0
+	for _, exe := range c.running {
+		if exe.commandId == commandId {
+			n++
+		}
+	}
+	return n
+}
+
+// active counts running and queued runs of a command.
+func (c *Service) active(commandId int) int {
+	n := c.runningOf(commandId)
+	for _, q := range c.queue {
+		if q.req.command.Id == commandId {
+			n++
+		}
+	}
+	return n
+}
+
+// oldestRun picks the execution of a command to replace: the oldest running
+// one, skipping runs that are already being stopped so bursts don't stop
+// everything, or else the oldest queued one. It returns -1 without one.
+func (c *Service) oldestRun(commandId int) (id int, running bool) {
+	oldest := -1
+	for id, exe := range c.running {
+		if exe.commandId != commandId || exe.stopped {
+			continue
+		}
+		if oldest == -1 || exe.startedAt.Before(c.running[oldest].startedAt) {
+			oldest = id
+		}
+	}
+	if oldest != -1 {
+		return oldest, true
+	}
+
+	for _, q := range c.queue {
+		if q.req.command.Id == commandId {
+			return q.id, false
+		}
+	}
+	return -1, false
+}
+
+// cancelQueued removes an execution from the queue and marks it as stopped.
+func (c *Service) cancelQueued(id int) bool {
+	i := slices.IndexFunc(c.queue, func(q *queued) bool { return q.id == id })
+	if i == -1 {
+		return false
+	}
+	q := c.queue[i]
+	c.queue = slices.Delete(c.queue, i, i+1)
+	c.settle(id, q.queuedAt, entities.StatusStopped, "stopped before it was started")
+	return true
+}
+
+// queuePosition returns the 1-based position of an execution in the queue,
+// or zero if it isn't queued.
+func (c *Service) queuePosition(id int) int {
+	return slices.IndexFunc(c.queue, func(q *queued) bool { return q.id == id }) + 1
+}
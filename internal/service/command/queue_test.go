@@ -0,0 +1,126 @@
+package command
+
+import (
+	"context"
+	"testex/internal/config"
+	"testex/internal/entities"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// submit executes a command without waiting for it.
+func submit(t *testing.T, s *Service, alias string) int {
+	t.Helper()
+	id, err := s.Execute(context.Background(), entities.ExecuteCommandDto{Alias: alias})
+	assert.NoError(t, err)
+	return id
+}
+
+// status returns the status of an execution and its place in the queue.
+func status(t *testing.T, s *Service, id int) (entities.ExecutionStatus, int) {
+	t.Helper()
+	ec, err := s.GetExecutedCommand(context.Background(), id)
+	assert.NoError(t, err)
+	return ec.Status, ec.QueuePosition
+}
+
+// running waits for a queued execution to be started.
+func running(t *testing.T, s *Service, id int, msgAndArgs ...any) {
+	t.Helper()
+	assert.Eventually(t, func() bool {
+		st, _ := status(t, s, id)
+		return st == entities.StatusRunning
+	}, 5*time.Second, 10*time.Millisecond, msgAndArgs...)
+}
+
+// stopAll stops executions and waits for them to finish.
+func stopAll(t *testing.T, s *Service, ids ...int) {
+	t.Helper()
+	for _, id := range ids {
+		_ = s.StopCommand(context.Background(), entities.StopCommandDto{Id: id})
+		wait(t, s, id)
+	}
+}
+
+func TestService_MaxConcurrency(t *testing.T) {
+	s, _ := newTestService(t, config.Config{},
+		entities.Command{Alias: "sleep", Script: "sleep 30", MaxConcurrency: 1, Overflow: entities.OverflowQueue},
+	)
+
+	first, second, third := submit(t, s, "sleep"), submit(t, s, "sleep"), submit(t, s, "sleep")
+	st, position := status(t, s, first)
+	assert.Equal(t, entities.StatusRunning, st)
+	assert.Zero(t, position)
+	st, position = status(t, s, second)
+	assert.Equal(t, entities.StatusQueued, st)
+	assert.Equal(t, 1, position)
+	_, position = status(t, s, third)
+	assert.Equal(t, 2, position)
+
+	stopAll(t, s, first)
+	running(t, s, second, "the next run starts when the slot frees up")
+	_, position = status(t, s, third)
+	assert.Equal(t, 1, position)
+	stopAll(t, s, third, second)
+}
+
+func TestService_OverflowReject(t *testing.T) {
+	s, _ := newTestService(t, config.Config{},
+		entities.Command{Alias: "sleep", Script: "sleep 30", MaxConcurrency: 1, Overflow: entities.OverflowReject},
+	)
+
+	first := submit(t, s, "sleep")
+	_, err := s.Execute(context.Background(), entities.ExecuteCommandDto{Alias: "sleep"})
+	assert.ErrorIs(t, err, entities.ErrExecutionRejected)
+	stopAll(t, s, first)
+}
+
+func TestService_QueueFull(t *testing.T) {
+	cfg := config.Config{Execution: config.Execution{Workers: 1, QueueSize: 1}}
+	s, _ := newTestService(t, cfg, entities.Command{Alias: "sleep", Script: "sleep 30"})
+
+	first, second := submit(t, s, "sleep"), submit(t, s, "sleep")
+	_, err := s.Execute(context.Background(), entities.ExecuteCommandDto{Alias: "sleep"})
+	assert.ErrorIs(t, err, entities.ErrExecutionRejected)
+	st, position := status(t, s, second)
+	assert.Equal(t, entities.StatusQueued, st)
+	assert.Equal(t, 1, position)
+	stopAll(t, s, second, first)
+}
+
+func TestService_OverflowReplace(t *testing.T) {
+	s, _ := newTestService(t, config.Config{},
+		entities.Command{Alias: "sleep", Script: "sleep 30", MaxConcurrency: 1, Overflow: entities.OverflowReplace},
+	)
+
+	first := submit(t, s, "sleep")
+	second := submit(t, s, "sleep")
+	assert.Equal(t, entities.StatusStopped, wait(t, s, first).Status)
+	running(t, s, second, "the replacing run starts once the old one exited")
+
+	// A queued run is replaced when no running one is left to stop.
+	third := submit(t, s, "sleep")
+	fourth := submit(t, s, "sleep")
+	assert.Equal(t, entities.StatusStopped, wait(t, s, third).Status)
+	st, position := status(t, s, fourth)
+	assert.Equal(t, entities.StatusQueued, st)
+	assert.Equal(t, 1, position)
+	stopAll(t, s, fourth, second)
+}
+
+func TestService_OverflowReplaceQueueFull(t *testing.T) {
+	cfg := config.Config{Execution: config.Execution{Workers: 1, QueueSize: 1}}
+	s, _ := newTestService(t, cfg,
+		entities.Command{Alias: "sleep", Script: "sleep 30", MaxConcurrency: 1, Overflow: entities.OverflowReplace},
+		entities.Command{Alias: "other", Script: "sleep 30"},
+	)
+
+	first, other := submit(t, s, "sleep"), submit(t, s, "other")
+	_, err := s.Execute(context.Background(), entities.ExecuteCommandDto{Alias: "sleep"})
+	assert.ErrorIs(t, err, entities.ErrExecutionRejected)
+	st, _ := status(t, s, first)
+	assert.Equal(t, entities.StatusRunning, st, "a rejected run doesn't stop the one it would replace")
+	stopAll(t, s, other, first)
+}
@@ -0,0 +1,113 @@
+package command
+
+import (
+	"log/slog"
+	"os"
+	"testex/internal/entities"
+	sl "testex/pkg/slog"
+	"time"
+)
+
+// adoptedPollInterval is how often adopted processes are checked for exit.
+const adoptedPollInterval = 2 * time.Second
+
+// Reconcile settles executions left active by a previous run of the server.
+// Processes that still run are adopted, so they can be stopped and their end
+// is noticed; the rest are marked as lost.
+func (c *Service) Reconcile() error {
+	active, err := c.Storage.GetActiveExecutedCommands()
+	if err != nil {
+		return err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for _, ec := range active {
+		if ec.Status == entities.StatusQueued {
+			c.Logger.Warn("queued execution is lost", slog.Int("id", ec.Id))
+			c.settle(ec.Id, ec.StartedAt, entities.StatusLost, "server restarted before the execution was started")
+			continue
+		}
+		key, _ := processKey(ec.PID)
+		if ec.ProcessKey != "" && key == ec.ProcessKey {
+			c.Logger.Info("adopting running execution", slog.Int("id", ec.Id), slog.Int("pid", ec.PID))
+			c.adopt(ec)
+			continue
+		}
+		c.Logger.Warn("execution is lost", slog.Int("id", ec.Id), slog.Int("pid", ec.PID))
+		c.settle(ec.Id, ec.StartedAt, entities.StatusLost, "process is gone after a server restart, exit status is unknown")
+	}
+	return nil
+}
+
+// adopt tracks a process started by a previous run. It is called with the
+// mutex held.
+func (c *Service) adopt(ec entities.ExecutedCommand) {
+	// FindProcess can't fail on unix, and processKey only matches on Linux.
+	process, _ := os.FindProcess(ec.PID)
+	exe := &execution{
+		commandId:  ec.CommandId,
+		process:    process,
+		processKey: ec.ProcessKey,
+		startedAt:  ec.StartedAt,
+		done:       make(chan struct{}),
+	}
+
+	c.running[ec.Id] = exe
+	c.waiters[ec.Id] = make(chan struct{})
+
+	go func() {
+		ticker := time.NewTicker(adoptedPollInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if key, err := processKey(ec.PID); err != nil || key != ec.ProcessKey {
+				break
+			}
+		}
+		close(exe.done)
+
+		c.mutex.Lock()
+		defer c.mutex.Unlock()
+		delete(c.running, ec.Id)
+		if exe.stopped {
+			c.settle(ec.Id, ec.StartedAt, entities.StatusStopped, "stopped, exit status of an adopted process is unknown")
+		} else {
+			c.settle(ec.Id, ec.StartedAt, entities.StatusLost, "process exited after a server restart, exit status is unknown")
+		}
+		c.dispatch()
+	}()
+}
+
+// settle finishes an execution that nobody can wait for, leaving a system
+// log line about what happened to it. It is called with the mutex held.
+func (c *Service) settle(id int, startedAt time.Time, status entities.ExecutionStatus, message string) {
+	seq, err := c.Storage.GetLastLogSeq(id)
+	if err != nil {
+		c.Logger.Error("failed to get last log seq", slog.Int("id", id), sl.Err(err))
+	}
+	now := time.Now()
+	_, err = c.Storage.SaveLog(entities.Log{
+		ExecutedCommandId: id,
+		Seq:               seq + 1,
+		Stream:            entities.StreamSystem,
+		Level:             outcomeLevel(status),
+		Message:           message,
+		Date:              now,
+	})
+	if err != nil {
+		c.Logger.Error("failed to save log", slog.Int("id", id), sl.Err(err))
+	}
+
+	elapsed := now.Sub(startedAt).Milliseconds()
+	err = c.Storage.FinishExecutedCommand(entities.ExecutedCommand{
+		Id:         id,
+		Status:     status,
+		FinishedAt: &now,
+		DurationMs: &elapsed,
+	})
+	if err != nil {
+		c.Logger.Error("failed to save execution result", slog.Int("id", id), sl.Err(err))
+	}
+	c.logs.close(id)
+	c.release(id)
+}
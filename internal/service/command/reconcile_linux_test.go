@@ -0,0 +1,46 @@
+package command
+
+import (
+	"context"
+	"os/exec"
+	"syscall"
+	"testex/internal/config"
+	"testex/internal/entities"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestService_ReconcileAdopt(t *testing.T) {
+	s, store := newTestService(t, config.Config{}, entities.Command{Alias: "sleep", Script: "sleep 30"})
+	// The process stands for one started by a previous run of the server.
+	cmd := exec.Command("sleep", "30")
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	assert.NoError(t, cmd.Start())
+	exited := make(chan struct{})
+	go func() {
+		_ = cmd.Wait()
+		close(exited)
+	}()
+	defer func() {
+		_ = cmd.Process.Kill()
+		<-exited
+	}()
+	key, err := processKey(cmd.Process.Pid)
+	assert.NoError(t, err)
+	store.add(entities.ExecutedCommand{CommandId: 1, Status: entities.StatusRunning, IsActive: true,
+		StartedAt: time.Now(), PID: cmd.Process.Pid, ProcessKey: key})
+
+	assert.NoError(t, s.Reconcile())
+	active, err := s.GetActiveExecutedCommand(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, active, 1)
+	assert.Equal(t, entities.StatusRunning, active[0].Status, "a live process is adopted")
+
+	assert.NoError(t, s.StopCommand(context.Background(), entities.StopCommandDto{Id: 1}))
+	<-exited
+	ec := wait(t, s, 1)
+	assert.Equal(t, entities.StatusStopped, ec.Status)
+	assert.Equal(t, []string{"stopped, exit status of an adopted process is unknown"}, store.messages(1))
+}
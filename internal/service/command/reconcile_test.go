@@ -0,0 +1,41 @@
+package command
+
+import (
+	"context"
+	"os"
+	"testex/internal/config"
+	"testex/internal/entities"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestService_ReconcileLost(t *testing.T) {
+	s, store := newTestService(t, config.Config{}, entities.Command{Alias: "sleep", Script: "sleep 30"})
+	startedAt := time.Now().Add(-time.Minute)
+	store.add(entities.ExecutedCommand{CommandId: 1, Status: entities.StatusQueued, StartedAt: startedAt,
+		IsActive: true})
+	// The pid is alive, but it is this test and not the process that was started.
+	store.add(entities.ExecutedCommand{CommandId: 1, Status: entities.StatusRunning, StartedAt: startedAt,
+		IsActive: true, PID: os.Getpid(), ProcessKey: "other-boot/1"})
+	store.add(entities.ExecutedCommand{CommandId: 1, Status: entities.StatusRunning, StartedAt: startedAt,
+		IsActive: true, PID: os.Getpid()})
+
+	assert.NoError(t, s.Reconcile())
+	messages := []string{
+		"server restarted before the execution was started",
+		"process is gone after a server restart, exit status is unknown",
+		"process is gone after a server restart, exit status is unknown",
+	}
+	for i, message := range messages {
+		ec, err := store.GetExecutedCommandById(i + 1)
+		assert.NoError(t, err)
+		assert.Equal(t, entities.StatusLost, ec.Status)
+		assert.False(t, ec.IsActive)
+		assert.Equal(t, []string{message}, store.messages(ec.Id))
+	}
+	active, err := s.GetActiveExecutedCommand(context.Background())
+	assert.NoError(t, err)
+	assert.Empty(t, active)
+}
@@ -0,0 +1,143 @@
+package command
+
+import (
+	"bytes"
+	"cmp"
+	"io"
+	"slices"
+	"strings"
+)
+
+const (
+	// redacted replaces values of secrets in output.
+	redacted = "***"
+	// minRedactedLine is the length below which single lines of multi-line
+	// secrets aren't redacted on their own, they are too likely to show up
+	// in unrelated output.
+	minRedactedLine = 8
+	// redactChunk is the size of reads from the redacted stream.
+	redactChunk = 32 * 1024
+)
+
+// redactor hides values of secrets in output. Patterns are tried longest
+// first, so a value containing another one is hidden whole.
+type redactor struct {
+	patterns [][]byte
+	// first marks bytes patterns start with, other bytes are passed as is.
+	first [256]bool
+}
+
+// newRedactor redacts the values, and the lines of multi-line values that
+// are long enough and not PEM armor, since a script may print a single line
+// of a key file. It returns nil without values.
+func newRedactor(values map[string]string) *redactor {
+	seen := make(map[string]bool)
+	var patterns []string
+	add := func(s string) {
+		if s != "" && !seen[s] {
+			seen[s] = true
+			patterns = append(patterns, s)
+		}
+	}
+	for _, value := range values {
+		add(value)
+		if !strings.Contains(value, "\n") {
+			continue
+		}
+		for _, line := range strings.Split(value, "\n") {
+			line = strings.TrimSpace(line)
+			if len(line) >= minRedactedLine && !strings.HasPrefix(line, "-----") {
+				add(line)
+			}
+		}
+	}
+	if len(patterns) == 0 {
+		return nil
+	}
+	slices.SortFunc(patterns, func(a, b string) int {
+		return cmp.Or(cmp.Compare(len(b), len(a)), strings.Compare(a, b))
+	})
+	r := &redactor{}
+	for _, pattern := range patterns {
+		r.patterns = append(r.patterns, []byte(pattern))
+		r.first[pattern[0]] = true
+	}
+	return r
+}
+
+// Reader returns a reader of src with the secrets replaced, also when a
+// value is split between reads.
+func (r *redactor) Reader(src io.Reader) io.Reader {
+	return &redactingReader{redactor: r, src: src, chunk: make([]byte, redactChunk)}
+}
+
+// match returns the length of the pattern found at the start of b. It asks
+// for more input when b may be the beginning of a pattern, unless b is the
+// end of the stream.
+func (r *redactor) match(b []byte, final bool) (n int, more bool) {
+	for _, pattern := range r.patterns {
+		if len(b) >= len(pattern) {
+			if bytes.HasPrefix(b, pattern) {
+				return len(pattern), false
+			}
+		} else if !final && bytes.HasPrefix(pattern, b) {
+			return 0, true
+		}
+	}
+	return 0, false
+}
+
+type redactingReader struct {
+	*redactor
+	src   io.Reader
+	chunk []byte
+	// in holds input that may start a secret, out redacted output not read
+	// yet and err the error of src, returned once both are drained.
+	in  []byte
+	out []byte
+	err error
+}
+
+func (r *redactingReader) Read(p []byte) (int, error) {
+	for len(r.out) == 0 {
+		if r.err != nil {
+			if len(r.in) == 0 {
+				return 0, r.err
+			}
+			r.redact(true)
+			continue
+		}
+		n, err := r.src.Read(r.chunk)
+		r.in = append(r.in, r.chunk[:n]...)
+		r.err = err
+		r.redact(err != nil)
+	}
+	n := copy(p, r.out)
+	r.out = r.out[n:]
+	return n, nil
+}
+
+// redact moves the input that can be decided on to the output.
+func (r *redactingReader) redact(final bool) {
+	start, i := 0, 0
+	for i < len(r.in) {
+		if !r.first[r.in[i]] {
+			i++
+			continue
+		}
+		n, more := r.match(r.in[i:], final)
+		if more {
+			break
+		}
+		if n == 0 {
+			i++
+			continue
+		}
+		r.out = append(r.out, r.in[start:i]...)
+		r.out = append(r.out, redacted...)
+		i += n
+		start = i
+	}
+	r.out = append(r.out, r.in[start:i]...)
+	r.in = append(r.in[:0], r.in[i:]...)
+}
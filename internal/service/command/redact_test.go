@@ -0,0 +1,80 @@
+package command
+
+import (
+	"io"
+	"strings"
+	"testex/internal/config"
+	"testex/internal/entities"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeSecrets resolves secrets from a map.
+type fakeSecrets map[string]string
+
+func (f fakeSecrets) Resolve(names []string) (map[string]string, error) {
+	values := make(map[string]string, len(names))
+	for _, name := range names {
+		values[name] = f[name]
+	}
+	return values, nil
+}
+
+func redactAll(r *redactor, s string) string {
+	data, _ := io.ReadAll(r.Reader(iotest.OneByteReader(strings.NewReader(s))))
+	return string(data)
+}
+
+func TestNewRedactor(t *testing.T) {
+	assert.Nil(t, newRedactor(map[string]string{"empty": ""}))
+
+	r := newRedactor(map[string]string{
+		"token":  "abc",
+		"longer": "abcdef",
+		"key":    "-----BEGIN KEY-----\r\nMIIEvQIBADANBgkq\r\n}\r\n-----END KEY-----",
+	})
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{name: "LongestFirst", input: "token=abcdef and abc", expected: "token=*** and ***"},
+		{name: "Partial", input: "ab\nabcde", expected: "ab\n***de"},
+		{name: "WholeKey", input: "key: -----BEGIN KEY-----\r\nMIIEvQIBADANBgkq\r\n}\r\n-----END KEY-----\n",
+			expected: "key: ***\n"},
+		{name: "KeyLine", input: "MIIEvQIBADANBgkq\n", expected: "***\n"},
+		{name: "Armor", input: "-----BEGIN KEY-----\n}\n", expected: "-----BEGIN KEY-----\n}\n"},
+		{name: "PrefixAtEnd", input: "abcde", expected: "***de"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, redactAll(r, test.input))
+		})
+	}
+}
+
+func TestService_RedactOutput(t *testing.T) {
+	refs := entities.SecretRefs{{Name: "token", Env: "TOKEN"}}
+	script := `printf 'token=%s\n' "$TOKEN"; printf '%s%s\n' "$(head -c 100 /dev/zero | tr '\0' x)" "$TOKEN"`
+	s, store := newTestService(t, config.Config{},
+		entities.Command{Alias: "raw", Script: script, OutputMode: entities.OutputRaw, Secrets: refs},
+		entities.Command{Alias: "both", Script: script, OutputMode: entities.OutputBoth, Secrets: refs,
+			MaxLineLength: 104},
+	)
+	s.Secrets = fakeSecrets{"token": "ghp_s3cret"}
+
+	for _, alias := range []string{"raw", "both"} {
+		ec := execute(t, s, alias)
+		assert.Equal(t, entities.StatusSucceeded, ec.Status)
+		output, err := store.GetExecutionOutput(ec.Id, entities.StreamStdout)
+		assert.NoError(t, err)
+		blob := string(store.blobs[output.BlobKey])
+		assert.Equal(t, "token=***\n"+strings.Repeat("x", 100)+"***\n", blob)
+		for _, message := range store.messages(ec.Id) {
+			assert.NotContains(t, message, "ghp_")
+		}
+	}
+}
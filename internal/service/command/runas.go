@@ -0,0 +1,59 @@
+package command
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"testex/internal/config"
+	"testex/internal/entities"
+)
+
+// identity is the OS user an execution runs as when it isn't the user of
+// testex.
+type identity struct {
+	uid    int
+	gid    int
+	groups []uint32
+	name   string
+	home   string
+}
+
+// validateRunAs checks that a command asks for a user of the allowlist.
+func validateRunAs(runAs string, cfg config.RunAs) error {
+	if runAs != "" && !slices.Contains(cfg.Allowed, runAs) {
+		return fmt.Errorf("%w: run_as %q is not allowed", entities.ErrInvalidParams, runAs)
+	}
+	return nil
+}
+
+// identityOf resolves the user a command runs as, nil for the user of testex.
+func (c *Service) identityOf(command entities.Command) (*identity, error) {
+	runAs := command.RunAs
+	if runAs == "" {
+		runAs = c.Config.RunAs.Default
+	}
+	if runAs == "" {
+		return nil, nil
+	}
+	return lookupIdentity(runAs)
+}
+
+// effectiveUid returns the uid the process of an execution runs with.
+func effectiveUid(who *identity) *int {
+	if who != nil {
+		return &who.uid
+	}
+	if uid := os.Geteuid(); uid >= 0 {
+		return &uid
+	}
+	return nil
+}
+
+// identityEnv points the variables describing the user at the one the script
+// runs as, instead of the user of testex.
+func identityEnv(who *identity) []string {
+	if who == nil {
+		return nil
+	}
+	return []string{"HOME=" + who.home, "USER=" + who.name, "LOGNAME=" + who.name}
+}
@@ -0,0 +1,65 @@
+package command
+
+import (
+	"errors"
+	"runtime"
+	"testex/internal/config"
+	"testex/internal/entities"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateRunAs(t *testing.T) {
+	cfg := config.RunAs{Allowed: []string{"runner", "runner:docker"}}
+	tests := []struct {
+		name  string
+		runAs string
+		valid bool
+	}{
+		{name: "Default", valid: true},
+		{name: "Allowed", runAs: "runner", valid: true},
+		{name: "AllowedWithGroup", runAs: "runner:docker", valid: true},
+		{name: "OtherGroup", runAs: "runner:root"},
+		{name: "NotAllowed", runAs: "root"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := validateRunAs(test.runAs, cfg)
+			if test.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.True(t, errors.Is(err, entities.ErrInvalidParams), err)
+			}
+		})
+	}
+}
+
+func TestIdentityOf(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("users can't be switched on Windows")
+	}
+	c := &Service{Config: config.Config{RunAs: config.RunAs{Default: "root"}}}
+
+	who, err := c.identityOf(entities.Command{})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, who.uid)
+	assert.Equal(t, 0, who.gid)
+	assert.Equal(t, "root", who.name)
+	assert.Equal(t, []string{"HOME=" + who.home, "USER=root", "LOGNAME=root"}, identityEnv(who))
+
+	who, err = c.identityOf(entities.Command{RunAs: "0:0"})
+	assert.NoError(t, err)
+	assert.Equal(t, "root", who.name)
+
+	_, err = c.identityOf(entities.Command{RunAs: "no-such-user"})
+	assert.Error(t, err)
+	_, err = c.identityOf(entities.Command{RunAs: "root:no-such-group"})
+	assert.Error(t, err)
+
+	who, err = (&Service{}).identityOf(entities.Command{})
+	assert.NoError(t, err)
+	assert.Nil(t, who)
+	assert.Nil(t, identityEnv(who))
+}
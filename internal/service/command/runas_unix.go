@@ -0,0 +1,68 @@
+//go:build !windows
+
+package command
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// lookupIdentity resolves a run_as value of the form user or user:group,
+// given by names or ids. The group defaults to the primary group of the user,
+// supplementary groups are those of the user.
+func lookupIdentity(runAs string) (*identity, error) {
+	name, group, hasGroup := strings.Cut(runAs, ":")
+	u, err := user.Lookup(name)
+	if err != nil {
+		var idErr error
+		if u, idErr = user.LookupId(name); idErr != nil {
+			return nil, fmt.Errorf("unknown user %q: %w", name, err)
+		}
+	}
+	who := &identity{name: u.Username, home: u.HomeDir}
+	if who.uid, err = strconv.Atoi(u.Uid); err != nil {
+		return nil, fmt.Errorf("bad uid %q of user %q", u.Uid, name)
+	}
+	gid := u.Gid
+	if hasGroup {
+		g, err := user.LookupGroup(group)
+		if err != nil {
+			var idErr error
+			if g, idErr = user.LookupGroupId(group); idErr != nil {
+				return nil, fmt.Errorf("unknown group %q: %w", group, err)
+			}
+		}
+		gid = g.Gid
+	}
+	if who.gid, err = strconv.Atoi(gid); err != nil {
+		return nil, fmt.Errorf("bad gid %q of user %q", gid, name)
+	}
+	groupIds, err := u.GroupIds()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list groups of user %q: %w", name, err)
+	}
+	for _, id := range groupIds {
+		if g, err := strconv.ParseUint(id, 10, 32); err == nil {
+			who.groups = append(who.groups, uint32(g))
+		}
+	}
+	return who, nil
+}
+
+// setCredential makes cmd run as the user. It must be called after
+// setProcessGroup.
+func setCredential(cmd *exec.Cmd, who *identity) error {
+	cmd.SysProcAttr.Credential = &syscall.Credential{Uid: uint32(who.uid), Gid: uint32(who.gid), Groups: who.groups}
+	return nil
+}
+
+// handOver gives a directory or file testex created for an execution to the
+// user the script runs as.
+func handOver(path string, who *identity) error {
+	return os.Chown(path, who.uid, who.gid)
+}
@@ -0,0 +1,22 @@
+//go:build windows
+
+package command
+
+import (
+	"errors"
+	"os/exec"
+)
+
+var errRunAsUnsupported = errors.New("running scripts as another user is not supported on Windows")
+
+func lookupIdentity(string) (*identity, error) {
+	return nil, errRunAsUnsupported
+}
+
+func setCredential(*exec.Cmd, *identity) error {
+	return errRunAsUnsupported
+}
+
+func handOver(string, *identity) error {
+	return errRunAsUnsupported
+}
@@ -0,0 +1,114 @@
+package command
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testex/internal/entities"
+	sl "testex/pkg/slog"
+)
+
+// SecretResolver resolves values of secrets referenced by commands.
+type SecretResolver interface {
+	Resolve(names []string) (map[string]string, error)
+}
+
+// validateSecrets checks secret references of a command. Secrets themselves
+// may be created later, they are resolved when the command is run.
+func validateSecrets(refs entities.SecretRefs) error {
+	seen := make(map[string]bool, len(refs))
+	for _, ref := range refs {
+		if ref.Name == "" {
+			return fmt.Errorf("%w: secret name is required", entities.ErrInvalidParams)
+		}
+		if !paramNameRegexp.MatchString(ref.Env) {
+			return fmt.Errorf("%w: bad env name %q of secret %q", entities.ErrInvalidParams, ref.Env, ref.Name)
+		}
+		if strings.HasPrefix(strings.ToUpper(ref.Env), reservedEnvPrefix) {
+			return fmt.Errorf("%w: env name %q uses the reserved prefix %s", entities.ErrInvalidParams, ref.Env,
+				reservedEnvPrefix)
+		}
+		if seen[ref.Env] {
+			return fmt.Errorf("%w: env %q is set by more than one secret", entities.ErrInvalidParams, ref.Env)
+		}
+		seen[ref.Env] = true
+	}
+	return nil
+}
+
+// injectSecrets resolves secrets of a command for an execution. It returns
+// the variables to set and a replacer hiding the values in output. Secrets
+// passed as files are written into a directory of the execution, readable
+// only by testex and the script.
+func (c *Service) injectSecrets(id int, refs entities.SecretRefs, who *identity) ([]string, *redactor, error) {
+	if len(refs) == 0 {
+		return nil, nil, nil
+	}
+	names := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		names = append(names, ref.Name)
+	}
+	values, err := c.Secrets.Resolve(names)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	env := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		value := values[ref.Name]
+		if !ref.File {
+			env = append(env, ref.Env+"="+value)
+			continue
+		}
+		dir, err := c.secretsDir(id)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err = os.MkdirAll(dir, 0o700); err != nil {
+			return nil, nil, err
+		}
+		path := filepath.Join(dir, ref.Env)
+		if err = os.WriteFile(path, []byte(value), 0o600); err != nil {
+			return nil, nil, err
+		}
+		if who != nil {
+			if err = c.handOverSecrets(dir, path, who); err != nil {
+				return nil, nil, err
+			}
+		}
+		env = append(env, ref.Env+"="+path)
+	}
+	return env, newRedactor(values), nil
+}
+
+func (c *Service) secretsDir(id int) (string, error) {
+	dir, err := c.Config.Workdir.SecretsDir()
+	return filepath.Join(dir, strconv.Itoa(id)), err
+}
+
+// handOverSecrets gives a secret file and the directory of the execution to
+// the user the script runs as. Other users can pass through the shared
+// directory, but can't list it.
+func (c *Service) handOverSecrets(dir, path string, who *identity) error {
+	if err := os.Chmod(filepath.Dir(dir), 0o711); err != nil {
+		return err
+	}
+	if err := handOver(dir, who); err != nil {
+		return err
+	}
+	return handOver(path, who)
+}
+
+// removeSecrets removes secret files of an execution, if there are any.
+func (c *Service) removeSecrets(id int) {
+	dir, err := c.secretsDir(id)
+	if err == nil {
+		err = os.RemoveAll(dir)
+	}
+	if err != nil {
+		c.Logger.Error("failed to remove secret files", slog.Int("id", id), sl.Err(err))
+	}
+}
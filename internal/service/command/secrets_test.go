@@ -0,0 +1,36 @@
+package command
+
+import (
+	"errors"
+	"testex/internal/entities"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateSecrets(t *testing.T) {
+	tests := []struct {
+		name  string
+		refs  entities.SecretRefs
+		valid bool
+	}{
+		{name: "Empty", valid: true},
+		{name: "Refs", refs: entities.SecretRefs{{Name: "token", Env: "TOKEN"}, {Name: "key", Env: "KEY", File: true}},
+			valid: true},
+		{name: "NoName", refs: entities.SecretRefs{{Env: "TOKEN"}}},
+		{name: "BadEnv", refs: entities.SecretRefs{{Name: "token", Env: "../x"}}},
+		{name: "Reserved", refs: entities.SecretRefs{{Name: "token", Env: "TESTEX_ALIAS"}}},
+		{name: "SameEnv", refs: entities.SecretRefs{{Name: "a", Env: "TOKEN"}, {Name: "b", Env: "TOKEN"}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := validateSecrets(test.refs)
+			if test.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.True(t, errors.Is(err, entities.ErrInvalidParams), err)
+			}
+		})
+	}
+}
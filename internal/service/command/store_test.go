@@ -0,0 +1,249 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"runtime"
+	"slices"
+	"sync"
+	"testex/internal/config"
+	"testex/internal/entities"
+	"testex/internal/service/audit"
+	"testex/internal/storage"
+	"testex/pkg/slog/slogdiscard"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// memStore keeps commands, executions, logs and blobs in memory, enough for
+// the service to run real processes.
+type memStore struct {
+	storage.CommandRepository
+	mu         sync.Mutex
+	commands   []entities.Command
+	executions []entities.ExecutedCommand
+	logs       []entities.Log
+	outputs    []entities.ExecutionOutput
+	blobs      map[string][]byte
+}
+
+func newMemStore(commands ...entities.Command) *memStore {
+	for i := range commands {
+		commands[i].Id = i + 1
+	}
+	return &memStore{commands: commands, blobs: make(map[string][]byte)}
+}
+
+func (m *memStore) GetCommand(alias string) (entities.Command, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, command := range m.commands {
+		if command.Alias == alias {
+			return command, nil
+		}
+	}
+	return entities.Command{}, entities.ErrNotFound
+}
+
+func (m *memStore) SaveExecutedCommand(ec entities.ExecutedCommand) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ec.Id = len(m.executions) + 1
+	ec.IsActive = true
+	m.executions = append(m.executions, ec)
+	return ec.Id, nil
+}
+
+// add stores an execution as it is, as if a previous run had left it.
+func (m *memStore) add(ec entities.ExecutedCommand) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ec.Id = len(m.executions) + 1
+	m.executions = append(m.executions, ec)
+}
+
+func (m *memStore) StartExecutedCommand(ec entities.ExecutedCommand) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e := &m.executions[ec.Id-1]
+	e.PID, e.Status, e.StartedAt, e.ProcessKey, e.Workdir, e.Uid = ec.PID, ec.Status, ec.StartedAt, ec.ProcessKey,
+		ec.Workdir, ec.Uid
+	return nil
+}
+
+func (m *memStore) FinishExecutedCommand(ec entities.ExecutedCommand) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e := &m.executions[ec.Id-1]
+	e.IsActive = false
+	e.Status, e.FinishedAt, e.ExitCode, e.Signal, e.DurationMs = ec.Status, ec.FinishedAt, ec.ExitCode, ec.Signal,
+		ec.DurationMs
+	e.DroppedLines, e.Truncated = ec.DroppedLines, ec.Truncated
+	return nil
+}
+
+func (m *memStore) GetExecutedCommandById(id int) (entities.ExecutedCommand, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if id < 1 || id > len(m.executions) {
+		return entities.ExecutedCommand{}, entities.ErrNotFound
+	}
+	return m.executions[id-1], nil
+}
+
+func (m *memStore) GetActiveExecutedCommands() ([]entities.ExecutedCommand, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var active []entities.ExecutedCommand
+	for _, ec := range m.executions {
+		if ec.IsActive {
+			active = append(active, ec)
+		}
+	}
+	return active, nil
+}
+
+func (m *memStore) SaveLog(log entities.Log) (int, error) {
+	ids, err := m.SaveLogs([]entities.Log{log})
+	return ids[0], err
+}
+
+func (m *memStore) SaveLogs(logs []entities.Log) ([]int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ids := make([]int, 0, len(logs))
+	for _, log := range logs {
+		log.Id = len(m.logs) + 1
+		m.logs = append(m.logs, log)
+		ids = append(ids, log.Id)
+	}
+	return ids, nil
+}
+
+func (m *memStore) GetLastLogSeq(id int) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var seq int64
+	for _, log := range m.logs {
+		if log.ExecutedCommandId == id {
+			seq = max(seq, log.Seq)
+		}
+	}
+	return seq, nil
+}
+
+func (m *memStore) GetLogsByExecutedCommand(id int, filter entities.LogFilter) ([]entities.Log, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var logs []entities.Log
+	for _, log := range m.logs {
+		if log.ExecutedCommandId == id && log.Seq > filter.AfterSeq {
+			logs = append(logs, log)
+		}
+	}
+	slices.SortFunc(logs, func(a, b entities.Log) int { return int(a.Seq - b.Seq) })
+	return logs, nil
+}
+
+// messages returns the messages of an execution in order.
+func (m *memStore) messages(id int) []string {
+	logs, _ := m.GetLogsByExecutedCommand(id, entities.LogFilter{})
+	var messages []string
+	for _, log := range logs {
+		messages = append(messages, log.Message)
+	}
+	return messages
+}
+
+func (m *memStore) SaveExecutionOutput(output entities.ExecutionOutput) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.outputs = append(m.outputs, output)
+	return nil
+}
+
+func (m *memStore) GetExecutionOutput(id int, stream entities.LogStream) (entities.ExecutionOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, output := range m.outputs {
+		if output.ExecutedCommandId == id && output.Stream == stream {
+			return output, nil
+		}
+	}
+	return entities.ExecutionOutput{}, entities.ErrNotFound
+}
+
+func (m *memStore) PutBlob(r io.Reader) (string, int64, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", 0, err
+	}
+	sum := sha256.Sum256(data)
+	key := hex.EncodeToString(sum[:])
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.blobs[key] = data
+	return key, int64(len(data)), nil
+}
+
+func (m *memStore) OpenBlob(key string) (io.ReadSeekCloser, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, ok := m.blobs[key]
+	if !ok {
+		return nil, entities.ErrNotFound
+	}
+	return nopCloser{bytes.NewReader(data)}, nil
+}
+
+func (m *memStore) DeleteBlob(key string, _ time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.blobs, key)
+	return true, nil
+}
+
+type nopCloser struct {
+	io.ReadSeeker
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
+
+// newTestService returns a service running scripts of the commands with
+// bash, which is why these tests don't run on Windows.
+func newTestService(t *testing.T, cfg config.Config, commands ...entities.Command) (*Service, *memStore) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("scripts are run with bash")
+	}
+	cfg.Workdir.Root = t.TempDir()
+	cfg.Logs.FlushInterval = 10 * time.Millisecond
+	store := newMemStore(commands...)
+	s := NewService(&storage.Storage{CommandRepository: store, BlobStore: store}, slogdiscard.NewDiscardLogger(), cfg,
+		nil, audit.Discard)
+	return s, store
+}
+
+// execute runs a command and waits for the execution to finish.
+func execute(t *testing.T, s *Service, alias string) entities.ExecutedCommand {
+	t.Helper()
+	id, err := s.Execute(context.Background(), entities.ExecuteCommandDto{Alias: alias})
+	assert.NoError(t, err)
+	return wait(t, s, id)
+}
+
+func wait(t *testing.T, s *Service, id int) entities.ExecutedCommand {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	ec, err := s.Wait(ctx, id)
+	assert.NoError(t, err)
+	return ec
+}
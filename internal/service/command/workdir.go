@@ -0,0 +1,93 @@
+package command
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testex/internal/config"
+	"testex/internal/entities"
+	sl "testex/pkg/slog"
+)
+
+// validateWorkdir checks the working directory settings of a command, filling
+// in the default mode.
+func validateWorkdir(dto *entities.CommandDto) error {
+	switch dto.WorkdirMode {
+	case "":
+		dto.WorkdirMode = entities.WorkdirTemp
+	case entities.WorkdirFixed, entities.WorkdirTemp, entities.WorkdirWorkspace:
+	default:
+		return fmt.Errorf("%w: unknown workdir mode %q", entities.ErrInvalidParams, dto.WorkdirMode)
+	}
+	if dto.WorkdirMode == entities.WorkdirFixed && dto.Workdir == "" {
+		return fmt.Errorf("%w: workdir is required in the fixed mode", entities.ErrInvalidParams)
+	}
+	if dto.WorkdirMode != entities.WorkdirFixed && dto.Workdir != "" {
+		return fmt.Errorf("%w: workdir is only used in the fixed mode", entities.ErrInvalidParams)
+	}
+	return nil
+}
+
+// workdirOf returns the absolute working directory of an execution of the
+// command. Temporary directories are named by the execution id, workspaces by
+// the command id, so they are kept when the command is renamed.
+func workdirOf(cfg config.Workdir, command entities.Command, id int) (string, error) {
+	root, err := cfg.RootDir()
+	if err != nil {
+		return "", err
+	}
+	switch command.WorkdirMode {
+	case entities.WorkdirFixed:
+		if filepath.IsAbs(command.Workdir) {
+			return filepath.Clean(command.Workdir), nil
+		}
+		return filepath.Join(root, command.Workdir), nil
+	case entities.WorkdirWorkspace:
+		return filepath.Join(root, "workspaces", strconv.Itoa(command.Id)), nil
+	default:
+		temp, err := cfg.TempDir()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(temp, strconv.Itoa(id)), nil
+	}
+}
+
+// prepareWorkdir creates the working directory of an execution. A temporary
+// directory left from an earlier execution with the same id is emptied.
+// Temporary directories and workspaces belong to the user the script runs
+// as, fixed directories are left as they are.
+func (c *Service) prepareWorkdir(id int, command entities.Command, who *identity) (string, error) {
+	dir, err := workdirOf(c.Config.Workdir, command, id)
+	if err != nil {
+		return "", err
+	}
+	if command.WorkdirMode == entities.WorkdirTemp {
+		if err = os.RemoveAll(dir); err != nil {
+			return "", err
+		}
+	}
+	if err = os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	if who != nil && command.WorkdirMode != entities.WorkdirFixed {
+		if err = handOver(dir, who); err != nil {
+			return "", err
+		}
+	}
+	return dir, nil
+}
+
+// cleanWorkdir removes the temporary directory of a finished execution unless
+// it is kept for a while, then the retention janitor removes it.
+func (c *Service) cleanWorkdir(id int, exe *execution) {
+	if exe.workdirMode != entities.WorkdirTemp || c.Config.Workdir.TempMaxAge > 0 {
+		return
+	}
+	if err := os.RemoveAll(exe.cmd.Dir); err != nil {
+		c.Logger.Warn("failed to remove working directory", slog.Int("id", id), slog.String("dir", exe.cmd.Dir),
+			sl.Err(err))
+	}
+}
@@ -0,0 +1,70 @@
+package command
+
+import (
+	"errors"
+	"path/filepath"
+	"testex/internal/config"
+	"testex/internal/entities"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateWorkdir(t *testing.T) {
+	tests := []struct {
+		name     string
+		dto      entities.CommandDto
+		expected entities.WorkdirMode
+		valid    bool
+	}{
+		{name: "Default", expected: entities.WorkdirTemp, valid: true},
+		{name: "Workspace", dto: entities.CommandDto{WorkdirMode: entities.WorkdirWorkspace},
+			expected: entities.WorkdirWorkspace, valid: true},
+		{name: "Fixed", dto: entities.CommandDto{WorkdirMode: entities.WorkdirFixed, Workdir: "/srv/app"},
+			expected: entities.WorkdirFixed, valid: true},
+		{name: "FixedWithoutDir", dto: entities.CommandDto{WorkdirMode: entities.WorkdirFixed}},
+		{name: "DirWithoutFixed", dto: entities.CommandDto{Workdir: "/srv/app"}},
+		{name: "Unknown", dto: entities.CommandDto{WorkdirMode: "shared"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := validateWorkdir(&test.dto)
+			if test.valid {
+				assert.NoError(t, err)
+				assert.Equal(t, test.expected, test.dto.WorkdirMode)
+			} else {
+				assert.True(t, errors.Is(err, entities.ErrInvalidParams), err)
+			}
+		})
+	}
+}
+
+func TestWorkdirOf(t *testing.T) {
+	root := t.TempDir()
+	cfg := config.Workdir{Root: root}
+	absolute := filepath.Join(root, "srv", "app")
+
+	tests := []struct {
+		name     string
+		command  entities.Command
+		expected string
+	}{
+		{name: "Temp", command: entities.Command{Id: 3, WorkdirMode: entities.WorkdirTemp},
+			expected: filepath.Join(root, "tmp", "42")},
+		{name: "Workspace", command: entities.Command{Id: 3, WorkdirMode: entities.WorkdirWorkspace},
+			expected: filepath.Join(root, "workspaces", "3")},
+		{name: "FixedAbsolute", command: entities.Command{WorkdirMode: entities.WorkdirFixed, Workdir: absolute},
+			expected: absolute},
+		{name: "FixedRelative", command: entities.Command{WorkdirMode: entities.WorkdirFixed, Workdir: "shared"},
+			expected: filepath.Join(root, "shared")},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir, err := workdirOf(cfg, test.command, 42)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, dir)
+		})
+	}
+}
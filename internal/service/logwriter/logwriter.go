@@ -0,0 +1,152 @@
+package logwriter
+
+import (
+	"log/slog"
+	"sync"
+	"testex/internal/config"
+	"testex/internal/entities"
+	sl "testex/pkg/slog"
+	"time"
+)
+
+const (
+	defaultBatchSize     = 500
+	defaultFlushInterval = 200 * time.Millisecond
+	defaultBufferSize    = 10000
+)
+
+// Sink stores a batch of log lines and returns their ids in the same order.
+type Sink interface {
+	SaveLogs(logs []entities.Log) ([]int, error)
+}
+
+// Writer batches log lines of executions before they are stored, so that a
+// chatty script costs one round-trip per batch instead of one per line.
+type Writer struct {
+	sink   Sink
+	logger *slog.Logger
+	cfg    config.Logs
+}
+
+func New(sink Sink, logger *slog.Logger, cfg config.Logs) *Writer {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = defaultBatchSize
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = defaultFlushInterval
+	}
+	if cfg.BufferSize <= 0 {
+		cfg.BufferSize = defaultBufferSize
+	}
+	return &Writer{sink: sink, logger: logger, cfg: cfg}
+}
+
+// Buffer collects the lines of a single execution. Lines are flushed when a
+// batch fills up or the flush interval passes, whichever comes first.
+type Buffer struct {
+	w  *Writer
+	id int
+	// onFlush receives every stored batch with ids filled in.
+	onFlush func([]entities.Log)
+
+	mu      sync.Mutex
+	pending []entities.Log
+	dropped int
+	closed  bool
+
+	full    chan struct{}
+	closing chan struct{}
+	done    chan struct{}
+}
+
+// Open starts buffering lines of an execution. onFlush may be nil.
+func (w *Writer) Open(executedCommandId int, onFlush func([]entities.Log)) *Buffer {
+	b := &Buffer{
+		w:       w,
+		id:      executedCommandId,
+		onFlush: onFlush,
+		full:    make(chan struct{}, 1),
+		closing: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go b.run()
+	return b
+}
+
+// Write queues a line without blocking. When the buffer is full because
+// the storage can't keep up, the line is dropped and counted.
+func (b *Buffer) Write(log entities.Log) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed || len(b.pending) >= b.w.cfg.BufferSize {
+		b.dropped++
+		return false
+	}
+	b.pending = append(b.pending, log)
+	if len(b.pending) >= b.w.cfg.BatchSize {
+		select {
+		case b.full <- struct{}{}:
+		default:
+		}
+	}
+	return true
+}
+
+// Close flushes the remaining lines and returns how many lines were dropped.
+func (b *Buffer) Close() int {
+	b.mu.Lock()
+	if !b.closed {
+		b.closed = true
+		close(b.closing)
+	}
+	b.mu.Unlock()
+
+	<-b.done
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.dropped
+}
+
+func (b *Buffer) run() {
+	defer close(b.done)
+	ticker := time.NewTicker(b.w.cfg.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			b.flush()
+		case <-b.full:
+			b.flush()
+		case <-b.closing:
+			b.flush()
+			return
+		}
+	}
+}
+
+func (b *Buffer) flush() {
+	b.mu.Lock()
+	pending := b.pending
+	b.pending = nil
+	b.mu.Unlock()
+
+	for len(pending) > 0 {
+		batch := pending[:min(len(pending), b.w.cfg.BatchSize)]
+		pending = pending[len(batch):]
+
+		ids, err := b.w.sink.SaveLogs(batch)
+		if err != nil {
+			b.w.logger.Error("failed to save logs", slog.Int("id", b.id), slog.Int("lines", len(batch)), sl.Err(err))
+			b.mu.Lock()
+			b.dropped += len(batch)
+			b.mu.Unlock()
+			continue
+		}
+		for i := range batch {
+			batch[i].Id = ids[i]
+		}
+		if b.onFlush != nil {
+			b.onFlush(batch)
+		}
+	}
+}
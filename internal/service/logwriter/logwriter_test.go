@@ -0,0 +1,120 @@
+package logwriter
+
+import (
+	"errors"
+	"sync"
+	"testex/internal/config"
+	"testex/internal/entities"
+	"testex/pkg/slog/slogdiscard"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeSink stores logs in memory.
+type fakeSink struct {
+	mu         sync.Mutex
+	fail       bool
+	batches    int
+	logs       []entities.Log
+	blockUntil chan struct{}
+}
+
+func (s *fakeSink) SaveLogs(logs []entities.Log) ([]int, error) {
+	if s.blockUntil != nil {
+		<-s.blockUntil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fail {
+		return nil, errors.New("db is down")
+	}
+	s.batches++
+	ids := make([]int, len(logs))
+	for i := range logs {
+		s.logs = append(s.logs, logs[i])
+		ids[i] = len(s.logs)
+	}
+	return ids, nil
+}
+
+func TestBuffer(t *testing.T) {
+	tests := []struct {
+		name            string
+		cfg             config.Logs
+		sink            *fakeSink
+		lines           int
+		expectedStored  int
+		expectedDropped int
+		expectedBatches int
+	}{
+		{
+			name:            "FlushOnBatchSizeAndClose",
+			cfg:             config.Logs{BatchSize: 10, FlushInterval: time.Hour, BufferSize: 100},
+			sink:            &fakeSink{},
+			lines:           25,
+			expectedStored:  25,
+			expectedBatches: 3,
+		},
+		{
+			name:            "DropWhenBufferIsFull",
+			cfg:             config.Logs{BatchSize: 1000, FlushInterval: time.Hour, BufferSize: 10},
+			sink:            &fakeSink{},
+			lines:           15,
+			expectedStored:  10,
+			expectedDropped: 5,
+			expectedBatches: 1,
+		},
+		{
+			name:            "CountFailedBatchesAsDropped",
+			cfg:             config.Logs{BatchSize: 5, FlushInterval: time.Hour, BufferSize: 100},
+			sink:            &fakeSink{fail: true},
+			lines:           12,
+			expectedDropped: 12,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := New(test.sink, slogdiscard.NewDiscardLogger(), test.cfg)
+			var flushed []entities.Log
+			b := w.Open(1, func(logs []entities.Log) {
+				flushed = append(flushed, logs...)
+			})
+
+			// Hold the sink until all lines are written, so batching is deterministic.
+			test.sink.blockUntil = make(chan struct{})
+			for i := 1; i <= test.lines; i++ {
+				b.Write(entities.Log{ExecutedCommandId: 1, Seq: int64(i)})
+			}
+			close(test.sink.blockUntil)
+			dropped := b.Close()
+
+			assert.Equal(t, test.expectedDropped, dropped)
+			assert.Len(t, test.sink.logs, test.expectedStored)
+			assert.Len(t, flushed, test.expectedStored)
+			if test.expectedBatches > 0 {
+				assert.Equal(t, test.expectedBatches, test.sink.batches)
+			}
+			for i, log := range flushed {
+				assert.Equal(t, int64(i+1), log.Seq)
+				assert.Equal(t, i+1, log.Id)
+			}
+		})
+	}
+}
+
+func TestBuffer_FlushInterval(t *testing.T) {
+	sink := &fakeSink{}
+	w := New(sink, slogdiscard.NewDiscardLogger(), config.Logs{BatchSize: 100, FlushInterval: 10 * time.Millisecond})
+	b := w.Open(1, nil)
+	defer b.Close()
+
+	b.Write(entities.Log{ExecutedCommandId: 1, Seq: 1})
+	assert.Eventually(t, func() bool {
+		sink.mu.Lock()
+		defer sink.mu.Unlock()
+		return len(sink.logs) == 1
+	}, time.Second, 5*time.Millisecond)
+}
@@ -5,6 +5,8 @@
 package mock_service
 
 import (
+	context "context"
+	io "io"
 	reflect "reflect"
 	entities "testex/internal/entities"
 
@@ -35,105 +37,800 @@ func (m *MockCommand) EXPECT() *MockCommandMockRecorder {
 }
 
 // Create mocks base method.
-func (m *MockCommand) Create(alias, script string) (int, error) {
+func (m *MockCommand) Create(ctx context.Context, dto entities.CommandDto) (int, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Create", alias, script)
+	ret := m.ctrl.Call(m, "Create", ctx, dto)
 	ret0, _ := ret[0].(int)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Create indicates an expected call of Create.
-func (mr *MockCommandMockRecorder) Create(alias, script interface{}) *gomock.Call {
+func (mr *MockCommandMockRecorder) Create(ctx, dto interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockCommand)(nil).Create), alias, script)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockCommand)(nil).Create), ctx, dto)
+}
+
+// Delete mocks base method.
+func (m *MockCommand) Delete(ctx context.Context, alias string, version int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", ctx, alias, version)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockCommandMockRecorder) Delete(ctx, alias, version interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockCommand)(nil).Delete), ctx, alias, version)
 }
 
 // Execute mocks base method.
-func (m *MockCommand) Execute(alias string) (int, error) {
+func (m *MockCommand) Execute(ctx context.Context, dto entities.ExecuteCommandDto) (int, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Execute", alias)
+	ret := m.ctrl.Call(m, "Execute", ctx, dto)
 	ret0, _ := ret[0].(int)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Execute indicates an expected call of Execute.
-func (mr *MockCommandMockRecorder) Execute(alias interface{}) *gomock.Call {
+func (mr *MockCommandMockRecorder) Execute(ctx, dto interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Execute", reflect.TypeOf((*MockCommand)(nil).Execute), ctx, dto)
+}
+
+// FollowLogs mocks base method.
+func (m *MockCommand) FollowLogs(ctx context.Context, executedCommandId int) (<-chan entities.Log, func(), error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FollowLogs", ctx, executedCommandId)
+	ret0, _ := ret[0].(<-chan entities.Log)
+	ret1, _ := ret[1].(func())
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// FollowLogs indicates an expected call of FollowLogs.
+func (mr *MockCommandMockRecorder) FollowLogs(ctx, executedCommandId interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Execute", reflect.TypeOf((*MockCommand)(nil).Execute), alias)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FollowLogs", reflect.TypeOf((*MockCommand)(nil).FollowLogs), ctx, executedCommandId)
 }
 
 // GetActiveExecutedCommand mocks base method.
-func (m *MockCommand) GetActiveExecutedCommand() ([]entities.ExecutedCommand, error) {
+func (m *MockCommand) GetActiveExecutedCommand(ctx context.Context) ([]entities.ExecutedCommand, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetActiveExecutedCommand")
+	ret := m.ctrl.Call(m, "GetActiveExecutedCommand", ctx)
 	ret0, _ := ret[0].([]entities.ExecutedCommand)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetActiveExecutedCommand indicates an expected call of GetActiveExecutedCommand.
-func (mr *MockCommandMockRecorder) GetActiveExecutedCommand() *gomock.Call {
+func (mr *MockCommandMockRecorder) GetActiveExecutedCommand(ctx interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetActiveExecutedCommand", reflect.TypeOf((*MockCommand)(nil).GetActiveExecutedCommand))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetActiveExecutedCommand", reflect.TypeOf((*MockCommand)(nil).GetActiveExecutedCommand), ctx)
 }
 
 // GetAll mocks base method.
-func (m *MockCommand) GetAll() ([]entities.Command, error) {
+func (m *MockCommand) GetAll(ctx context.Context) ([]entities.Command, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetAll")
+	ret := m.ctrl.Call(m, "GetAll", ctx)
 	ret0, _ := ret[0].([]entities.Command)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetAll indicates an expected call of GetAll.
-func (mr *MockCommandMockRecorder) GetAll() *gomock.Call {
+func (mr *MockCommandMockRecorder) GetAll(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*MockCommand)(nil).GetAll), ctx)
+}
+
+// GetArtifacts mocks base method.
+func (m *MockCommand) GetArtifacts(ctx context.Context, id int) ([]entities.Artifact, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetArtifacts", ctx, id)
+	ret0, _ := ret[0].([]entities.Artifact)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetArtifacts indicates an expected call of GetArtifacts.
+func (mr *MockCommandMockRecorder) GetArtifacts(ctx, id interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*MockCommand)(nil).GetAll))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetArtifacts", reflect.TypeOf((*MockCommand)(nil).GetArtifacts), ctx, id)
+}
+
+// GetCommandExecutions mocks base method.
+func (m *MockCommand) GetCommandExecutions(ctx context.Context, alias string, filter entities.ExecutionFilter, cursor string) (entities.ExecutionPage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCommandExecutions", ctx, alias, filter, cursor)
+	ret0, _ := ret[0].(entities.ExecutionPage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCommandExecutions indicates an expected call of GetCommandExecutions.
+func (mr *MockCommandMockRecorder) GetCommandExecutions(ctx, alias, filter, cursor interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCommandExecutions", reflect.TypeOf((*MockCommand)(nil).GetCommandExecutions), ctx, alias, filter, cursor)
+}
+
+// GetExecutedCommand mocks base method.
+func (m *MockCommand) GetExecutedCommand(ctx context.Context, id int) (entities.ExecutedCommand, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetExecutedCommand", ctx, id)
+	ret0, _ := ret[0].(entities.ExecutedCommand)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetExecutedCommand indicates an expected call of GetExecutedCommand.
+func (mr *MockCommandMockRecorder) GetExecutedCommand(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetExecutedCommand", reflect.TypeOf((*MockCommand)(nil).GetExecutedCommand), ctx, id)
+}
+
+// GetExecution mocks base method.
+func (m *MockCommand) GetExecution(ctx context.Context, id int) (entities.ExecutionDetails, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetExecution", ctx, id)
+	ret0, _ := ret[0].(entities.ExecutionDetails)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetExecution indicates an expected call of GetExecution.
+func (mr *MockCommandMockRecorder) GetExecution(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetExecution", reflect.TypeOf((*MockCommand)(nil).GetExecution), ctx, id)
+}
+
+// GetExecutions mocks base method.
+func (m *MockCommand) GetExecutions(ctx context.Context, filter entities.ExecutionFilter, cursor string) (entities.ExecutionPage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetExecutions", ctx, filter, cursor)
+	ret0, _ := ret[0].(entities.ExecutionPage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetExecutions indicates an expected call of GetExecutions.
+func (mr *MockCommandMockRecorder) GetExecutions(ctx, filter, cursor interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetExecutions", reflect.TypeOf((*MockCommand)(nil).GetExecutions), ctx, filter, cursor)
 }
 
 // GetLogs mocks base method.
-func (m *MockCommand) GetLogs(executedCommandId int) ([]entities.Log, error) {
+func (m *MockCommand) GetLogs(ctx context.Context, executedCommandId int, filter entities.LogFilter) ([]entities.Log, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetLogs", executedCommandId)
+	ret := m.ctrl.Call(m, "GetLogs", ctx, executedCommandId, filter)
 	ret0, _ := ret[0].([]entities.Log)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetLogs indicates an expected call of GetLogs.
-func (mr *MockCommandMockRecorder) GetLogs(executedCommandId interface{}) *gomock.Call {
+func (mr *MockCommandMockRecorder) GetLogs(ctx, executedCommandId, filter interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLogs", reflect.TypeOf((*MockCommand)(nil).GetLogs), executedCommandId)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLogs", reflect.TypeOf((*MockCommand)(nil).GetLogs), ctx, executedCommandId, filter)
 }
 
 // GetOne mocks base method.
-func (m *MockCommand) GetOne(alias string) (entities.Command, error) {
+func (m *MockCommand) GetOne(ctx context.Context, alias string) (entities.Command, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetOne", alias)
+	ret := m.ctrl.Call(m, "GetOne", ctx, alias)
 	ret0, _ := ret[0].(entities.Command)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetOne indicates an expected call of GetOne.
-func (mr *MockCommandMockRecorder) GetOne(alias interface{}) *gomock.Call {
+func (mr *MockCommandMockRecorder) GetOne(ctx, alias interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOne", reflect.TypeOf((*MockCommand)(nil).GetOne), ctx, alias)
+}
+
+// GetRevisions mocks base method.
+func (m *MockCommand) GetRevisions(ctx context.Context, alias string) ([]entities.CommandRevision, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRevisions", ctx, alias)
+	ret0, _ := ret[0].([]entities.CommandRevision)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRevisions indicates an expected call of GetRevisions.
+func (mr *MockCommandMockRecorder) GetRevisions(ctx, alias interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRevisions", reflect.TypeOf((*MockCommand)(nil).GetRevisions), ctx, alias)
+}
+
+// OpenArtifact mocks base method.
+func (m *MockCommand) OpenArtifact(ctx context.Context, id int, path string) (entities.Artifact, io.ReadSeekCloser, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "OpenArtifact", ctx, id, path)
+	ret0, _ := ret[0].(entities.Artifact)
+	ret1, _ := ret[1].(io.ReadSeekCloser)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// OpenArtifact indicates an expected call of OpenArtifact.
+func (mr *MockCommandMockRecorder) OpenArtifact(ctx, id, path interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OpenArtifact", reflect.TypeOf((*MockCommand)(nil).OpenArtifact), ctx, id, path)
+}
+
+// OpenOutput mocks base method.
+func (m *MockCommand) OpenOutput(ctx context.Context, id int, stream entities.LogStream) (entities.ExecutionOutput, io.ReadSeekCloser, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "OpenOutput", ctx, id, stream)
+	ret0, _ := ret[0].(entities.ExecutionOutput)
+	ret1, _ := ret[1].(io.ReadSeekCloser)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// OpenOutput indicates an expected call of OpenOutput.
+func (mr *MockCommandMockRecorder) OpenOutput(ctx, id, stream interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OpenOutput", reflect.TypeOf((*MockCommand)(nil).OpenOutput), ctx, id, stream)
+}
+
+// Patch mocks base method.
+func (m *MockCommand) Patch(ctx context.Context, alias string, version int, dto entities.CommandPatchDto) (entities.Command, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Patch", ctx, alias, version, dto)
+	ret0, _ := ret[0].(entities.Command)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Patch indicates an expected call of Patch.
+func (mr *MockCommandMockRecorder) Patch(ctx, alias, version, dto interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Patch", reflect.TypeOf((*MockCommand)(nil).Patch), ctx, alias, version, dto)
+}
+
+// Reconcile mocks base method.
+func (m *MockCommand) Reconcile() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Reconcile")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Reconcile indicates an expected call of Reconcile.
+func (mr *MockCommandMockRecorder) Reconcile() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Reconcile", reflect.TypeOf((*MockCommand)(nil).Reconcile))
+}
+
+// SearchLogs mocks base method.
+func (m *MockCommand) SearchLogs(ctx context.Context, search entities.LogSearch) ([]entities.LogMatch, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SearchLogs", ctx, search)
+	ret0, _ := ret[0].([]entities.LogMatch)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SearchLogs indicates an expected call of SearchLogs.
+func (mr *MockCommandMockRecorder) SearchLogs(ctx, search interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOne", reflect.TypeOf((*MockCommand)(nil).GetOne), alias)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SearchLogs", reflect.TypeOf((*MockCommand)(nil).SearchLogs), ctx, search)
 }
 
 // StopCommand mocks base method.
-func (m *MockCommand) StopCommand(id int) error {
+func (m *MockCommand) StopCommand(ctx context.Context, dto entities.StopCommandDto) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "StopCommand", id)
+	ret := m.ctrl.Call(m, "StopCommand", ctx, dto)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // StopCommand indicates an expected call of StopCommand.
-func (mr *MockCommandMockRecorder) StopCommand(id interface{}) *gomock.Call {
+func (mr *MockCommandMockRecorder) StopCommand(ctx, dto interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StopCommand", reflect.TypeOf((*MockCommand)(nil).StopCommand), ctx, dto)
+}
+
+// Update mocks base method.
+func (m *MockCommand) Update(ctx context.Context, alias string, version int, dto entities.CommandDto) (entities.Command, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", ctx, alias, version, dto)
+	ret0, _ := ret[0].(entities.Command)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockCommandMockRecorder) Update(ctx, alias, version, dto interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockCommand)(nil).Update), ctx, alias, version, dto)
+}
+
+// Wait mocks base method.
+func (m *MockCommand) Wait(ctx context.Context, id int) (entities.ExecutedCommand, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Wait", ctx, id)
+	ret0, _ := ret[0].(entities.ExecutedCommand)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Wait indicates an expected call of Wait.
+func (mr *MockCommandMockRecorder) Wait(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Wait", reflect.TypeOf((*MockCommand)(nil).Wait), ctx, id)
+}
+
+// MockSchedule is a mock of Schedule interface.
+type MockSchedule struct {
+	ctrl     *gomock.Controller
+	recorder *MockScheduleMockRecorder
+}
+
+// MockScheduleMockRecorder is the mock recorder for MockSchedule.
+type MockScheduleMockRecorder struct {
+	mock *MockSchedule
+}
+
+// NewMockSchedule creates a new mock instance.
+func NewMockSchedule(ctrl *gomock.Controller) *MockSchedule {
+	mock := &MockSchedule{ctrl: ctrl}
+	mock.recorder = &MockScheduleMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSchedule) EXPECT() *MockScheduleMockRecorder {
+	return m.recorder
+}
+
+// CreateSchedule mocks base method.
+func (m *MockSchedule) CreateSchedule(ctx context.Context, dto entities.ScheduleDto) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateSchedule", ctx, dto)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateSchedule indicates an expected call of CreateSchedule.
+func (mr *MockScheduleMockRecorder) CreateSchedule(ctx, dto interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateSchedule", reflect.TypeOf((*MockSchedule)(nil).CreateSchedule), ctx, dto)
+}
+
+// DeleteSchedule mocks base method.
+func (m *MockSchedule) DeleteSchedule(ctx context.Context, id int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteSchedule", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteSchedule indicates an expected call of DeleteSchedule.
+func (mr *MockScheduleMockRecorder) DeleteSchedule(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteSchedule", reflect.TypeOf((*MockSchedule)(nil).DeleteSchedule), ctx, id)
+}
+
+// GetSchedule mocks base method.
+func (m *MockSchedule) GetSchedule(ctx context.Context, id int) (entities.Schedule, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSchedule", ctx, id)
+	ret0, _ := ret[0].(entities.Schedule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSchedule indicates an expected call of GetSchedule.
+func (mr *MockScheduleMockRecorder) GetSchedule(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSchedule", reflect.TypeOf((*MockSchedule)(nil).GetSchedule), ctx, id)
+}
+
+// GetSchedules mocks base method.
+func (m *MockSchedule) GetSchedules(ctx context.Context) ([]entities.Schedule, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSchedules", ctx)
+	ret0, _ := ret[0].([]entities.Schedule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSchedules indicates an expected call of GetSchedules.
+func (mr *MockScheduleMockRecorder) GetSchedules(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSchedules", reflect.TypeOf((*MockSchedule)(nil).GetSchedules), ctx)
+}
+
+// StartScheduler mocks base method.
+func (m *MockSchedule) StartScheduler() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StartScheduler")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// StartScheduler indicates an expected call of StartScheduler.
+func (mr *MockScheduleMockRecorder) StartScheduler() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StartScheduler", reflect.TypeOf((*MockSchedule)(nil).StartScheduler))
+}
+
+// UpdateSchedule mocks base method.
+func (m *MockSchedule) UpdateSchedule(ctx context.Context, id int, dto entities.ScheduleDto) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateSchedule", ctx, id, dto)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateSchedule indicates an expected call of UpdateSchedule.
+func (mr *MockScheduleMockRecorder) UpdateSchedule(ctx, id, dto interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateSchedule", reflect.TypeOf((*MockSchedule)(nil).UpdateSchedule), ctx, id, dto)
+}
+
+// MockPipeline is a mock of Pipeline interface.
+type MockPipeline struct {
+	ctrl     *gomock.Controller
+	recorder *MockPipelineMockRecorder
+}
+
+// MockPipelineMockRecorder is the mock recorder for MockPipeline.
+type MockPipelineMockRecorder struct {
+	mock *MockPipeline
+}
+
+// NewMockPipeline creates a new mock instance.
+func NewMockPipeline(ctrl *gomock.Controller) *MockPipeline {
+	mock := &MockPipeline{ctrl: ctrl}
+	mock.recorder = &MockPipelineMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockPipeline) EXPECT() *MockPipelineMockRecorder {
+	return m.recorder
+}
+
+// CreatePipeline mocks base method.
+func (m *MockPipeline) CreatePipeline(ctx context.Context, dto entities.PipelineDto) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreatePipeline", ctx, dto)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreatePipeline indicates an expected call of CreatePipeline.
+func (mr *MockPipelineMockRecorder) CreatePipeline(ctx, dto interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePipeline", reflect.TypeOf((*MockPipeline)(nil).CreatePipeline), ctx, dto)
+}
+
+// GetPipeline mocks base method.
+func (m *MockPipeline) GetPipeline(ctx context.Context, name string) (entities.Pipeline, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPipeline", ctx, name)
+	ret0, _ := ret[0].(entities.Pipeline)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPipeline indicates an expected call of GetPipeline.
+func (mr *MockPipelineMockRecorder) GetPipeline(ctx, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPipeline", reflect.TypeOf((*MockPipeline)(nil).GetPipeline), ctx, name)
+}
+
+// GetPipelineRun mocks base method.
+func (m *MockPipeline) GetPipelineRun(ctx context.Context, id int) (entities.PipelineRun, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPipelineRun", ctx, id)
+	ret0, _ := ret[0].(entities.PipelineRun)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPipelineRun indicates an expected call of GetPipelineRun.
+func (mr *MockPipelineMockRecorder) GetPipelineRun(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPipelineRun", reflect.TypeOf((*MockPipeline)(nil).GetPipelineRun), ctx, id)
+}
+
+// GetPipelines mocks base method.
+func (m *MockPipeline) GetPipelines(ctx context.Context) ([]entities.Pipeline, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPipelines", ctx)
+	ret0, _ := ret[0].([]entities.Pipeline)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPipelines indicates an expected call of GetPipelines.
+func (mr *MockPipelineMockRecorder) GetPipelines(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPipelines", reflect.TypeOf((*MockPipeline)(nil).GetPipelines), ctx)
+}
+
+// ReconcilePipelines mocks base method.
+func (m *MockPipeline) ReconcilePipelines() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReconcilePipelines")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReconcilePipelines indicates an expected call of ReconcilePipelines.
+func (mr *MockPipelineMockRecorder) ReconcilePipelines() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReconcilePipelines", reflect.TypeOf((*MockPipeline)(nil).ReconcilePipelines))
+}
+
+// RunPipeline mocks base method.
+func (m *MockPipeline) RunPipeline(ctx context.Context, dto entities.RunPipelineDto) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RunPipeline", ctx, dto)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RunPipeline indicates an expected call of RunPipeline.
+func (mr *MockPipelineMockRecorder) RunPipeline(ctx, dto interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RunPipeline", reflect.TypeOf((*MockPipeline)(nil).RunPipeline), ctx, dto)
+}
+
+// MockRetention is a mock of Retention interface.
+type MockRetention struct {
+	ctrl     *gomock.Controller
+	recorder *MockRetentionMockRecorder
+}
+
+// MockRetentionMockRecorder is the mock recorder for MockRetention.
+type MockRetentionMockRecorder struct {
+	mock *MockRetention
+}
+
+// NewMockRetention creates a new mock instance.
+func NewMockRetention(ctrl *gomock.Controller) *MockRetention {
+	mock := &MockRetention{ctrl: ctrl}
+	mock.recorder = &MockRetentionMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRetention) EXPECT() *MockRetentionMockRecorder {
+	return m.recorder
+}
+
+// StartJanitor mocks base method.
+func (m *MockRetention) StartJanitor() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "StartJanitor")
+}
+
+// StartJanitor indicates an expected call of StartJanitor.
+func (mr *MockRetentionMockRecorder) StartJanitor() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StartJanitor", reflect.TypeOf((*MockRetention)(nil).StartJanitor))
+}
+
+// MockSecret is a mock of Secret interface.
+type MockSecret struct {
+	ctrl     *gomock.Controller
+	recorder *MockSecretMockRecorder
+}
+
+// MockSecretMockRecorder is the mock recorder for MockSecret.
+type MockSecretMockRecorder struct {
+	mock *MockSecret
+}
+
+// NewMockSecret creates a new mock instance.
+func NewMockSecret(ctrl *gomock.Controller) *MockSecret {
+	mock := &MockSecret{ctrl: ctrl}
+	mock.recorder = &MockSecretMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSecret) EXPECT() *MockSecretMockRecorder {
+	return m.recorder
+}
+
+// DeleteSecret mocks base method.
+func (m *MockSecret) DeleteSecret(ctx context.Context, name string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteSecret", ctx, name)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteSecret indicates an expected call of DeleteSecret.
+func (mr *MockSecretMockRecorder) DeleteSecret(ctx, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteSecret", reflect.TypeOf((*MockSecret)(nil).DeleteSecret), ctx, name)
+}
+
+// GetSecrets mocks base method.
+func (m *MockSecret) GetSecrets() ([]entities.Secret, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSecrets")
+	ret0, _ := ret[0].([]entities.Secret)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSecrets indicates an expected call of GetSecrets.
+func (mr *MockSecretMockRecorder) GetSecrets() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSecrets", reflect.TypeOf((*MockSecret)(nil).GetSecrets))
+}
+
+// PutSecret mocks base method.
+func (m *MockSecret) PutSecret(ctx context.Context, name string, dto entities.SecretDto) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PutSecret", ctx, name, dto)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// PutSecret indicates an expected call of PutSecret.
+func (mr *MockSecretMockRecorder) PutSecret(ctx, name, dto interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PutSecret", reflect.TypeOf((*MockSecret)(nil).PutSecret), ctx, name, dto)
+}
+
+// MockAuth is a mock of Auth interface.
+type MockAuth struct {
+	ctrl     *gomock.Controller
+	recorder *MockAuthMockRecorder
+}
+
+// MockAuthMockRecorder is the mock recorder for MockAuth.
+type MockAuthMockRecorder struct {
+	mock *MockAuth
+}
+
+// NewMockAuth creates a new mock instance.
+func NewMockAuth(ctrl *gomock.Controller) *MockAuth {
+	mock := &MockAuth{ctrl: ctrl}
+	mock.recorder = &MockAuthMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAuth) EXPECT() *MockAuthMockRecorder {
+	return m.recorder
+}
+
+// Authenticate mocks base method.
+func (m *MockAuth) Authenticate(token string) (entities.Principal, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Authenticate", token)
+	ret0, _ := ret[0].(entities.Principal)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Authenticate indicates an expected call of Authenticate.
+func (mr *MockAuthMockRecorder) Authenticate(token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Authenticate", reflect.TypeOf((*MockAuth)(nil).Authenticate), token)
+}
+
+// CreateApiKey mocks base method.
+func (m *MockAuth) CreateApiKey(ctx context.Context, dto entities.ApiKeyDto) (entities.CreatedApiKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateApiKey", ctx, dto)
+	ret0, _ := ret[0].(entities.CreatedApiKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateApiKey indicates an expected call of CreateApiKey.
+func (mr *MockAuthMockRecorder) CreateApiKey(ctx, dto interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateApiKey", reflect.TypeOf((*MockAuth)(nil).CreateApiKey), ctx, dto)
+}
+
+// DeleteApiKey mocks base method.
+func (m *MockAuth) DeleteApiKey(ctx context.Context, id int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteApiKey", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteApiKey indicates an expected call of DeleteApiKey.
+func (mr *MockAuthMockRecorder) DeleteApiKey(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteApiKey", reflect.TypeOf((*MockAuth)(nil).DeleteApiKey), ctx, id)
+}
+
+// GetApiKeys mocks base method.
+func (m *MockAuth) GetApiKeys() ([]entities.ApiKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetApiKeys")
+	ret0, _ := ret[0].([]entities.ApiKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetApiKeys indicates an expected call of GetApiKeys.
+func (mr *MockAuthMockRecorder) GetApiKeys() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetApiKeys", reflect.TypeOf((*MockAuth)(nil).GetApiKeys))
+}
+
+// MockAudit is a mock of Audit interface.
+type MockAudit struct {
+	ctrl     *gomock.Controller
+	recorder *MockAuditMockRecorder
+}
+
+// MockAuditMockRecorder is the mock recorder for MockAudit.
+type MockAuditMockRecorder struct {
+	mock *MockAudit
+}
+
+// NewMockAudit creates a new mock instance.
+func NewMockAudit(ctrl *gomock.Controller) *MockAudit {
+	mock := &MockAudit{ctrl: ctrl}
+	mock.recorder = &MockAuditMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAudit) EXPECT() *MockAuditMockRecorder {
+	return m.recorder
+}
+
+// ExportAuditEvents mocks base method.
+func (m *MockAudit) ExportAuditEvents(filter entities.AuditFilter, w io.Writer) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExportAuditEvents", filter, w)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ExportAuditEvents indicates an expected call of ExportAuditEvents.
+func (mr *MockAuditMockRecorder) ExportAuditEvents(filter, w interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExportAuditEvents", reflect.TypeOf((*MockAudit)(nil).ExportAuditEvents), filter, w)
+}
+
+// GetAuditEvents mocks base method.
+func (m *MockAudit) GetAuditEvents(filter entities.AuditFilter) ([]entities.AuditEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAuditEvents", filter)
+	ret0, _ := ret[0].([]entities.AuditEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAuditEvents indicates an expected call of GetAuditEvents.
+func (mr *MockAuditMockRecorder) GetAuditEvents(filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAuditEvents", reflect.TypeOf((*MockAudit)(nil).GetAuditEvents), filter)
+}
+
+// Record mocks base method.
+func (m *MockAudit) Record(ctx context.Context, action, target string, payload any, err error) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Record", ctx, action, target, payload, err)
+}
+
+// Record indicates an expected call of Record.
+func (mr *MockAuditMockRecorder) Record(ctx, action, target, payload, err interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StopCommand", reflect.TypeOf((*MockCommand)(nil).StopCommand), id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Record", reflect.TypeOf((*MockAudit)(nil).Record), ctx, action, target, payload, err)
 }
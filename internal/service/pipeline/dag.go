@@ -0,0 +1,97 @@
+package pipeline
+
+import (
+	"fmt"
+	"testex/internal/entities"
+)
+
+// checkSteps validates the step graph of a pipeline and fills in default
+// conditions.
+func checkSteps(steps entities.PipelineSteps) error {
+	if len(steps) == 0 {
+		return fmt.Errorf("%w: pipeline has no steps", entities.ErrInvalidParams)
+	}
+
+	index := make(map[string]int, len(steps))
+	for i, step := range steps {
+		if step.Name == "" {
+			return fmt.Errorf("%w: step %d has no name", entities.ErrInvalidParams, i+1)
+		}
+		if _, ok := index[step.Name]; ok {
+			return fmt.Errorf("%w: duplicate step %q", entities.ErrInvalidParams, step.Name)
+		}
+		index[step.Name] = i
+	}
+
+	for i := range steps {
+		step := &steps[i]
+		switch step.When {
+		case "":
+			step.When = entities.WhenOnSuccess
+		case entities.WhenOnSuccess, entities.WhenOnFailure, entities.WhenAlways:
+		default:
+			return fmt.Errorf("%w: step %q has unknown condition %q", entities.ErrInvalidParams, step.Name, step.When)
+		}
+		if step.Alias == "" {
+			return fmt.Errorf("%w: step %q has no alias", entities.ErrInvalidParams, step.Name)
+		}
+		if step.Timeout < 0 {
+			return fmt.Errorf("%w: step %q has a negative timeout", entities.ErrInvalidParams, step.Name)
+		}
+		for _, need := range step.Needs {
+			if _, ok := index[need]; !ok {
+				return fmt.Errorf("%w: step %q needs unknown step %q", entities.ErrInvalidParams, step.Name, need)
+			}
+		}
+	}
+
+	// Kahn's algorithm: steps left over after removing all reachable ones
+	// form a cycle.
+	pending := make([]int, len(steps))
+	dependents := make([][]int, len(steps))
+	for i, step := range steps {
+		pending[i] = len(step.Needs)
+		for _, need := range step.Needs {
+			dependents[index[need]] = append(dependents[index[need]], i)
+		}
+	}
+	var ready []int
+	for i := range steps {
+		if pending[i] == 0 {
+			ready = append(ready, i)
+		}
+	}
+	visited := 0
+	for len(ready) > 0 {
+		i := ready[0]
+		ready = ready[1:]
+		visited++
+		for _, d := range dependents[i] {
+			if pending[d]--; pending[d] == 0 {
+				ready = append(ready, d)
+			}
+		}
+	}
+	if visited != len(steps) {
+		for i := range steps {
+			if pending[i] > 0 {
+				return fmt.Errorf("%w: step %q is part of a dependency cycle", entities.ErrInvalidParams, steps[i].Name)
+			}
+		}
+	}
+	return nil
+}
+
+// shouldRun decides whether a step runs once all the steps it needs have
+// finished. upstreamFailed tells whether one of them failed, or was skipped
+// because of a failure further up.
+func shouldRun(when entities.StepCondition, upstreamFailed bool) bool {
+	switch when {
+	case entities.WhenAlways:
+		return true
+	case entities.WhenOnFailure:
+		return upstreamFailed
+	default:
+		return !upstreamFailed
+	}
+}
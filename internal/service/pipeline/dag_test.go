@@ -0,0 +1,85 @@
+package pipeline
+
+import (
+	"testex/internal/entities"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckSteps(t *testing.T) {
+	tests := []struct {
+		name        string
+		steps       entities.PipelineSteps
+		expectedErr string
+	}{
+		{
+			name: "Ok",
+			steps: entities.PipelineSteps{
+				{Name: "build", Alias: "build"},
+				{Name: "test", Alias: "test", Needs: []string{"build"}},
+				{Name: "lint", Alias: "lint", Needs: []string{"build"}},
+				{Name: "deploy", Alias: "deploy", Needs: []string{"test", "lint"}},
+				{Name: "notify", Alias: "notify", Needs: []string{"deploy"}, When: entities.WhenAlways},
+			},
+		},
+		{
+			name:        "NoSteps",
+			expectedErr: "invalid command params: pipeline has no steps",
+		},
+		{
+			name: "DuplicateStep",
+			steps: entities.PipelineSteps{
+				{Name: "build", Alias: "build"},
+				{Name: "build", Alias: "build"},
+			},
+			expectedErr: `invalid command params: duplicate step "build"`,
+		},
+		{
+			name: "UnknownNeed",
+			steps: entities.PipelineSteps{
+				{Name: "test", Alias: "test", Needs: []string{"build"}},
+			},
+			expectedErr: `invalid command params: step "test" needs unknown step "build"`,
+		},
+		{
+			name: "UnknownCondition",
+			steps: entities.PipelineSteps{
+				{Name: "test", Alias: "test", When: "sometimes"},
+			},
+			expectedErr: `invalid command params: step "test" has unknown condition "sometimes"`,
+		},
+		{
+			name: "Cycle",
+			steps: entities.PipelineSteps{
+				{Name: "build", Alias: "build"},
+				{Name: "test", Alias: "test", Needs: []string{"build", "deploy"}},
+				{Name: "deploy", Alias: "deploy", Needs: []string{"test"}},
+			},
+			expectedErr: `invalid command params: step "test" is part of a dependency cycle`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := checkSteps(test.steps)
+			if test.expectedErr != "" {
+				assert.EqualError(t, err, test.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+			for _, step := range test.steps {
+				assert.NotEmpty(t, step.When)
+			}
+		})
+	}
+}
+
+func TestShouldRun(t *testing.T) {
+	assert.True(t, shouldRun(entities.WhenOnSuccess, false))
+	assert.False(t, shouldRun(entities.WhenOnSuccess, true))
+	assert.False(t, shouldRun(entities.WhenOnFailure, false))
+	assert.True(t, shouldRun(entities.WhenOnFailure, true))
+	assert.True(t, shouldRun(entities.WhenAlways, false))
+	assert.True(t, shouldRun(entities.WhenAlways, true))
+}
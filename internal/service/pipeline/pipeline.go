@@ -0,0 +1,276 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"testex/internal/entities"
+	"testex/internal/service/audit"
+	"testex/internal/storage"
+	sl "testex/pkg/slog"
+	"time"
+)
+
+// Executor runs the commands of pipeline steps.
+type Executor interface {
+	Execute(ctx context.Context, dto entities.ExecuteCommandDto) (int, error)
+	Wait(ctx context.Context, id int) (entities.ExecutedCommand, error)
+}
+
+type Service struct {
+	Storage  *storage.Storage
+	Logger   *slog.Logger
+	Executor Executor
+	Audit    audit.Recorder
+}
+
+func NewService(storage *storage.Storage, logger *slog.Logger, executor Executor, recorder audit.Recorder) *Service {
+	return &Service{
+		Storage:  storage,
+		Logger:   logger,
+		Executor: executor,
+		Audit:    recorder,
+	}
+}
+
+func (s *Service) CreatePipeline(ctx context.Context, dto entities.PipelineDto) (id int, err error) {
+	defer func() { s.Audit.Record(ctx, "pipeline.create", "pipelines/"+dto.Name, dto, err) }()
+	if dto.Name == "" {
+		return -1, fmt.Errorf("%w: pipeline has no name", entities.ErrInvalidParams)
+	}
+	if err := checkSteps(dto.Steps); err != nil {
+		return -1, err
+	}
+	for _, step := range dto.Steps {
+		if _, err := s.Storage.GetCommand(step.Alias); err != nil {
+			if errors.Is(err, entities.ErrNotFound) {
+				return -1, fmt.Errorf("%w: step %q runs unknown command %q", entities.ErrInvalidParams, step.Name, step.Alias)
+			}
+			return -1, err
+		}
+	}
+	return s.Storage.SavePipeline(entities.Pipeline{Name: dto.Name, Steps: dto.Steps})
+}
+
+// GetPipelines returns the pipelines all of whose commands the principal of
+// ctx may view.
+func (s *Service) GetPipelines(ctx context.Context) ([]entities.Pipeline, error) {
+	pipelines, err := s.Storage.GetAllPipelines()
+	if err != nil {
+		return nil, err
+	}
+	visible, err := entities.ViewFilter(ctx, s.Storage.GetAllCommands)
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(pipelines, func(pipeline entities.Pipeline) bool {
+		return slices.ContainsFunc(pipeline.Steps, func(step entities.PipelineStep) bool { return !visible(step.Alias) })
+	}), nil
+}
+
+// GetPipeline returns a pipeline, hidden ones are not found.
+func (s *Service) GetPipeline(ctx context.Context, name string) (entities.Pipeline, error) {
+	pipeline, err := s.Storage.GetPipeline(name)
+	if err != nil {
+		return pipeline, err
+	}
+	visible, err := entities.ViewFilter(ctx, s.Storage.GetAllCommands)
+	if err != nil {
+		return entities.Pipeline{}, err
+	}
+	if slices.ContainsFunc(pipeline.Steps, func(step entities.PipelineStep) bool { return !visible(step.Alias) }) {
+		return entities.Pipeline{}, entities.ErrNotFound
+	}
+	return pipeline, nil
+}
+
+// GetPipelineRun returns a run, it is not found unless the principal of ctx
+// may view the executions of all its steps.
+func (s *Service) GetPipelineRun(ctx context.Context, id int) (entities.PipelineRun, error) {
+	run, err := s.Storage.GetPipelineRun(id)
+	if err != nil {
+		return run, err
+	}
+	visible, err := entities.ViewFilter(ctx, s.Storage.GetAllCommands)
+	if err != nil {
+		return entities.PipelineRun{}, err
+	}
+	if slices.ContainsFunc(run.Steps, func(step entities.PipelineRunStep) bool { return !visible(step.Alias) }) {
+		return entities.PipelineRun{}, entities.ErrNotFound
+	}
+	return run, nil
+}
+
+// ReconcilePipelines marks runs left over by a previous run of the server as
+// lost, nobody follows their steps anymore.
+func (s *Service) ReconcilePipelines() error {
+	return s.Storage.AbandonPipelineRuns()
+}
+
+// RunPipeline starts a run of a pipeline and returns its id. Steps are
+// executed in the background, so the principal of ctx must be allowed to
+// execute all of them up front.
+func (s *Service) RunPipeline(ctx context.Context, dto entities.RunPipelineDto) (id int, err error) {
+	defer func() { s.Audit.Record(ctx, "pipeline.run", "pipelines/"+dto.Name, dto, err) }()
+	pipeline, err := s.Storage.GetPipeline(dto.Name)
+	if err != nil {
+		return -1, err
+	}
+	if principal, ok := entities.PrincipalFromContext(ctx); ok {
+		for _, step := range pipeline.Steps {
+			command, err := s.Storage.GetCommand(step.Alias)
+			if errors.Is(err, entities.ErrNotFound) {
+				// The step fails when it is reached, as it would without ACLs.
+				continue
+			}
+			if err != nil {
+				return -1, err
+			}
+			if !command.Acl.Permits(principal, entities.PermissionExecute) {
+				return -1, fmt.Errorf("%w: step %q executes %s, which is not permitted", entities.ErrForbidden,
+					step.Name, step.Alias)
+			}
+		}
+	}
+
+	run := entities.PipelineRun{
+		PipelineId: pipeline.Id,
+		Status:     entities.PipelineRunning,
+		StartedAt:  time.Now(),
+	}
+	for _, step := range pipeline.Steps {
+		run.Steps = append(run.Steps, entities.PipelineRunStep{
+			Name:   step.Name,
+			Alias:  step.Alias,
+			Status: entities.StepPending,
+		})
+	}
+	run, err = s.Storage.SavePipelineRun(run)
+	if err != nil {
+		return -1, err
+	}
+
+	go s.execute(pipeline, run)
+	return run.Id, nil
+}
+
+type stepResult struct {
+	index int
+	step  entities.PipelineRunStep
+}
+
+// execute runs the steps of a pipeline run, each as soon as the steps it needs
+// have finished, so independent steps run in parallel.
+func (s *Service) execute(pipeline entities.Pipeline, run entities.PipelineRun) {
+	index := make(map[string]int, len(pipeline.Steps))
+	for i, step := range pipeline.Steps {
+		index[step.Name] = i
+	}
+	// failed tells whether a step failed or was skipped because of a failure.
+	failed := make([]bool, len(pipeline.Steps))
+	results := make(chan stepResult)
+	running := 0
+
+	for {
+		// Skipping a step may unblock others, so repeat until nothing changes.
+		for changed := true; changed; {
+			changed = false
+			for i, def := range pipeline.Steps {
+				if run.Steps[i].Status != entities.StepPending {
+					continue
+				}
+				upstreamFailed, ready := false, true
+				for _, need := range def.Needs {
+					status := run.Steps[index[need]].Status
+					if status == entities.StepPending || status == entities.StepRunning {
+						ready = false
+						break
+					}
+					upstreamFailed = upstreamFailed || failed[index[need]]
+				}
+				if !ready {
+					continue
+				}
+
+				now := time.Now()
+				if !shouldRun(def.When, upstreamFailed) {
+					run.Steps[i].Status = entities.StepSkipped
+					run.Steps[i].FinishedAt = &now
+					failed[i] = upstreamFailed
+					s.saveStep(run.Steps[i])
+					changed = true
+					continue
+				}
+				run.Steps[i].Status = entities.StepRunning
+				run.Steps[i].StartedAt = &now
+				s.saveStep(run.Steps[i])
+				running++
+				go func(i int, def entities.PipelineStep, step entities.PipelineRunStep) {
+					results <- stepResult{index: i, step: s.runStep(def, step)}
+				}(i, def, run.Steps[i])
+			}
+		}
+
+		if running == 0 {
+			break
+		}
+		result := <-results
+		running--
+		now := time.Now()
+		result.step.FinishedAt = &now
+		run.Steps[result.index] = result.step
+		failed[result.index] = result.step.Status == entities.StepFailed
+		s.saveStep(result.step)
+	}
+
+	now := time.Now()
+	run.Status = entities.PipelineSucceeded
+	for _, step := range run.Steps {
+		if step.Status == entities.StepFailed {
+			run.Status = entities.PipelineFailed
+		}
+	}
+	run.FinishedAt = &now
+	if err := s.Storage.FinishPipelineRun(run); err != nil {
+		s.Logger.Error("failed to save pipeline run", slog.Int("id", run.Id), sl.Err(err))
+	}
+	s.Logger.Info("pipeline run finished", slog.Int("id", run.Id), slog.String("status", string(run.Status)))
+}
+
+// runStep executes the command of a step and waits for it to finish.
+func (s *Service) runStep(def entities.PipelineStep, step entities.PipelineRunStep) entities.PipelineRunStep {
+	id, err := s.Executor.Execute(context.Background(), entities.ExecuteCommandDto{
+		Alias:       def.Alias,
+		Params:      def.Params,
+		Timeout:     def.Timeout,
+		TriggeredBy: entities.TriggeredByPipeline,
+	})
+	if err != nil {
+		step.Status = entities.StepFailed
+		step.Error = err.Error()
+		return step
+	}
+	step.ExecutedCommandId = &id
+	s.saveStep(step)
+
+	executed, err := s.Executor.Wait(context.Background(), id)
+	switch {
+	case err != nil:
+		step.Status = entities.StepFailed
+		step.Error = err.Error()
+	case executed.Status == entities.StatusSucceeded:
+		step.Status = entities.StepSucceeded
+	default:
+		step.Status = entities.StepFailed
+		step.Error = fmt.Sprintf("execution %d %s", id, executed.Status)
+	}
+	return step
+}
+
+func (s *Service) saveStep(step entities.PipelineRunStep) {
+	if err := s.Storage.UpdatePipelineRunStep(step); err != nil {
+		s.Logger.Error("failed to save pipeline step", slog.Int("id", step.Id), sl.Err(err))
+	}
+}
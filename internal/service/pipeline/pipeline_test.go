@@ -0,0 +1,103 @@
+package pipeline
+
+import (
+	"context"
+	"testex/internal/entities"
+	"testex/internal/service/audit"
+	"testex/internal/storage"
+	"testex/pkg/slog/slogdiscard"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeStorage serves commands, pipelines and runs from memory.
+type fakeStorage struct {
+	storage.CommandRepository
+	storage.PipelineRepository
+	commands  []entities.Command
+	pipelines []entities.Pipeline
+	runs      []entities.PipelineRun
+}
+
+func (f *fakeStorage) GetAllCommands() ([]entities.Command, error) {
+	return f.commands, nil
+}
+
+func (f *fakeStorage) GetAllPipelines() ([]entities.Pipeline, error) {
+	return append([]entities.Pipeline(nil), f.pipelines...), nil
+}
+
+func (f *fakeStorage) GetPipeline(name string) (entities.Pipeline, error) {
+	for _, pipeline := range f.pipelines {
+		if pipeline.Name == name {
+			return pipeline, nil
+		}
+	}
+	return entities.Pipeline{}, entities.ErrNotFound
+}
+
+func (f *fakeStorage) GetPipelineRun(id int) (entities.PipelineRun, error) {
+	for _, run := range f.runs {
+		if run.Id == id {
+			return run, nil
+		}
+	}
+	return entities.PipelineRun{}, entities.ErrNotFound
+}
+
+func TestService_Acl(t *testing.T) {
+	fake := &fakeStorage{
+		commands: []entities.Command{
+			{Alias: "build"},
+			{Alias: "deploy", Acl: entities.Acl{
+				{Principal: "release", Permissions: []entities.Permission{entities.PermissionViewLogs}},
+			}},
+		},
+		pipelines: []entities.Pipeline{
+			{Id: 1, Name: "ci", Steps: entities.PipelineSteps{{Name: "build", Alias: "build"}}},
+			{Id: 2, Name: "release", Steps: entities.PipelineSteps{
+				{Name: "build", Alias: "build"},
+				{Name: "deploy", Alias: "deploy", Needs: []string{"build"}},
+			}},
+		},
+		runs: []entities.PipelineRun{
+			{Id: 1, PipelineId: 1, Steps: []entities.PipelineRunStep{{Name: "build", Alias: "build"}}},
+			{Id: 2, PipelineId: 2, Steps: []entities.PipelineRunStep{
+				{Name: "build", Alias: "build"},
+				{Name: "deploy", Alias: "deploy"},
+			}},
+		},
+	}
+	s := NewService(&storage.Storage{CommandRepository: fake, PipelineRepository: fake},
+		slogdiscard.NewDiscardLogger(), nil, audit.Discard)
+
+	operator := entities.ContextWithPrincipal(context.Background(),
+		entities.Principal{Name: "bob", Role: entities.RoleOperator})
+	release := entities.ContextWithPrincipal(context.Background(),
+		entities.Principal{Name: "release", Role: entities.RoleOperator})
+
+	pipelines, err := s.GetPipelines(operator)
+	assert.NoError(t, err)
+	assert.Equal(t, fake.pipelines[:1], pipelines, "pipelines running hidden commands are hidden")
+	pipelines, err = s.GetPipelines(release)
+	assert.NoError(t, err)
+	assert.Len(t, pipelines, 2)
+	pipelines, err = s.GetPipelines(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, pipelines, 2, "calls of the server itself are not restricted")
+
+	_, err = s.GetPipeline(operator, "ci")
+	assert.NoError(t, err)
+	_, err = s.GetPipeline(operator, "release")
+	assert.ErrorIs(t, err, entities.ErrNotFound)
+	_, err = s.GetPipeline(release, "release")
+	assert.NoError(t, err)
+
+	_, err = s.GetPipelineRun(operator, 1)
+	assert.NoError(t, err)
+	_, err = s.GetPipelineRun(operator, 2)
+	assert.ErrorIs(t, err, entities.ErrNotFound)
+	_, err = s.GetPipelineRun(release, 2)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,179 @@
+package retention
+
+import (
+	"log/slog"
+	"testex/internal/config"
+	"testex/internal/entities"
+	"testex/internal/storage"
+	sl "testex/pkg/slog"
+	"time"
+)
+
+const defaultBatchSize = 1000
+
+// blobGracePeriod spares blobs stored recently, a run may have stored the
+// same content and be about to save the row that refers to it.
+const blobGracePeriod = time.Hour
+
+type Service struct {
+	Storage *storage.Storage
+	Logger  *slog.Logger
+	Config  config.Retention
+	Workdir config.Workdir
+}
+
+func NewService(storage *storage.Storage, logger *slog.Logger, cfg config.Retention, workdir config.Workdir) *Service {
+	return &Service{
+		Storage: storage,
+		Logger:  logger,
+		Config:  cfg,
+		Workdir: workdir,
+	}
+}
+
+// report counts what a janitor pass has deleted.
+type report struct {
+	executions int
+	lines      int64
+	trimmed    int64
+	blobs      int
+	workdirs   int
+}
+
+// StartJanitor prunes old executions and logs in the background, right away
+// and then every configured interval.
+func (s *Service) StartJanitor() {
+	if s.Config.Interval <= 0 {
+		s.Logger.Info("retention janitor is disabled")
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(s.Config.Interval)
+		defer ticker.Stop()
+		for {
+			start := time.Now()
+			r, err := s.prune()
+			if err != nil {
+				s.Logger.Error("failed to prune executions", sl.Err(err))
+			}
+			if r.workdirs, err = s.sweepWorkdirs(); err != nil {
+				s.Logger.Error("failed to remove working directories", sl.Err(err))
+			}
+			if r.executions > 0 || r.lines > 0 || r.trimmed > 0 || r.blobs > 0 || r.workdirs > 0 {
+				s.Logger.Info("pruned executions", slog.Int("executions", r.executions),
+					slog.Int64("lines", r.lines), slog.Int64("trimmed", r.trimmed), slog.Int("blobs", r.blobs),
+					slog.Int("workdirs", r.workdirs), slog.Duration("took", time.Since(start)))
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// prune deletes expired executions with their logs, then trims logs of
+// finished executions to the size limit. Every statement touches at most a
+// batch of rows, so writers of the same tables are never blocked for long.
+func (s *Service) prune() (report, error) {
+	var r report
+	batch := s.Config.BatchSize
+	if batch <= 0 {
+		batch = defaultBatchSize
+	}
+	policy := entities.RetentionPolicy{
+		MaxAge:        s.Config.MaxAge,
+		MaxExecutions: s.Config.MaxExecutions,
+		MaxLogBytes:   s.Config.MaxLogBytes,
+	}
+
+	for {
+		ids, err := s.Storage.GetExpiredExecutions(policy, batch)
+		if err != nil {
+			return r, err
+		}
+		if len(ids) == 0 {
+			break
+		}
+		for {
+			n, err := s.Storage.DeleteExecutionLogs(ids, batch)
+			if err != nil {
+				return r, err
+			}
+			r.lines += n
+			if n < int64(batch) {
+				break
+			}
+		}
+		if err = s.Storage.DeleteExecutions(ids); err != nil {
+			return r, err
+		}
+		r.executions += len(ids)
+		if len(ids) < batch {
+			break
+		}
+	}
+
+	var err error
+	if r.blobs, err = s.sweepBlobs(batch); err != nil {
+		return r, err
+	}
+
+	for {
+		compactions, err := s.Storage.GetUncompactedExecutions(policy.MaxLogBytes, batch)
+		if err != nil || len(compactions) == 0 {
+			return r, err
+		}
+		for _, compaction := range compactions {
+			var trimmed int64
+			for compaction.MaxLogBytes > 0 {
+				n, err := s.Storage.TrimLogs(compaction.ExecutedCommandId, compaction.MaxLogBytes, batch)
+				if err != nil {
+					return r, err
+				}
+				trimmed += n
+				if n < int64(batch) {
+					break
+				}
+			}
+			if err = s.Storage.FinishCompaction(compaction.ExecutedCommandId, trimmed); err != nil {
+				return r, err
+			}
+			r.trimmed += trimmed
+		}
+		if len(compactions) < batch {
+			return r, nil
+		}
+	}
+}
+
+// sweepBlobs deletes blobs marked as orphaned, except those stored within
+// the grace period. They stay marked and are checked again next time.
+func (s *Service) sweepBlobs(batch int) (int, error) {
+	deleted, after := 0, ""
+	writtenBefore := time.Now().Add(-blobGracePeriod)
+	for {
+		keys, err := s.Storage.GetOrphanedBlobs(after, batch)
+		if err != nil {
+			return deleted, err
+		}
+		var gone []string
+		for _, key := range keys {
+			ok, err := s.Storage.DeleteBlob(key, writtenBefore)
+			if err != nil {
+				s.Logger.Error("failed to delete blob", slog.String("key", key), sl.Err(err))
+				continue
+			}
+			if ok {
+				gone = append(gone, key)
+			}
+		}
+		if len(gone) > 0 {
+			if err = s.Storage.UnmarkBlobs(gone); err != nil {
+				return deleted, err
+			}
+			deleted += len(gone)
+		}
+		if len(keys) < batch {
+			return deleted, nil
+		}
+		after = keys[len(keys)-1]
+	}
+}
@@ -0,0 +1,186 @@
+package retention
+
+import (
+	"sort"
+	"testex/internal/config"
+	"testex/internal/entities"
+	"testex/internal/storage"
+	"testex/pkg/slog/slogdiscard"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeRetention keeps executions with their log line counts and raw output
+// keys in memory. Limits given to queries are honored like the database would.
+type fakeRetention struct {
+	storage.BlobStore
+	lines      map[int]int64
+	outputs    map[int]string
+	blobs      []string
+	marked     map[string]bool
+	fresh      map[string]bool
+	expired    map[int]bool
+	compacted  map[int]int64
+	maxBytes   int64
+	statements int
+}
+
+func (f *fakeRetention) GetExpiredExecutions(_ entities.RetentionPolicy, limit int) ([]int, error) {
+	f.statements++
+	ids := []int{}
+	for id := range f.expired {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids[:min(limit, len(ids))], nil
+}
+
+func (f *fakeRetention) DeleteExecutionLogs(ids []int, limit int) (int64, error) {
+	f.statements++
+	var deleted int64
+	for _, id := range ids {
+		n := min(f.lines[id], int64(limit)-deleted)
+		f.lines[id] -= n
+		deleted += n
+	}
+	return deleted, nil
+}
+
+func (f *fakeRetention) DeleteExecutions(ids []int) error {
+	f.statements++
+	var keys []string
+	for _, id := range ids {
+		if key, ok := f.outputs[id]; ok {
+			keys = append(keys, key)
+		}
+		delete(f.expired, id)
+		delete(f.lines, id)
+		delete(f.outputs, id)
+	}
+	kept := map[string]bool{}
+	for _, key := range f.outputs {
+		kept[key] = true
+	}
+	for _, key := range keys {
+		if !kept[key] {
+			f.marked[key] = true
+		}
+	}
+	return nil
+}
+
+func (f *fakeRetention) GetOrphanedBlobs(after string, limit int) ([]string, error) {
+	f.statements++
+	kept := map[string]bool{}
+	for _, key := range f.outputs {
+		kept[key] = true
+	}
+	keys := []string{}
+	for key := range f.marked {
+		if key > after && !kept[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys[:min(limit, len(keys))], nil
+}
+
+func (f *fakeRetention) UnmarkBlobs(keys []string) error {
+	f.statements++
+	for _, key := range keys {
+		delete(f.marked, key)
+	}
+	return nil
+}
+
+// DeleteBlob spares blobs in fresh, as if they were stored just now.
+func (f *fakeRetention) DeleteBlob(key string, _ time.Time) (bool, error) {
+	if f.fresh[key] {
+		return false, nil
+	}
+	f.blobs = append(f.blobs, key)
+	return true, nil
+}
+
+func (f *fakeRetention) GetUncompactedExecutions(maxLogBytes int64, limit int) ([]entities.LogCompaction, error) {
+	f.statements++
+	compactions := []entities.LogCompaction{}
+	for id := range f.lines {
+		if _, ok := f.compacted[id]; !ok {
+			compactions = append(compactions, entities.LogCompaction{ExecutedCommandId: id, MaxLogBytes: maxLogBytes})
+		}
+	}
+	sort.Slice(compactions, func(i, j int) bool { return compactions[i].ExecutedCommandId < compactions[j].ExecutedCommandId })
+	return compactions[:min(limit, len(compactions))], nil
+}
+
+// TrimLogs treats every line as one byte long.
+func (f *fakeRetention) TrimLogs(id int, maxBytes int64, limit int) (int64, error) {
+	f.statements++
+	n := min(max(f.lines[id]-maxBytes, 0), int64(limit))
+	f.lines[id] -= n
+	return n, nil
+}
+
+func (f *fakeRetention) FinishCompaction(id int, trimmed int64) error {
+	f.statements++
+	f.compacted[id] = trimmed
+	return nil
+}
+
+func TestPrune(t *testing.T) {
+	repo := &fakeRetention{
+		lines:     map[int]int64{1: 25, 2: 3, 3: 0, 4: 12, 5: 4},
+		outputs:   map[int]string{1: "a", 2: "b", 3: "c", 4: "b"},
+		marked:    map[string]bool{},
+		fresh:     map[string]bool{"c": true},
+		expired:   map[int]bool{1: true, 2: true, 3: true},
+		compacted: map[int]int64{},
+	}
+	s := NewService(&storage.Storage{RetentionRepository: repo, BlobStore: repo}, slogdiscard.NewDiscardLogger(),
+		config.Retention{BatchSize: 2, MaxLogBytes: 5}, config.Workdir{})
+
+	r, err := s.prune()
+	assert.NoError(t, err)
+	assert.Equal(t, report{executions: 3, lines: 28, trimmed: 7, blobs: 1}, r)
+	// The output of execution 2 is shared with 4, which is kept. The output
+	// of 3 was just stored again by another run.
+	assert.Equal(t, []string{"a"}, repo.blobs)
+	assert.Equal(t, map[string]bool{"c": true}, repo.marked)
+	assert.Equal(t, map[int]int64{4: 5, 5: 4}, repo.lines)
+	assert.Equal(t, map[int]int64{4: 7, 5: 0}, repo.compacted)
+
+	// Nothing is left to do on the next pass.
+	repo.statements = 0
+	r, err = s.prune()
+	assert.NoError(t, err)
+	assert.Equal(t, report{}, r)
+	assert.Equal(t, 3, repo.statements)
+
+	// Once the grace period is over, the sweep deletes it.
+	repo.fresh = nil
+	r, err = s.prune()
+	assert.NoError(t, err)
+	assert.Equal(t, report{blobs: 1}, r)
+	assert.Equal(t, []string{"a", "c"}, repo.blobs)
+	assert.Empty(t, repo.marked)
+}
+
+func TestPrune_NoSizeLimit(t *testing.T) {
+	repo := &fakeRetention{
+		lines:     map[int]int64{1: 100},
+		marked:    map[string]bool{},
+		expired:   map[int]bool{},
+		compacted: map[int]int64{},
+	}
+	s := NewService(&storage.Storage{RetentionRepository: repo}, slogdiscard.NewDiscardLogger(), config.Retention{},
+		config.Workdir{})
+
+	r, err := s.prune()
+	assert.NoError(t, err)
+	assert.Equal(t, report{}, r)
+	assert.Equal(t, map[int]int64{1: 100}, repo.lines)
+	assert.Equal(t, map[int]int64{1: 0}, repo.compacted)
+}
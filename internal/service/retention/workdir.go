@@ -0,0 +1,68 @@
+package retention
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testex/internal/entities"
+	sl "testex/pkg/slog"
+	"time"
+)
+
+// sweepWorkdirs removes temporary working directories of executions finished
+// more than the configured time ago, and of executions that are gone. Secret
+// files of finished executions, left if testex was stopped while they ran, are
+// removed right away. It returns the number of removed directories.
+func (s *Service) sweepWorkdirs() (int, error) {
+	temp, err := s.Workdir.TempDir()
+	if err != nil {
+		return 0, err
+	}
+	secrets, err := s.Workdir.SecretsDir()
+	if err != nil {
+		return 0, err
+	}
+	removed, err := s.sweep(temp, s.Workdir.TempMaxAge)
+	if err != nil {
+		return removed, err
+	}
+	n, err := s.sweep(secrets, 0)
+	return removed + n, err
+}
+
+// sweep removes directories named by ids of executions finished more than
+// maxAge ago, or gone.
+func (s *Service) sweep(dir string, maxAge time.Duration) (int, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	cutoff := time.Now().Add(-maxAge)
+	removed := 0
+	for _, entry := range entries {
+		id, err := strconv.Atoi(entry.Name())
+		if err != nil || !entry.IsDir() {
+			continue
+		}
+		executed, err := s.Storage.GetExecutedCommandById(id)
+		switch {
+		case errors.Is(err, entities.ErrNotFound):
+		case err != nil:
+			return removed, err
+		case executed.FinishedAt == nil || executed.FinishedAt.After(cutoff):
+			continue
+		}
+		if err = os.RemoveAll(filepath.Join(dir, entry.Name())); err != nil {
+			s.Logger.Error("failed to remove directory", slog.String("dir", dir), slog.Int("id", id), sl.Err(err))
+			continue
+		}
+		removed++
+	}
+	return removed, nil
+}
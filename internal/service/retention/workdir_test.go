@@ -0,0 +1,74 @@
+package retention
+
+import (
+	"os"
+	"path/filepath"
+	"testex/internal/config"
+	"testex/internal/entities"
+	"testex/internal/storage"
+	"testex/pkg/slog/slogdiscard"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeExecutions finds executions by id, missing ones are not found.
+type fakeExecutions struct {
+	storage.CommandRepository
+	executions map[int]entities.ExecutedCommand
+}
+
+func (f fakeExecutions) GetExecutedCommandById(id int) (entities.ExecutedCommand, error) {
+	executed, ok := f.executions[id]
+	if !ok {
+		return executed, entities.ErrNotFound
+	}
+	return executed, nil
+}
+
+func TestSweepWorkdirs(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"1", "2", "3", "4", "other"} {
+		assert.NoError(t, os.MkdirAll(filepath.Join(root, "tmp", name), 0o755))
+	}
+	for _, name := range []string{"2", "3"} {
+		assert.NoError(t, os.MkdirAll(filepath.Join(root, "secrets", name), 0o700))
+	}
+	old := time.Now().Add(-2 * time.Hour)
+	recent := time.Now().Add(-time.Minute)
+	repo := fakeExecutions{executions: map[int]entities.ExecutedCommand{
+		1: {Id: 1, FinishedAt: &old},
+		2: {Id: 2, FinishedAt: &recent},
+		3: {Id: 3, IsActive: true},
+	}}
+	s := NewService(&storage.Storage{CommandRepository: repo}, slogdiscard.NewDiscardLogger(), config.Retention{},
+		config.Workdir{Root: root, TempMaxAge: time.Hour})
+
+	removed, err := s.sweepWorkdirs()
+	assert.NoError(t, err)
+	// Execution 1 finished long ago and 4 is deleted, 2 is recent and 3 runs.
+	// Secrets of the finished execution 2 are removed anyway.
+	assert.Equal(t, 3, removed)
+	assert.Equal(t, []string{"2", "3", "other"}, dirNames(t, filepath.Join(root, "tmp")))
+	assert.Equal(t, []string{"3"}, dirNames(t, filepath.Join(root, "secrets")))
+}
+
+func dirNames(t *testing.T, dir string) []string {
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	return names
+}
+
+func TestSweepWorkdirs_NoDir(t *testing.T) {
+	s := NewService(&storage.Storage{}, slogdiscard.NewDiscardLogger(), config.Retention{},
+		config.Workdir{Root: filepath.Join(t.TempDir(), "missing")})
+
+	removed, err := s.sweepWorkdirs()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, removed)
+}
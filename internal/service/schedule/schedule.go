@@ -0,0 +1,282 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"testex/internal/entities"
+	"testex/internal/service/audit"
+	"testex/internal/storage"
+	sl "testex/pkg/slog"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// Executor runs commands on behalf of schedules.
+type Executor interface {
+	Execute(ctx context.Context, dto entities.ExecuteCommandDto) (int, error)
+	GetExecutedCommand(ctx context.Context, id int) (entities.ExecutedCommand, error)
+	CheckParams(command entities.Command, input entities.ParamInput) error
+}
+
+type Service struct {
+	Storage  *storage.Storage
+	Logger   *slog.Logger
+	Executor Executor
+	Audit    audit.Recorder
+	mutex    sync.Mutex
+	cron     *cron.Cron
+	entries  map[int]cron.EntryID
+}
+
+func NewService(storage *storage.Storage, logger *slog.Logger, executor Executor, recorder audit.Recorder) *Service {
+	return &Service{
+		Storage:  storage,
+		Logger:   logger,
+		Executor: executor,
+		Audit:    recorder,
+		cron:     cron.New(),
+		entries:  make(map[int]cron.EntryID),
+	}
+}
+
+// StartScheduler registers enabled schedules and starts triggering them.
+// Schedules with catch-up that missed runs while the server was down are
+// run once right away.
+func (s *Service) StartScheduler() error {
+	schedules, err := s.Storage.GetAllSchedules()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, schedule := range schedules {
+		if !schedule.Enabled {
+			continue
+		}
+		spec, err := parse(schedule.Cron, schedule.Timezone)
+		if err != nil {
+			s.Logger.Error("failed to parse schedule", slog.Int("id", schedule.Id), sl.Err(err))
+			continue
+		}
+		s.register(schedule.Id, spec)
+
+		last := schedule.CreatedAt
+		if schedule.LastRunAt != nil {
+			last = *schedule.LastRunAt
+		}
+		if schedule.CatchUp && !spec.Next(last).After(now) {
+			s.Logger.Info("catching up missed schedule run", slog.Int("id", schedule.Id))
+			go s.trigger(schedule.Id)
+		}
+	}
+	s.cron.Start()
+	return nil
+}
+
+func (s *Service) CreateSchedule(ctx context.Context, dto entities.ScheduleDto) (id int, err error) {
+	defer func() { s.Audit.Record(ctx, "schedule.create", audit.Target("schedules", id), dto, err) }()
+	schedule, err := s.validate(dto)
+	if err != nil {
+		return -1, err
+	}
+	id, err = s.Storage.SaveSchedule(schedule)
+	if err != nil {
+		return -1, err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if schedule.Enabled {
+		spec, _ := parse(schedule.Cron, schedule.Timezone)
+		s.register(id, spec)
+	}
+	return id, nil
+}
+
+// GetSchedules returns the schedules of commands whose executions the
+// principal of ctx may view.
+func (s *Service) GetSchedules(ctx context.Context) ([]entities.Schedule, error) {
+	schedules, err := s.Storage.GetAllSchedules()
+	if err != nil {
+		return nil, err
+	}
+	visible, err := entities.ViewFilter(ctx, s.Storage.GetAllCommands)
+	if err != nil {
+		return nil, err
+	}
+	schedules = slices.DeleteFunc(schedules, func(schedule entities.Schedule) bool {
+		return !visible(schedule.Alias)
+	})
+	for i := range schedules {
+		schedules[i].NextRunAt = nextRun(schedules[i])
+	}
+	return schedules, nil
+}
+
+// GetSchedule returns a schedule, hidden ones are not found.
+func (s *Service) GetSchedule(ctx context.Context, id int) (entities.Schedule, error) {
+	schedule, err := s.Storage.GetSchedule(id)
+	if err != nil {
+		return schedule, err
+	}
+	visible, err := entities.ViewFilter(ctx, s.Storage.GetAllCommands)
+	if err != nil {
+		return entities.Schedule{}, err
+	}
+	if !visible(schedule.Alias) {
+		return entities.Schedule{}, entities.ErrNotFound
+	}
+	schedule.NextRunAt = nextRun(schedule)
+	return schedule, nil
+}
+
+func (s *Service) UpdateSchedule(ctx context.Context, id int, dto entities.ScheduleDto) (err error) {
+	defer func() { s.Audit.Record(ctx, "schedule.update", audit.Target("schedules", id), dto, err) }()
+	schedule, err := s.validate(dto)
+	if err != nil {
+		return err
+	}
+	schedule.Id = id
+	if err = s.Storage.UpdateSchedule(schedule); err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.unregister(id)
+	if schedule.Enabled {
+		spec, _ := parse(schedule.Cron, schedule.Timezone)
+		s.register(id, spec)
+	}
+	return nil
+}
+
+func (s *Service) DeleteSchedule(ctx context.Context, id int) (err error) {
+	defer func() { s.Audit.Record(ctx, "schedule.delete", audit.Target("schedules", id), nil, err) }()
+	if err := s.Storage.DeleteSchedule(id); err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.unregister(id)
+	return nil
+}
+
+// validate checks a schedule before it is stored, filling in defaults.
+func (s *Service) validate(dto entities.ScheduleDto) (entities.Schedule, error) {
+	schedule := entities.Schedule{
+		Alias:         dto.Alias,
+		Cron:          strings.TrimSpace(dto.Cron),
+		Timezone:      dto.Timezone,
+		Enabled:       dto.Enabled == nil || *dto.Enabled,
+		Params:        dto.Params,
+		SkipIfRunning: dto.SkipIfRunning,
+		CatchUp:       dto.CatchUp,
+	}
+	if schedule.Timezone == "" {
+		schedule.Timezone = "UTC"
+	}
+
+	command, err := s.Storage.GetCommand(schedule.Alias)
+	if err != nil {
+		if errors.Is(err, entities.ErrNotFound) {
+			return schedule, fmt.Errorf("%w: unknown command %q", entities.ErrInvalidParams, schedule.Alias)
+		}
+		return schedule, err
+	}
+	// Params are checked now, a schedule that can't run would only fail later.
+	if err = s.Executor.CheckParams(command, schedule.Params); err != nil {
+		return schedule, err
+	}
+	if _, err := time.LoadLocation(schedule.Timezone); err != nil {
+		return schedule, fmt.Errorf("%w: unknown timezone %q", entities.ErrInvalidParams, schedule.Timezone)
+	}
+	// The timezone has its own field, so it must not be set in the expression.
+	if strings.HasPrefix(schedule.Cron, "TZ=") || strings.HasPrefix(schedule.Cron, "CRON_TZ=") {
+		return schedule, fmt.Errorf("%w: use the timezone field instead of TZ in the cron expression", entities.ErrInvalidParams)
+	}
+	if _, err := parse(schedule.Cron, schedule.Timezone); err != nil {
+		return schedule, fmt.Errorf("%w: %v", entities.ErrInvalidParams, err)
+	}
+	return schedule, nil
+}
+
+// register adds a schedule to the cron loop. It is called with the mutex held.
+func (s *Service) register(id int, spec cron.Schedule) {
+	s.entries[id] = s.cron.Schedule(spec, cron.FuncJob(func() {
+		s.trigger(id)
+	}))
+}
+
+// unregister removes a schedule from the cron loop. It is called with the
+// mutex held.
+func (s *Service) unregister(id int) {
+	if entry, ok := s.entries[id]; ok {
+		s.cron.Remove(entry)
+		delete(s.entries, id)
+	}
+}
+
+// trigger executes the command of a schedule through the regular Execute
+// path. The schedule is read again, so that a run never uses stale params.
+func (s *Service) trigger(id int) {
+	schedule, err := s.Storage.GetSchedule(id)
+	if err != nil {
+		s.Logger.Error("failed to get schedule", slog.Int("id", id), sl.Err(err))
+		return
+	}
+	if !schedule.Enabled {
+		return
+	}
+
+	if schedule.SkipIfRunning && schedule.LastExecutionId != nil {
+		last, err := s.Executor.GetExecutedCommand(context.Background(), *schedule.LastExecutionId)
+		if err == nil && last.IsActive {
+			s.Logger.Info("skipping schedule run, previous run is still active",
+				slog.Int("id", id), slog.Int("execution_id", last.Id))
+			return
+		}
+	}
+
+	now := time.Now()
+	var executionId *int
+	executed, err := s.Executor.Execute(context.Background(), entities.ExecuteCommandDto{
+		Alias:       schedule.Alias,
+		Params:      schedule.Params,
+		ScheduleId:  &schedule.Id,
+		TriggeredBy: entities.TriggeredBySchedule,
+	})
+	if err != nil {
+		s.Logger.Error("failed to execute scheduled command", slog.Int("id", id), sl.Err(err))
+	} else {
+		executionId = &executed
+	}
+	if err = s.Storage.SaveScheduleRun(id, now, executionId); err != nil {
+		s.Logger.Error("failed to save schedule run", slog.Int("id", id), sl.Err(err))
+	}
+}
+
+func parse(expr, timezone string) (cron.Schedule, error) {
+	return cron.ParseStandard("CRON_TZ=" + timezone + " " + expr)
+}
+
+func nextRun(schedule entities.Schedule) *time.Time {
+	if !schedule.Enabled {
+		return nil
+	}
+	spec, err := parse(schedule.Cron, schedule.Timezone)
+	if err != nil {
+		return nil
+	}
+	next := spec.Next(time.Now())
+	return &next
+}
@@ -0,0 +1,261 @@
+package schedule
+
+import (
+	"context"
+	"sync"
+	"testex/internal/config"
+	"testex/internal/entities"
+	"testex/internal/service/audit"
+	"testex/internal/service/command"
+	"testex/internal/storage"
+	"testex/pkg/slog/slogdiscard"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeStorage serves commands and schedules from memory.
+type fakeStorage struct {
+	storage.CommandRepository
+	storage.ScheduleRepository
+	mu        sync.Mutex
+	commands  []entities.Command
+	schedules []entities.Schedule
+	runs      []int
+}
+
+func (f *fakeStorage) GetCommand(alias string) (entities.Command, error) {
+	for _, command := range f.commands {
+		if command.Alias == alias {
+			return command, nil
+		}
+	}
+	return entities.Command{}, entities.ErrNotFound
+}
+
+func (f *fakeStorage) SaveSchedule(schedule entities.Schedule) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	schedule.Id = len(f.schedules) + 1
+	f.schedules = append(f.schedules, schedule)
+	return schedule.Id, nil
+}
+
+func (f *fakeStorage) UpdateSchedule(schedule entities.Schedule) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.schedules[schedule.Id-1] = schedule
+	return nil
+}
+
+func (f *fakeStorage) SaveScheduleRun(id int, at time.Time, executionId *int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.schedules[id-1].LastRunAt = &at
+	f.schedules[id-1].LastExecutionId = executionId
+	f.runs = append(f.runs, id)
+	return nil
+}
+
+// ran returns the ids of schedules that were triggered, in order.
+func (f *fakeStorage) ran() []int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]int(nil), f.runs...)
+}
+
+func (f *fakeStorage) GetAllCommands() ([]entities.Command, error) {
+	return f.commands, nil
+}
+
+func (f *fakeStorage) GetAllSchedules() ([]entities.Schedule, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]entities.Schedule(nil), f.schedules...), nil
+}
+
+func (f *fakeStorage) GetSchedule(id int) (entities.Schedule, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, schedule := range f.schedules {
+		if schedule.Id == id {
+			return schedule, nil
+		}
+	}
+	return entities.Schedule{}, entities.ErrNotFound
+}
+
+// fakeExecutor records executions instead of running them. Params are checked
+// by the command service.
+type fakeExecutor struct {
+	*command.Service
+	mu       sync.Mutex
+	executed []entities.ExecuteCommandDto
+	// active is reported for every execution.
+	active bool
+}
+
+func (f *fakeExecutor) Execute(_ context.Context, dto entities.ExecuteCommandDto) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.executed = append(f.executed, dto)
+	return len(f.executed), nil
+}
+
+func (f *fakeExecutor) GetExecutedCommand(_ context.Context, id int) (entities.ExecutedCommand, error) {
+	return entities.ExecutedCommand{Id: id, IsActive: f.active}, nil
+}
+
+func newTestService(fake *fakeStorage) (*Service, *fakeExecutor) {
+	s := &storage.Storage{CommandRepository: fake, ScheduleRepository: fake}
+	executor := &fakeExecutor{
+		Service: command.NewService(s, slogdiscard.NewDiscardLogger(), config.Config{}, nil, audit.Discard),
+	}
+	return NewService(s, slogdiscard.NewDiscardLogger(), executor, audit.Discard), executor
+}
+
+func TestService_ValidateParams(t *testing.T) {
+	fake := &fakeStorage{commands: []entities.Command{{Alias: "backup", Params: entities.CommandParams{
+		{Name: "target", Type: entities.ParamEnum, Values: []string{"s3", "disk"}, Required: true},
+	}}}}
+	s, _ := newTestService(fake)
+	enabled := false
+
+	tests := []struct {
+		name   string
+		params entities.ParamInput
+		valid  bool
+	}{
+		{name: "Ok", params: entities.ParamInput{"target": "s3"}, valid: true},
+		{name: "WrongValue", params: entities.ParamInput{"target": "tape"}},
+		{name: "Unknown", params: entities.ParamInput{"target": "s3", "compress": true}},
+		{name: "Missing"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dto := entities.ScheduleDto{Alias: "backup", Cron: "0 3 * * *", Enabled: &enabled, Params: test.params}
+			_, err := s.CreateSchedule(context.Background(), dto)
+			if test.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, entities.ErrInvalidParams)
+			}
+		})
+	}
+
+	dto := entities.ScheduleDto{Alias: "backup", Cron: "0 3 * * *", Enabled: &enabled,
+		Params: entities.ParamInput{"target": "tape"}}
+	assert.ErrorIs(t, s.UpdateSchedule(context.Background(), 1, dto), entities.ErrInvalidParams)
+	assert.Equal(t, entities.ParamInput{"target": "s3"}, fake.schedules[0].Params, "invalid updates are not saved")
+}
+
+func TestService_Timezone(t *testing.T) {
+	s, _ := newTestService(&fakeStorage{commands: []entities.Command{{Alias: "backup"}}})
+	enabled := false
+	for _, dto := range []entities.ScheduleDto{
+		{Alias: "backup", Cron: "0 3 * * *", Timezone: "Mars/Olympus"},
+		{Alias: "backup", Cron: "CRON_TZ=Asia/Tokyo 0 3 * * *"},
+		{Alias: "backup", Cron: "0 25 * * *"},
+		{Alias: "restore", Cron: "0 3 * * *"},
+	} {
+		dto.Enabled = &enabled
+		_, err := s.CreateSchedule(context.Background(), dto)
+		assert.ErrorIs(t, err, entities.ErrInvalidParams, dto.Cron)
+	}
+
+	// 09:00 in Tokyo is midnight UTC.
+	spec, err := parse("0 9 * * *", "Asia/Tokyo")
+	assert.NoError(t, err)
+	next := spec.Next(time.Date(2024, 4, 30, 12, 0, 0, 0, time.UTC))
+	assert.True(t, next.Equal(time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)), next)
+}
+
+func TestService_SkipIfRunning(t *testing.T) {
+	last := 7
+	fake := &fakeStorage{
+		commands: []entities.Command{{Alias: "backup"}},
+		schedules: []entities.Schedule{
+			{Id: 1, Alias: "backup", Cron: "* * * * *", Enabled: true, SkipIfRunning: true, LastExecutionId: &last},
+			{Id: 2, Alias: "backup", Cron: "* * * * *", Enabled: true, LastExecutionId: &last},
+		},
+	}
+	s, executor := newTestService(fake)
+
+	executor.active = true
+	s.trigger(1)
+	s.trigger(2)
+	assert.Equal(t, []int{2}, fake.ran(), "runs are skipped while the previous one is active")
+
+	executor.active = false
+	s.trigger(1)
+	assert.Equal(t, []int{2, 1}, fake.ran())
+	assert.Len(t, executor.executed, 2)
+	assert.Equal(t, entities.TriggeredBySchedule, executor.executed[1].TriggeredBy)
+	assert.Equal(t, 1, *executor.executed[1].ScheduleId)
+}
+
+func TestService_CatchUp(t *testing.T) {
+	missed := time.Now().Add(-48 * time.Hour)
+	fake := &fakeStorage{
+		commands: []entities.Command{{Alias: "backup"}},
+		schedules: []entities.Schedule{
+			{Id: 1, Alias: "backup", Cron: "0 3 * * *", Timezone: "UTC", Enabled: true, CatchUp: true,
+				LastRunAt: &missed},
+			{Id: 2, Alias: "backup", Cron: "0 3 * * *", Timezone: "UTC", Enabled: true, LastRunAt: &missed},
+			{Id: 3, Alias: "backup", Cron: "0 3 * * *", Timezone: "UTC", Enabled: true, CatchUp: true,
+				CreatedAt: time.Now()},
+			{Id: 4, Alias: "backup", Cron: "0 3 * * *", Timezone: "UTC", CatchUp: true, LastRunAt: &missed},
+		},
+	}
+	s, _ := newTestService(fake)
+
+	assert.NoError(t, s.StartScheduler())
+	defer s.cron.Stop()
+	assert.Eventually(t, func() bool { return len(fake.ran()) > 0 }, 5*time.Second, 10*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, []int{1}, fake.ran(), "only enabled schedules with catch-up that missed a run are caught up")
+}
+
+func TestService_Acl(t *testing.T) {
+	fake := &fakeStorage{
+		commands: []entities.Command{
+			{Alias: "backup"},
+			{Alias: "rotate-keys", Acl: entities.Acl{
+				{Group: "security", Permissions: []entities.Permission{entities.PermissionViewLogs}},
+			}},
+		},
+		schedules: []entities.Schedule{
+			{Id: 1, Alias: "backup", Cron: "0 3 * * *", Timezone: "UTC"},
+			{Id: 2, Alias: "rotate-keys", Cron: "0 4 * * 1", Timezone: "UTC", Enabled: true,
+				Params: entities.ParamInput{"vault": "prod"}},
+		},
+	}
+	s := NewService(&storage.Storage{CommandRepository: fake, ScheduleRepository: fake},
+		slogdiscard.NewDiscardLogger(), nil, audit.Discard)
+
+	operator := entities.ContextWithPrincipal(context.Background(),
+		entities.Principal{Name: "bob", Role: entities.RoleOperator})
+	security := entities.ContextWithPrincipal(context.Background(),
+		entities.Principal{Name: "eve", Role: entities.RoleViewer, Groups: entities.Groups{"security"}})
+
+	schedules, err := s.GetSchedules(operator)
+	assert.NoError(t, err)
+	assert.Len(t, schedules, 1)
+	assert.Equal(t, "backup", schedules[0].Alias)
+	schedules, err = s.GetSchedules(security)
+	assert.NoError(t, err)
+	assert.Len(t, schedules, 2)
+	schedules, err = s.GetSchedules(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, schedules, 2, "calls of the server itself are not restricted")
+
+	_, err = s.GetSchedule(operator, 1)
+	assert.NoError(t, err)
+	_, err = s.GetSchedule(operator, 2)
+	assert.ErrorIs(t, err, entities.ErrNotFound, "hidden schedules are not found")
+	schedule, err := s.GetSchedule(security, 2)
+	assert.NoError(t, err)
+	assert.NotNil(t, schedule.NextRunAt)
+}
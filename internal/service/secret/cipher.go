@@ -0,0 +1,45 @@
+package secret
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+)
+
+// Cipher seals secret values with AES-256-GCM. The name of a secret is
+// authenticated along with its value, so a sealed value can't be moved to
+// another secret.
+type Cipher struct {
+	aead cipher.AEAD
+}
+
+func NewCipher(key []byte) (*Cipher, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Cipher{aead: aead}, nil
+}
+
+// Seal encrypts a value, prepending a random nonce to it.
+func (c *Cipher) Seal(name string, value []byte) ([]byte, error) {
+	nonce := make([]byte, c.aead.NonceSize(), c.aead.NonceSize()+len(value)+c.aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return c.aead.Seal(nonce, nonce, value, []byte(name)), nil
+}
+
+// Open decrypts a value sealed by Seal.
+func (c *Cipher) Open(name string, sealed []byte) ([]byte, error) {
+	size := c.aead.NonceSize()
+	if len(sealed) < size {
+		return nil, errors.New("sealed value is too short")
+	}
+	return c.aead.Open(nil, sealed[:size], sealed[size:], []byte(name))
+}
@@ -0,0 +1,97 @@
+package secret
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"testex/internal/entities"
+	"testex/internal/service/audit"
+	"testex/internal/storage"
+)
+
+// maxValueSize limits a secret value, secrets are tokens and keys rather than
+// files.
+const maxValueSize = 64 * 1024
+
+var nameRegexp = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.-]{0,254}$`)
+
+// ErrNoKey is returned when secrets are used without a configured key.
+var ErrNoKey = errors.New("secrets key is not configured")
+
+type Service struct {
+	Storage *storage.Storage
+	Logger  *slog.Logger
+	// cipher is nil when no key is configured.
+	cipher *Cipher
+	Audit  audit.Recorder
+}
+
+func NewService(storage *storage.Storage, logger *slog.Logger, cipher *Cipher, recorder audit.Recorder) *Service {
+	return &Service{
+		Storage: storage,
+		Logger:  logger,
+		cipher:  cipher,
+		Audit:   recorder,
+	}
+}
+
+// PutSecret creates a secret or replaces its value. The value isn't hashed
+// into the audit trail, short values could be guessed from the hash.
+func (s *Service) PutSecret(ctx context.Context, name string, dto entities.SecretDto) (err error) {
+	defer func() { s.Audit.Record(ctx, "secret.put", "secrets/"+name, nil, err) }()
+	if !nameRegexp.MatchString(name) {
+		return fmt.Errorf("%w: bad secret name %q", entities.ErrInvalidParams, name)
+	}
+	if dto.Value == "" || len(dto.Value) > maxValueSize {
+		return fmt.Errorf("%w: secret value must be from 1 to %d bytes long", entities.ErrInvalidParams, maxValueSize)
+	}
+	if s.cipher == nil {
+		return ErrNoKey
+	}
+	sealed, err := s.cipher.Seal(name, []byte(dto.Value))
+	if err != nil {
+		return err
+	}
+	return s.Storage.SaveSecret(entities.Secret{Name: name, Value: sealed})
+}
+
+// GetSecrets lists secrets, values are never returned.
+func (s *Service) GetSecrets() ([]entities.Secret, error) {
+	return s.Storage.GetSecrets()
+}
+
+func (s *Service) DeleteSecret(ctx context.Context, name string) (err error) {
+	defer func() { s.Audit.Record(ctx, "secret.delete", "secrets/"+name, nil, err) }()
+	return s.Storage.DeleteSecret(name)
+}
+
+// Resolve decrypts values of the named secrets for an execution. All of them
+// must exist.
+func (s *Service) Resolve(names []string) (map[string]string, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	if s.cipher == nil {
+		return nil, ErrNoKey
+	}
+	secrets, err := s.Storage.GetSecretsByName(names)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]string, len(secrets))
+	for _, secret := range secrets {
+		value, err := s.cipher.Open(secret.Name, secret.Value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt secret %q: %w", secret.Name, err)
+		}
+		values[secret.Name] = string(value)
+	}
+	for _, name := range names {
+		if _, ok := values[name]; !ok {
+			return nil, fmt.Errorf("secret %q is not found", name)
+		}
+	}
+	return values, nil
+}
@@ -0,0 +1,83 @@
+package secret
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testex/internal/entities"
+	"testex/internal/service/audit"
+	"testex/internal/storage"
+	"testex/pkg/slog/slogdiscard"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeSecrets keeps sealed secrets in memory.
+type fakeSecrets struct {
+	storage.SecretRepository
+	secrets map[string][]byte
+}
+
+func (f *fakeSecrets) SaveSecret(secret entities.Secret) error {
+	f.secrets[secret.Name] = secret.Value
+	return nil
+}
+
+func (f *fakeSecrets) GetSecretsByName(names []string) ([]entities.Secret, error) {
+	var secrets []entities.Secret
+	for _, name := range names {
+		if value, ok := f.secrets[name]; ok {
+			secrets = append(secrets, entities.Secret{Name: name, Value: value})
+		}
+	}
+	return secrets, nil
+}
+
+func TestCipher(t *testing.T) {
+	c, err := NewCipher(bytes.Repeat([]byte{7}, 32))
+	assert.NoError(t, err)
+
+	sealed, err := c.Seal("token", []byte("s3cret"))
+	assert.NoError(t, err)
+	assert.NotContains(t, string(sealed), "s3cret")
+	again, err := c.Seal("token", []byte("s3cret"))
+	assert.NoError(t, err)
+	assert.NotEqual(t, sealed, again, "nonces must differ")
+
+	value, err := c.Open("token", sealed)
+	assert.NoError(t, err)
+	assert.Equal(t, "s3cret", string(value))
+
+	_, err = c.Open("other", sealed)
+	assert.Error(t, err, "a value must not open under another name")
+	_, err = c.Open("token", sealed[:4])
+	assert.Error(t, err)
+}
+
+func TestService(t *testing.T) {
+	c, err := NewCipher(bytes.Repeat([]byte{7}, 32))
+	assert.NoError(t, err)
+	repo := &fakeSecrets{secrets: map[string][]byte{}}
+	s := NewService(&storage.Storage{SecretRepository: repo}, slogdiscard.NewDiscardLogger(), c, audit.Discard)
+
+	assert.NoError(t, s.PutSecret(context.Background(), "github_token", entities.SecretDto{Value: "ghp_123"}))
+	assert.NotContains(t, string(repo.secrets["github_token"]), "ghp_123")
+
+	err = s.PutSecret(context.Background(), "../x", entities.SecretDto{Value: "v"})
+	assert.True(t, errors.Is(err, entities.ErrInvalidParams), err)
+	err = s.PutSecret(context.Background(), "empty", entities.SecretDto{})
+	assert.True(t, errors.Is(err, entities.ErrInvalidParams), err)
+
+	values, err := s.Resolve([]string{"github_token"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"github_token": "ghp_123"}, values)
+
+	_, err = s.Resolve([]string{"github_token", "missing"})
+	assert.EqualError(t, err, `secret "missing" is not found`)
+
+	noKey := NewService(&storage.Storage{SecretRepository: repo}, slogdiscard.NewDiscardLogger(), nil, audit.Discard)
+	assert.ErrorIs(t, noKey.PutSecret(context.Background(), "github_token", entities.SecretDto{Value: "v"}), ErrNoKey)
+	_, err = noKey.Resolve([]string{"github_token"})
+	assert.ErrorIs(t, err, ErrNoKey)
+}
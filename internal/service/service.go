@@ -1,10 +1,18 @@
 package service
 
 import (
+	"context"
+	"io"
 	"log/slog"
 	"testex/internal/config"
 	"testex/internal/entities"
+	"testex/internal/service/audit"
+	"testex/internal/service/auth"
 	"testex/internal/service/command"
+	"testex/internal/service/pipeline"
+	"testex/internal/service/retention"
+	"testex/internal/service/schedule"
+	"testex/internal/service/secret"
 	"testex/internal/storage"
 )
 
@@ -12,20 +20,92 @@ import (
 
 type Service struct {
 	Command
+	Schedule
+	Pipeline
+	Retention
+	Secret
+	Auth
+	Audit
 }
 
-func New(s *storage.Storage, logger *slog.Logger, cfg config.Config) *Service {
+func New(s *storage.Storage, logger *slog.Logger, cfg config.Config, cipher *secret.Cipher) *Service {
+	auditor := audit.NewService(s, logger)
+	secrets := secret.NewService(s, logger, cipher, auditor)
+	commands := command.NewService(s, logger, cfg, secrets, auditor)
 	return &Service{
-		Command: command.NewService(s, logger, cfg),
+		Command:   commands,
+		Secret:    secrets,
+		Schedule:  schedule.NewService(s, logger, commands, auditor),
+		Pipeline:  pipeline.NewService(s, logger, commands, auditor),
+		Retention: retention.NewService(s, logger, cfg.Retention, cfg.Workdir),
+		Auth:      auth.NewService(s, logger, cfg.Auth, auditor),
+		Audit:     auditor,
 	}
 }
 
 type Command interface {
-	Execute(alias string) (int, error)
-	Create(alias string, script string) (int, error)
-	GetAll() ([]entities.Command, error)
-	GetOne(alias string) (entities.Command, error)
-	GetActiveExecutedCommand() ([]entities.ExecutedCommand, error)
-	StopCommand(id int) error
-	GetLogs(executedCommandId int) ([]entities.Log, error)
+	Execute(ctx context.Context, dto entities.ExecuteCommandDto) (int, error)
+	Create(ctx context.Context, dto entities.CommandDto) (int, error)
+	GetAll(ctx context.Context) ([]entities.Command, error)
+	GetOne(ctx context.Context, alias string) (entities.Command, error)
+	Update(ctx context.Context, alias string, version int, dto entities.CommandDto) (entities.Command, error)
+	Patch(ctx context.Context, alias string, version int, dto entities.CommandPatchDto) (entities.Command, error)
+	Delete(ctx context.Context, alias string, version int) error
+	GetRevisions(ctx context.Context, alias string) ([]entities.CommandRevision, error)
+	GetActiveExecutedCommand(ctx context.Context) ([]entities.ExecutedCommand, error)
+	StopCommand(ctx context.Context, dto entities.StopCommandDto) error
+	GetLogs(ctx context.Context, executedCommandId int, filter entities.LogFilter) ([]entities.Log, error)
+	GetExecutedCommand(ctx context.Context, id int) (entities.ExecutedCommand, error)
+	FollowLogs(ctx context.Context, executedCommandId int) (<-chan entities.Log, func(), error)
+	Wait(ctx context.Context, id int) (entities.ExecutedCommand, error)
+	GetExecutions(ctx context.Context, filter entities.ExecutionFilter, cursor string) (entities.ExecutionPage, error)
+	GetCommandExecutions(ctx context.Context, alias string, filter entities.ExecutionFilter,
+		cursor string) (entities.ExecutionPage, error)
+	GetExecution(ctx context.Context, id int) (entities.ExecutionDetails, error)
+	SearchLogs(ctx context.Context, search entities.LogSearch) ([]entities.LogMatch, error)
+	OpenOutput(ctx context.Context, id int, stream entities.LogStream) (entities.ExecutionOutput, io.ReadSeekCloser, error)
+	GetArtifacts(ctx context.Context, id int) ([]entities.Artifact, error)
+	OpenArtifact(ctx context.Context, id int, path string) (entities.Artifact, io.ReadSeekCloser, error)
+	Reconcile() error
+}
+
+type Schedule interface {
+	CreateSchedule(ctx context.Context, dto entities.ScheduleDto) (int, error)
+	GetSchedules(ctx context.Context) ([]entities.Schedule, error)
+	GetSchedule(ctx context.Context, id int) (entities.Schedule, error)
+	UpdateSchedule(ctx context.Context, id int, dto entities.ScheduleDto) error
+	DeleteSchedule(ctx context.Context, id int) error
+	StartScheduler() error
+}
+
+type Pipeline interface {
+	CreatePipeline(ctx context.Context, dto entities.PipelineDto) (int, error)
+	GetPipelines(ctx context.Context) ([]entities.Pipeline, error)
+	GetPipeline(ctx context.Context, name string) (entities.Pipeline, error)
+	RunPipeline(ctx context.Context, dto entities.RunPipelineDto) (int, error)
+	GetPipelineRun(ctx context.Context, id int) (entities.PipelineRun, error)
+	ReconcilePipelines() error
+}
+
+type Retention interface {
+	StartJanitor()
+}
+
+type Secret interface {
+	PutSecret(ctx context.Context, name string, dto entities.SecretDto) error
+	GetSecrets() ([]entities.Secret, error)
+	DeleteSecret(ctx context.Context, name string) error
+}
+
+type Auth interface {
+	Authenticate(token string) (entities.Principal, error)
+	CreateApiKey(ctx context.Context, dto entities.ApiKeyDto) (entities.CreatedApiKey, error)
+	GetApiKeys() ([]entities.ApiKey, error)
+	DeleteApiKey(ctx context.Context, id int) error
+}
+
+type Audit interface {
+	Record(ctx context.Context, action, target string, payload any, err error)
+	GetAuditEvents(filter entities.AuditFilter) ([]entities.AuditEvent, error)
+	ExportAuditEvents(filter entities.AuditFilter, w io.Writer) error
 }
@@ -0,0 +1,121 @@
+package filesystem
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"testex/internal/entities"
+	"time"
+)
+
+// BlobStorage keeps blobs in a directory on the local filesystem, named by
+// the SHA-256 of their content, so equal outputs are stored once.
+type BlobStorage struct {
+	Dir string
+	// mu orders storing a blob against deleting one with the same key.
+	mu sync.Mutex
+}
+
+func NewBlobStorage(dir string) (*BlobStorage, error) {
+	const fn = "storage.filesystem.NewBlobStorage"
+
+	if err := os.MkdirAll(filepath.Join(dir, "tmp"), 0o750); err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+	return &BlobStorage{Dir: dir}, nil
+}
+
+// PutBlob stores everything read from r and returns the key of the blob. The
+// content is written to a temporary file first, so a failed write never
+// leaves a partial blob under a key. The blob is written anew even if it
+// exists, so its modification time tells when it was last stored.
+func (s *BlobStorage) PutBlob(r io.Reader) (string, int64, error) {
+	tmp, err := os.CreateTemp(filepath.Join(s.Dir, "tmp"), "blob-*")
+	if err != nil {
+		return "", 0, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hash), r)
+	if err != nil {
+		return "", 0, err
+	}
+	if err = tmp.Sync(); err != nil {
+		return "", 0, err
+	}
+	if err = tmp.Close(); err != nil {
+		return "", 0, err
+	}
+
+	key := hex.EncodeToString(hash.Sum(nil))
+	path, err := s.path(key)
+	if err != nil {
+		return "", 0, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err = os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return "", 0, err
+	}
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return "", 0, err
+	}
+	return key, size, nil
+}
+
+func (s *BlobStorage) OpenBlob(key string) (io.ReadSeekCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, entities.ErrNotFound
+	}
+	return f, err
+}
+
+// DeleteBlob deletes a blob unless it was stored after writtenBefore: a run
+// may have just stored the same content and not yet saved what refers to it.
+// It reports whether the blob is gone.
+func (s *BlobStorage) DeleteBlob(key string, writtenBefore time.Time) (bool, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return false, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if info.ModTime().After(writtenBefore) {
+		return false, nil
+	}
+	if err = os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return false, err
+	}
+	return true, nil
+}
+
+// path spreads blobs over subdirectories by the first byte of the key.
+func (s *BlobStorage) path(key string) (string, error) {
+	if len(key) != sha256.Size*2 {
+		return "", fmt.Errorf("malformed blob key %q", key)
+	}
+	if _, err := hex.DecodeString(key); err != nil {
+		return "", fmt.Errorf("malformed blob key %q", key)
+	}
+	return filepath.Join(s.Dir, key[:2], key), nil
+}
@@ -0,0 +1,61 @@
+package filesystem
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testex/internal/entities"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBlobStorage(t *testing.T) {
+	const key = "b206899bc103669c8e7b36de29d73f95b46795b508aa87d612b2ce84bfb29df2"
+
+	s, err := NewBlobStorage(t.TempDir())
+	assert.NoError(t, err)
+
+	stored, size, err := s.PutBlob(strings.NewReader("hello\x00world"))
+	assert.NoError(t, err)
+	assert.Equal(t, key, stored)
+	assert.EqualValues(t, 11, size)
+	assert.FileExists(t, filepath.Join(s.Dir, "b2", key))
+
+	// Equal content is stored once under the same key.
+	stored, _, err = s.PutBlob(strings.NewReader("hello\x00world"))
+	assert.NoError(t, err)
+	assert.Equal(t, key, stored)
+
+	blob, err := s.OpenBlob(key)
+	assert.NoError(t, err)
+	_, err = blob.Seek(6, io.SeekStart)
+	assert.NoError(t, err)
+	data, err := io.ReadAll(blob)
+	assert.NoError(t, err)
+	assert.Equal(t, "world", string(data))
+	assert.NoError(t, blob.Close())
+
+	// Blobs stored within the grace period are kept.
+	deleted, err := s.DeleteBlob(key, time.Now().Add(-time.Hour))
+	assert.NoError(t, err)
+	assert.False(t, deleted)
+	deleted, err = s.DeleteBlob(key, time.Now().Add(time.Second))
+	assert.NoError(t, err)
+	assert.True(t, deleted)
+	deleted, err = s.DeleteBlob(key, time.Now())
+	assert.NoError(t, err)
+	assert.True(t, deleted, "missing blobs are gone already")
+	_, err = s.OpenBlob(key)
+	assert.ErrorIs(t, err, entities.ErrNotFound)
+
+	_, err = s.OpenBlob("../../etc/passwd")
+	assert.Error(t, err)
+
+	// Temporary files don't outlive a write.
+	tmp, err := os.ReadDir(filepath.Join(s.Dir, "tmp"))
+	assert.NoError(t, err)
+	assert.Empty(t, tmp)
+}
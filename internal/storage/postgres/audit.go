@@ -0,0 +1,72 @@
+package postgres
+
+import (
+	"fmt"
+	"testex/internal/entities"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type AuditStorage struct {
+	Db *sqlx.DB
+}
+
+func NewAuditStorage(db *sqlx.DB) *AuditStorage {
+	return &AuditStorage{db}
+}
+
+func (s AuditStorage) SaveAuditEvent(event entities.AuditEvent) error {
+	query := fmt.Sprintf(`INSERT INTO %s (actor, role, source_ip, action, target, payload_hash, outcome, error)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`, AuditEventsTable)
+	_, err := s.Db.Exec(query, event.Actor, event.Role, event.SourceIp, event.Action, event.Target,
+		event.PayloadHash, event.Outcome, event.Error)
+	return err
+}
+
+// GetAuditEvents lists events matching the filter, newest first unless the
+// filter is ascending.
+func (s AuditStorage) GetAuditEvents(filter entities.AuditFilter) ([]entities.AuditEvent, error) {
+	query := fmt.Sprintf("SELECT * FROM %s WHERE true", AuditEventsTable)
+	var args []any
+	where := func(condition string, arg any) {
+		args = append(args, arg)
+		query += fmt.Sprintf(" AND "+condition, len(args))
+	}
+	if filter.Actor != "" {
+		where("actor = $%d", filter.Actor)
+	}
+	if filter.Action != "" {
+		where("action = $%d", filter.Action)
+	}
+	if filter.Target != "" {
+		where("target = $%d", filter.Target)
+	}
+	if filter.Outcome != "" {
+		where("outcome = $%d", filter.Outcome)
+	}
+	if !filter.From.IsZero() {
+		where("at >= $%d", filter.From)
+	}
+	if !filter.To.IsZero() {
+		where("at < $%d", filter.To)
+	}
+	if filter.BeforeId > 0 {
+		where("id < $%d", filter.BeforeId)
+	}
+	if filter.AfterId > 0 {
+		where("id > $%d", filter.AfterId)
+	}
+	order := "DESC"
+	if filter.Ascending {
+		order = "ASC"
+	}
+	query += " ORDER BY id " + order
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+
+	events := []entities.AuditEvent{}
+	err := s.Db.Select(&events, query, args...)
+	return events, err
+}
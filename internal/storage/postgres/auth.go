@@ -0,0 +1,52 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"testex/internal/entities"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type AuthStorage struct {
+	Db *sqlx.DB
+}
+
+func NewAuthStorage(db *sqlx.DB) *AuthStorage {
+	return &AuthStorage{db}
+}
+
+func (s AuthStorage) SaveApiKey(key entities.ApiKey) (entities.ApiKey, error) {
+	query := fmt.Sprintf(`INSERT INTO %s (name, role, prefix, hash, groups) VALUES ($1, $2, $3, $4, $5)
+		RETURNING *`, ApiKeysTable)
+	var saved entities.ApiKey
+	err := s.Db.Get(&saved, query, key.Name, key.Role, key.Prefix, key.Hash, key.Groups)
+	return saved, err
+}
+
+func (s AuthStorage) GetApiKeys() ([]entities.ApiKey, error) {
+	keys := []entities.ApiKey{}
+	query := fmt.Sprintf("SELECT * FROM %s ORDER BY id", ApiKeysTable)
+	err := s.Db.Select(&keys, query)
+	return keys, err
+}
+
+func (s AuthStorage) GetApiKeyByHash(hash string) (entities.ApiKey, error) {
+	var key entities.ApiKey
+	query := fmt.Sprintf("SELECT * FROM %s WHERE hash = $1", ApiKeysTable)
+	err := s.Db.Get(&key, query, hash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return key, entities.ErrNotFound
+	}
+	return key, err
+}
+
+func (s AuthStorage) DeleteApiKey(id int) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE id = $1", ApiKeysTable)
+	res, err := s.Db.Exec(query, id)
+	if err != nil {
+		return err
+	}
+	return expectRow(res)
+}
@@ -1,10 +1,14 @@
 package postgres
 
 import (
+	"database/sql"
+	"errors"
 	"fmt"
+	"strings"
 	"testex/internal/entities"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
 type CommandStorage struct {
@@ -15,44 +19,259 @@ func NewCommandStorage(db *sqlx.DB) *CommandStorage {
 	return &CommandStorage{db}
 }
 
+// SaveCommand stores a new command together with its first revision.
 func (s CommandStorage) SaveCommand(command entities.Command) (int, error) {
+	tx, err := s.Db.Beginx()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
 	var id int
-	query := fmt.Sprintf("INSERT INTO %s (alias ,script) VALUES ($1,$2) RETURNING id", CommandTable)
-	row := s.Db.QueryRow(query, command.Alias, command.Script)
-	if err := row.Scan(&id); err != nil {
+	query := fmt.Sprintf(`INSERT INTO %s (alias, script, params, timeout, stop_signal, grace_period, max_concurrency, overflow,
+		output_mode, artifacts, workdir_mode, workdir, env, env_inherit, secrets, acl, run_as, max_line_length,
+		max_output_bytes, max_output_lines, retention_days, keep_executions, max_log_bytes)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21,
+			$22, $23)
+		RETURNING id`, CommandTable)
+	row := tx.QueryRow(query, command.Alias, command.Script, command.Params, command.Timeout,
+		command.StopSignal, command.GracePeriod, command.MaxConcurrency, command.Overflow, command.OutputMode,
+		command.Artifacts, command.WorkdirMode, command.Workdir, command.Env, command.EnvInherit, command.Secrets,
+		command.Acl, command.RunAs, command.MaxLineLength, command.MaxOutputBytes, command.MaxOutputLines,
+		command.RetentionDays, command.KeepExecutions, command.MaxLogBytes)
+	if err = row.Scan(&id); err != nil {
+		return 0, conflict(err)
+	}
+	if _, err = saveRevision(tx, id, 1, command); err != nil {
 		return 0, err
 	}
-	return id, nil
+	return id, tx.Commit()
 }
 
 func (s CommandStorage) GetCommand(alias string) (entities.Command, error) {
 	var c entities.Command
-	query := fmt.Sprintf("SELECT * from %s WHERE alias=$1", CommandTable)
+	query := fmt.Sprintf("SELECT * from %s WHERE alias=$1 AND deleted_at IS NULL", CommandTable)
 	err := s.Db.Get(&c, query, alias)
+	if errors.Is(err, sql.ErrNoRows) {
+		return c, entities.ErrNotFound
+	}
 	return c, err
 }
 
 func (s CommandStorage) GetAllCommands() ([]entities.Command, error) {
 	var c []entities.Command
-	query := fmt.Sprintf("SELECT * from %s", CommandTable)
+	query := fmt.Sprintf("SELECT * from %s WHERE deleted_at IS NULL", CommandTable)
 	err := s.Db.Select(&c, query)
 	return c, err
 }
 
+// UpdateCommand replaces the command with the given alias and stores a new
+// revision of it. A non-zero version must match the current one. Schedules
+// and pipelines follow a renamed command.
+func (s CommandStorage) UpdateCommand(alias string, version int, command entities.Command) (entities.Command, error) {
+	tx, err := s.Db.Beginx()
+	if err != nil {
+		return command, err
+	}
+	defer tx.Rollback()
+
+	current, err := lockCommand(tx, alias, version)
+	if err != nil {
+		return command, err
+	}
+
+	var updated entities.Command
+	query := fmt.Sprintf(`UPDATE %s SET alias = $1, script = $2, params = $3, timeout = $4, stop_signal = $5,
+		grace_period = $6, max_concurrency = $7, overflow = $8, output_mode = $9, artifacts = $10,
+		workdir_mode = $11, workdir = $12, env = $13, env_inherit = $14, secrets = $15, acl = $16, run_as = $17,
+		max_line_length = $18, max_output_bytes = $19, max_output_lines = $20, retention_days = $21,
+		keep_executions = $22, max_log_bytes = $23, version = version + 1 WHERE id = $24 RETURNING *`, CommandTable)
+	err = tx.Get(&updated, query, command.Alias, command.Script, command.Params, command.Timeout, command.StopSignal,
+		command.GracePeriod, command.MaxConcurrency, command.Overflow, command.OutputMode, command.Artifacts,
+		command.WorkdirMode, command.Workdir, command.Env, command.EnvInherit, command.Secrets, command.Acl,
+		command.RunAs, command.MaxLineLength, command.MaxOutputBytes, command.MaxOutputLines, command.RetentionDays,
+		command.KeepExecutions, command.MaxLogBytes, current.Id)
+	if err != nil {
+		return command, conflict(err)
+	}
+	if updated.RevisionId, err = saveRevision(tx, updated.Id, updated.Version, updated); err != nil {
+		return command, err
+	}
+
+	if updated.Alias != current.Alias {
+		query = fmt.Sprintf("UPDATE %s SET alias = $1 WHERE alias = $2", SchedulesTable)
+		if _, err = tx.Exec(query, updated.Alias, current.Alias); err != nil {
+			return command, err
+		}
+		query = fmt.Sprintf(`UPDATE %s SET steps = (
+				SELECT jsonb_agg(CASE WHEN step->>'alias' = $2 THEN jsonb_set(step, '{alias}', to_jsonb($1::text))
+					ELSE step END ORDER BY n)
+				FROM jsonb_array_elements(steps) WITH ORDINALITY AS t(step, n))
+			WHERE steps @> jsonb_build_array(jsonb_build_object('alias', $2::text))`, PipelinesTable)
+		if _, err = tx.Exec(query, updated.Alias, current.Alias); err != nil {
+			return command, err
+		}
+	}
+	return updated, tx.Commit()
+}
+
+// DeleteCommand marks a command as deleted, keeping it for the history of its
+// executions, and disables its schedules. A non-zero version must match the
+// current one.
+func (s CommandStorage) DeleteCommand(alias string, version int) error {
+	tx, err := s.Db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	current, err := lockCommand(tx, alias, version)
+	if err != nil {
+		return err
+	}
+	query := fmt.Sprintf("UPDATE %s SET deleted_at = CURRENT_TIMESTAMP WHERE id = $1", CommandTable)
+	if _, err = tx.Exec(query, current.Id); err != nil {
+		return err
+	}
+	query = fmt.Sprintf("UPDATE %s SET enabled = false WHERE alias = $1", SchedulesTable)
+	if _, err = tx.Exec(query, alias); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s CommandStorage) GetCommandRevisions(commandId int) ([]entities.CommandRevision, error) {
+	var revisions []entities.CommandRevision
+	query := fmt.Sprintf("SELECT * FROM %s WHERE command_id = $1 ORDER BY version", CommandRevisionsTable)
+	err := s.Db.Select(&revisions, query, commandId)
+	return revisions, err
+}
+
+// lockCommand reads a live command for an edit and checks its version.
+func lockCommand(tx *sqlx.Tx, alias string, version int) (entities.Command, error) {
+	var current entities.Command
+	query := fmt.Sprintf("SELECT * FROM %s WHERE alias = $1 AND deleted_at IS NULL FOR UPDATE", CommandTable)
+	err := tx.Get(&current, query, alias)
+	if errors.Is(err, sql.ErrNoRows) {
+		return current, entities.ErrNotFound
+	}
+	if err != nil {
+		return current, err
+	}
+	if version != 0 && version != current.Version {
+		return current, fmt.Errorf("%w: command %s is at version %d", entities.ErrVersionMismatch, alias, current.Version)
+	}
+	return current, nil
+}
+
+// saveRevision stores the script of a command version and makes it current.
+func saveRevision(tx *sqlx.Tx, commandId, version int, command entities.Command) (int, error) {
+	var id int
+	query := fmt.Sprintf(`INSERT INTO %s (command_id, version, script, params) VALUES ($1, $2, $3, $4)
+		RETURNING id`, CommandRevisionsTable)
+	if err := tx.QueryRow(query, commandId, version, command.Script, command.Params).Scan(&id); err != nil {
+		return 0, err
+	}
+	query = fmt.Sprintf("UPDATE %s SET revision_id = $1 WHERE id = $2", CommandTable)
+	_, err := tx.Exec(query, id, commandId)
+	return id, err
+}
+
+// viewable limits a query joining commands as c to the ones whose ACL lets
+// the principal view logs, see entities.Acl.
+func viewable(query *string, args []any, principal entities.Principal) []any {
+	args = append(args, principal.Name, pq.Array([]string(principal.Groups)))
+	*query += fmt.Sprintf(` AND (c.acl IS NULL OR c.acl IN ('null'::jsonb, '[]'::jsonb) OR EXISTS (
+		SELECT 1 FROM jsonb_array_elements(c.acl) a
+		WHERE (a->>'principal' = $%d OR (COALESCE(a->>'principal', '') = '' AND a->>'group' = ANY($%d)))
+			AND a->'permissions' @> '["view_logs"]'))`, len(args)-1, len(args))
+	return args
+}
+
+// conflict turns unique violations, e.g. of an alias, into entities.ErrConflict.
+func conflict(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return fmt.Errorf("%w: %s", entities.ErrConflict, pqErr.Detail)
+	}
+	return err
+}
+
 func (s CommandStorage) SaveLog(log entities.Log) (int, error) {
 	var id int
-	query := fmt.Sprintf("INSERT INTO %s (executed_command_id, message ) VALUES ($1,$2) RETURNING id", LogsTable)
-	row := s.Db.QueryRow(query, log.ExecutedCommandId, log.Message)
+	query := fmt.Sprintf(`INSERT INTO %s (executed_command_id, seq, stream, level, message, date)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`, LogsTable)
+	row := s.Db.QueryRow(query, log.ExecutedCommandId, log.Seq, log.Stream, log.Level, log.Message, log.Date)
 	if err := row.Scan(&id); err != nil {
 		return 0, err
 	}
 	return id, nil
 }
 
+// logColumns is the number of values inserted per log line.
+const logColumns = 6
+
+// maxLogRows keeps an insert of logs under the limit of 65535 bind parameters
+// of Postgres.
+const maxLogRows = 65535 / logColumns
+
+// SaveLogs stores a batch of logs with a single multi-row INSERT and returns
+// their ids in order. Larger batches are split into several inserts within one
+// transaction.
+func (s CommandStorage) SaveLogs(logs []entities.Log) ([]int, error) {
+	if len(logs) == 0 {
+		return nil, nil
+	}
+	if len(logs) <= maxLogRows {
+		return insertLogs(s.Db, logs)
+	}
+
+	tx, err := s.Db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	ids := make([]int, 0, len(logs))
+	for start := 0; start < len(logs); start += maxLogRows {
+		chunk, err := insertLogs(tx, logs[start:min(start+maxLogRows, len(logs))])
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, chunk...)
+	}
+	return ids, tx.Commit()
+}
+
+func insertLogs(q sqlx.Queryer, logs []entities.Log) ([]int, error) {
+	values := make([]string, 0, len(logs))
+	args := make([]any, 0, len(logs)*logColumns)
+	for i, log := range logs {
+		n := i * logColumns
+		values = append(values, fmt.Sprintf("($%d, $%d, $%d, $%d, $%d, $%d)", n+1, n+2, n+3, n+4, n+5, n+6))
+		args = append(args, log.ExecutedCommandId, log.Seq, log.Stream, log.Level, log.Message, log.Date)
+	}
+	query := fmt.Sprintf(`INSERT INTO %s (executed_command_id, seq, stream, level, message, date)
+		VALUES %s RETURNING id`, LogsTable, strings.Join(values, ", "))
+
+	ids := make([]int, 0, len(logs))
+	err := sqlx.Select(q, &ids, query, args...)
+	return ids, err
+}
+
+func (s CommandStorage) GetLastLogSeq(executedCommandID int) (int64, error) {
+	var seq int64
+	query := fmt.Sprintf("SELECT COALESCE(MAX(seq), 0) FROM %s WHERE executed_command_id = $1", LogsTable)
+	err := s.Db.Get(&seq, query, executedCommandID)
+	return seq, err
+}
+
 func (s CommandStorage) SaveExecutedCommand(ec entities.ExecutedCommand) (int, error) {
 	var id int
-	query := fmt.Sprintf("INSERT INTO %s (command_id, PID) VALUES ($1, $2) RETURNING id", ExecutedCommandsTable)
-	row := s.Db.QueryRow(query, ec.CommandId, ec.PID)
+	query := fmt.Sprintf(`INSERT INTO %s (command_id, PID, params, status, started_at, process_key, schedule_id,
+		revision_id, triggered_by, actor) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING id`,
+		ExecutedCommandsTable)
+	row := s.Db.QueryRow(query, ec.CommandId, ec.PID, ec.Params, ec.Status, ec.StartedAt, ec.ProcessKey, ec.ScheduleId,
+		ec.RevisionId, ec.TriggeredBy, ec.Actor)
 	if err := row.Scan(&id); err != nil {
 		return 0, err
 	}
@@ -61,27 +280,208 @@ func (s CommandStorage) SaveExecutedCommand(ec entities.ExecutedCommand) (int, e
 
 func (s CommandStorage) GetActiveExecutedCommands() ([]entities.ExecutedCommand, error) {
 	var c []entities.ExecutedCommand
-	query := fmt.Sprintf("SELECT * from %s WHERE is_active = true", ExecutedCommandsTable)
+	query := fmt.Sprintf(`SELECT e.*, c.alias, c.acl FROM %s e JOIN %s c ON c.id = e.command_id
+		WHERE e.is_active = true ORDER BY e.id`, ExecutedCommandsTable, CommandTable)
 	err := s.Db.Select(&c, query)
 	return c, err
 }
 
-func (s CommandStorage) FinishCommand(commandID int) error {
-	query := fmt.Sprintf("UPDATE %s SET is_active = $1 WHERE id = $2", ExecutedCommandsTable)
-	_, err := s.Db.Exec(query, false, commandID)
+// GetExecutedCommands lists executions matching the filter, sorted by start
+// time.
+func (s CommandStorage) GetExecutedCommands(filter entities.ExecutionFilter) ([]entities.ExecutedCommand, error) {
+	query := fmt.Sprintf("SELECT e.*, c.alias, c.acl FROM %s e JOIN %s c ON c.id = e.command_id WHERE true",
+		ExecutedCommandsTable, CommandTable)
+	var args []any
+	where := func(condition string, arg any) {
+		args = append(args, arg)
+		query += fmt.Sprintf(" AND "+condition, len(args))
+	}
+	if filter.Alias != "" {
+		where("c.alias = $%d", filter.Alias)
+	}
+	if filter.CommandId != 0 {
+		where("e.command_id = $%d", filter.CommandId)
+	}
+	if filter.Status != "" {
+		where("e.status = $%d", filter.Status)
+	}
+	if filter.TriggeredBy != "" {
+		where("e.triggered_by = $%d", filter.TriggeredBy)
+	}
+	if filter.ViewableBy != nil {
+		args = viewable(&query, args, *filter.ViewableBy)
+	}
+	if !filter.From.IsZero() {
+		where("e.started_at >= $%d", filter.From)
+	}
+	if !filter.To.IsZero() {
+		where("e.started_at < $%d", filter.To)
+	}
+
+	order, op := "DESC", "<"
+	if filter.Ascending {
+		order, op = "ASC", ">"
+	}
+	if filter.After != nil {
+		args = append(args, filter.After.StartedAt, filter.After.Id)
+		query += fmt.Sprintf(" AND (e.started_at, e.id) %s ($%d, $%d)", op, len(args)-1, len(args))
+	}
+	query += fmt.Sprintf(" ORDER BY e.started_at %s, e.id %s", order, order)
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+
+	executions := []entities.ExecutedCommand{}
+	err := s.Db.Select(&executions, query, args...)
+	return executions, err
+}
+
+func (s CommandStorage) GetCommandRevision(id int) (entities.CommandRevision, error) {
+	var revision entities.CommandRevision
+	query := fmt.Sprintf("SELECT * FROM %s WHERE id = $1", CommandRevisionsTable)
+	err := s.Db.Get(&revision, query, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return revision, entities.ErrNotFound
+	}
+	return revision, err
+}
+
+func (s CommandStorage) CountLogs(executedCommandID int) (int, error) {
+	var count int
+	query := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE executed_command_id = $1", LogsTable)
+	err := s.Db.Get(&count, query, executedCommandID)
+	return count, err
+}
+
+func (s CommandStorage) StartExecutedCommand(ec entities.ExecutedCommand) error {
+	query := fmt.Sprintf(`UPDATE %s SET PID = $1, status = $2, started_at = $3, process_key = $4, workdir = $5,
+		uid = $6 WHERE id = $7`, ExecutedCommandsTable)
+	_, err := s.Db.Exec(query, ec.PID, ec.Status, ec.StartedAt, ec.ProcessKey, ec.Workdir, ec.Uid, ec.Id)
+	return err
+}
+
+func (s CommandStorage) FinishExecutedCommand(ec entities.ExecutedCommand) error {
+	query := fmt.Sprintf(`UPDATE %s SET is_active = false, status = $1, finished_at = $2, exit_code = $3, signal = $4,
+		duration_ms = $5, dropped_lines = $6, truncated = $7 WHERE id = $8`, ExecutedCommandsTable)
+	_, err := s.Db.Exec(query, ec.Status, ec.FinishedAt, ec.ExitCode, ec.Signal, ec.DurationMs, ec.DroppedLines,
+		ec.Truncated, ec.Id)
 	return err
 }
 
 func (s CommandStorage) GetExecutedCommandById(id int) (entities.ExecutedCommand, error) {
 	var c entities.ExecutedCommand
-	query := fmt.Sprintf("SELECT * from %s WHERE id = $1", ExecutedCommandsTable)
+	query := fmt.Sprintf("SELECT e.*, c.alias, c.acl FROM %s e JOIN %s c ON c.id = e.command_id WHERE e.id = $1",
+		ExecutedCommandsTable, CommandTable)
 	err := s.Db.Get(&c, query, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return c, entities.ErrNotFound
+	}
 	return c, err
 }
 
-func (s CommandStorage) GetLogsByExecutedCommand(executedCommandID int) ([]entities.Log, error) {
+func (s CommandStorage) GetLogsByExecutedCommand(executedCommandID int, filter entities.LogFilter) ([]entities.Log, error) {
 	var logs []entities.Log
-	query := fmt.Sprintf("SELECT * from %s WHERE executed_command_id = $1", LogsTable)
-	err := s.Db.Select(&logs, query, executedCommandID)
+	query := fmt.Sprintf("SELECT * from %s WHERE executed_command_id = $1 AND seq > $2", LogsTable)
+	args := []any{executedCommandID, filter.AfterSeq}
+	if filter.Stream != "" {
+		args = append(args, filter.Stream)
+		query += fmt.Sprintf(" AND stream = $%d", len(args))
+	}
+	query += " ORDER BY seq"
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+	err := s.Db.Select(&logs, query, args...)
 	return logs, err
 }
+
+// SearchLogs finds log lines matching a web search style query, newest first.
+func (s CommandStorage) SearchLogs(search entities.LogSearch) ([]entities.LogMatch, error) {
+	query := fmt.Sprintf(`SELECT l.*, c.alias, e.status,
+			ts_headline('simple', l.message, q, 'StartSel=<mark>, StopSel=</mark>, MaxFragments=2') AS snippet
+		FROM %s l JOIN %s e ON e.id = l.executed_command_id JOIN %s c ON c.id = e.command_id,
+			websearch_to_tsquery('simple', $1) q
+		WHERE to_tsvector('simple', l.message) @@ q`, LogsTable, ExecutedCommandsTable, CommandTable)
+	args := []any{search.Query}
+	where := func(condition string, arg any) {
+		args = append(args, arg)
+		query += fmt.Sprintf(" AND "+condition, len(args))
+	}
+	if search.Alias != "" {
+		where("c.alias = $%d", search.Alias)
+	}
+	if search.Stream != "" {
+		where("l.stream = $%d", search.Stream)
+	}
+	if search.Status != "" {
+		where("e.status = $%d", search.Status)
+	}
+	if search.ViewableBy != nil {
+		args = viewable(&query, args, *search.ViewableBy)
+	}
+	if !search.From.IsZero() {
+		where("l.date >= $%d", search.From)
+	}
+	if !search.To.IsZero() {
+		where("l.date < $%d", search.To)
+	}
+	query += " ORDER BY l.date DESC, l.id DESC"
+	if search.Limit > 0 {
+		args = append(args, search.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+
+	matches := []entities.LogMatch{}
+	err := s.Db.Select(&matches, query, args...)
+	return matches, err
+}
+
+func (s CommandStorage) SaveExecutionOutput(output entities.ExecutionOutput) error {
+	query := fmt.Sprintf(`INSERT INTO %s (executed_command_id, stream, blob_key, size) VALUES ($1, $2, $3, $4)`,
+		ExecutionOutputsTable)
+	_, err := s.Db.Exec(query, output.ExecutedCommandId, output.Stream, output.BlobKey, output.Size)
+	return err
+}
+
+func (s CommandStorage) GetExecutionOutput(executedCommandID int, stream entities.LogStream) (entities.ExecutionOutput, error) {
+	var output entities.ExecutionOutput
+	query := fmt.Sprintf("SELECT * FROM %s WHERE executed_command_id = $1 AND stream = $2", ExecutionOutputsTable)
+	err := s.Db.Get(&output, query, executedCommandID, stream)
+	if errors.Is(err, sql.ErrNoRows) {
+		return output, entities.ErrNotFound
+	}
+	return output, err
+}
+
+func (s CommandStorage) GetExecutionOutputs(executedCommandID int) ([]entities.ExecutionOutput, error) {
+	var outputs []entities.ExecutionOutput
+	query := fmt.Sprintf("SELECT * FROM %s WHERE executed_command_id = $1 ORDER BY stream", ExecutionOutputsTable)
+	err := s.Db.Select(&outputs, query, executedCommandID)
+	return outputs, err
+}
+
+func (s CommandStorage) SaveArtifact(artifact entities.Artifact) error {
+	query := fmt.Sprintf(`INSERT INTO %s (executed_command_id, path, blob_key, size) VALUES ($1, $2, $3, $4)`,
+		ArtifactsTable)
+	_, err := s.Db.Exec(query, artifact.ExecutedCommandId, artifact.Path, artifact.BlobKey, artifact.Size)
+	return err
+}
+
+func (s CommandStorage) GetArtifacts(executedCommandID int) ([]entities.Artifact, error) {
+	artifacts := []entities.Artifact{}
+	query := fmt.Sprintf("SELECT * FROM %s WHERE executed_command_id = $1 ORDER BY path", ArtifactsTable)
+	err := s.Db.Select(&artifacts, query, executedCommandID)
+	return artifacts, err
+}
+
+func (s CommandStorage) GetArtifact(executedCommandID int, path string) (entities.Artifact, error) {
+	var artifact entities.Artifact
+	query := fmt.Sprintf("SELECT * FROM %s WHERE executed_command_id = $1 AND path = $2", ArtifactsTable)
+	err := s.Db.Get(&artifact, query, executedCommandID, path)
+	if errors.Is(err, sql.ErrNoRows) {
+		return artifact, entities.ErrNotFound
+	}
+	return artifact, err
+}
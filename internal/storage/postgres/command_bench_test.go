@@ -0,0 +1,105 @@
+//go:build integration
+
+package postgres
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"testex/internal/config"
+	"testex/internal/entities"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// benchDB connects to the Postgres given by the libpq PG* variables, with the
+// defaults of config/config.yaml:
+//
+//	PGHOST=localhost go test -tags integration -run '^$' -bench . ./internal/storage/postgres
+func benchDB(b *testing.B) *sqlx.DB {
+	cfg := config.PostgresDatabase{Host: "localhost", Port: 5432, Database: "postgres", Username: "postgres",
+		Password: "qwerty"}
+	for name, dst := range map[string]*string{"PGHOST": &cfg.Host, "PGDATABASE": &cfg.Database,
+		"PGUSER": &cfg.Username, "PGPASSWORD": &cfg.Password} {
+		if value := os.Getenv(name); value != "" {
+			*dst = value
+		}
+	}
+	if value := os.Getenv("PGPORT"); value != "" {
+		port, err := strconv.Atoi(value)
+		if err != nil {
+			b.Fatalf("wrong PGPORT: %v", err)
+		}
+		cfg.Port = port
+	}
+	db, err := New(cfg)
+	if err != nil {
+		b.Skipf("postgres is not available: %v", err)
+	}
+	b.Cleanup(func() { db.Close() })
+	return db
+}
+
+// benchExecution creates an execution to attach logs to, it is deleted with
+// its logs after the benchmark.
+func benchExecution(b *testing.B, s *CommandStorage) int {
+	const alias = "bench-save-logs"
+	command, err := s.GetCommand(alias)
+	if errors.Is(err, entities.ErrNotFound) {
+		command.Id, err = s.SaveCommand(entities.Command{Alias: alias, Script: "true"})
+	}
+	if err != nil {
+		b.Fatal(err)
+	}
+	id, err := s.SaveExecutedCommand(entities.ExecutedCommand{CommandId: command.Id, Status: entities.StatusRunning,
+		StartedAt: time.Now(), TriggeredBy: entities.TriggeredByApi})
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() {
+		s.Db.Exec(fmt.Sprintf("DELETE FROM %s WHERE executed_command_id = $1", LogsTable), id)
+		s.Db.Exec(fmt.Sprintf("DELETE FROM %s WHERE id = $1", ExecutedCommandsTable), id)
+	})
+	return id
+}
+
+func benchLogs(id, n int) []entities.Log {
+	logs := make([]entities.Log, n)
+	for i := range logs {
+		logs[i] = entities.Log{ExecutedCommandId: id, Seq: int64(i + 1), Stream: entities.StreamStdout,
+			Level: entities.LevelInfo, Message: fmt.Sprintf("line %d of the build output", i+1), Date: time.Now()}
+	}
+	return logs
+}
+
+// BenchmarkSaveLog is the path before batching: one INSERT per line.
+func BenchmarkSaveLog(b *testing.B) {
+	s := NewCommandStorage(benchDB(b))
+	logs := benchLogs(benchExecution(b, s), b.N)
+	b.ResetTimer()
+	for _, log := range logs {
+		if _, err := s.SaveLog(log); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkSaveLogs stores the same lines in batches of the given size, the
+// largest is split into several inserts. ns/op is per line.
+func BenchmarkSaveLogs(b *testing.B) {
+	for _, size := range []int{10, 100, 500, maxLogRows + 1} {
+		b.Run(strconv.Itoa(size), func(b *testing.B) {
+			s := NewCommandStorage(benchDB(b))
+			logs := benchLogs(benchExecution(b, s), b.N)
+			b.ResetTimer()
+			for start := 0; start < len(logs); start += size {
+				if _, err := s.SaveLogs(logs[start:min(start+size, len(logs))]); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"testex/internal/entities"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type PipelineStorage struct {
+	Db *sqlx.DB
+}
+
+func NewPipelineStorage(db *sqlx.DB) *PipelineStorage {
+	return &PipelineStorage{db}
+}
+
+func (s PipelineStorage) SavePipeline(pipeline entities.Pipeline) (int, error) {
+	var id int
+	query := fmt.Sprintf("INSERT INTO %s (name, steps) VALUES ($1, $2) RETURNING id", PipelinesTable)
+	if err := s.Db.QueryRow(query, pipeline.Name, pipeline.Steps).Scan(&id); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (s PipelineStorage) GetPipeline(name string) (entities.Pipeline, error) {
+	var pipeline entities.Pipeline
+	query := fmt.Sprintf("SELECT * FROM %s WHERE name = $1", PipelinesTable)
+	err := s.Db.Get(&pipeline, query, name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return pipeline, entities.ErrNotFound
+	}
+	return pipeline, err
+}
+
+func (s PipelineStorage) GetAllPipelines() ([]entities.Pipeline, error) {
+	var pipelines []entities.Pipeline
+	query := fmt.Sprintf("SELECT * FROM %s ORDER BY id", PipelinesTable)
+	err := s.Db.Select(&pipelines, query)
+	return pipelines, err
+}
+
+// SavePipelineRun stores a run together with its steps and returns it with
+// the ids filled in.
+func (s PipelineStorage) SavePipelineRun(run entities.PipelineRun) (entities.PipelineRun, error) {
+	tx, err := s.Db.Beginx()
+	if err != nil {
+		return run, err
+	}
+	defer tx.Rollback()
+
+	query := fmt.Sprintf("INSERT INTO %s (pipeline_id, status, started_at) VALUES ($1, $2, $3) RETURNING id",
+		PipelineRunsTable)
+	if err = tx.QueryRow(query, run.PipelineId, run.Status, run.StartedAt).Scan(&run.Id); err != nil {
+		return run, err
+	}
+	query = fmt.Sprintf("INSERT INTO %s (run_id, name, alias, status) VALUES ($1, $2, $3, $4) RETURNING id",
+		PipelineRunStepsTable)
+	for i := range run.Steps {
+		step := &run.Steps[i]
+		step.RunId = run.Id
+		if err = tx.QueryRow(query, step.RunId, step.Name, step.Alias, step.Status).Scan(&step.Id); err != nil {
+			return run, err
+		}
+	}
+	return run, tx.Commit()
+}
+
+func (s PipelineStorage) GetPipelineRun(id int) (entities.PipelineRun, error) {
+	var run entities.PipelineRun
+	query := fmt.Sprintf("SELECT * FROM %s WHERE id = $1", PipelineRunsTable)
+	err := s.Db.Get(&run, query, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return run, entities.ErrNotFound
+	}
+	if err != nil {
+		return run, err
+	}
+	query = fmt.Sprintf("SELECT * FROM %s WHERE run_id = $1 ORDER BY id", PipelineRunStepsTable)
+	err = s.Db.Select(&run.Steps, query, id)
+	return run, err
+}
+
+func (s PipelineStorage) UpdatePipelineRunStep(step entities.PipelineRunStep) error {
+	query := fmt.Sprintf(`UPDATE %s SET status = $1, executed_command_id = $2, error = $3, started_at = $4,
+		finished_at = $5 WHERE id = $6`, PipelineRunStepsTable)
+	_, err := s.Db.Exec(query, step.Status, step.ExecutedCommandId, step.Error, step.StartedAt, step.FinishedAt, step.Id)
+	return err
+}
+
+func (s PipelineStorage) FinishPipelineRun(run entities.PipelineRun) error {
+	query := fmt.Sprintf("UPDATE %s SET status = $1, finished_at = $2 WHERE id = $3", PipelineRunsTable)
+	_, err := s.Db.Exec(query, run.Status, run.FinishedAt, run.Id)
+	return err
+}
+
+// AbandonPipelineRuns marks runs interrupted by a restart as lost. Steps that
+// haven't started are skipped and running ones fail, their executions are
+// settled on their own.
+func (s PipelineStorage) AbandonPipelineRuns() error {
+	tx, err := s.Db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := fmt.Sprintf(`UPDATE %s SET status = $1, error = $2, finished_at = CURRENT_TIMESTAMP
+		WHERE status = $3 AND run_id IN (SELECT id FROM %s WHERE status = $4)`, PipelineRunStepsTable, PipelineRunsTable)
+	steps := []struct {
+		from, to entities.StepStatus
+		error    string
+	}{
+		{entities.StepPending, entities.StepSkipped, ""},
+		{entities.StepRunning, entities.StepFailed, "interrupted by a server restart"},
+	}
+	for _, step := range steps {
+		if _, err = tx.Exec(query, step.to, step.error, step.from, entities.PipelineRunning); err != nil {
+			return err
+		}
+	}
+	query = fmt.Sprintf("UPDATE %s SET status = $1, finished_at = CURRENT_TIMESTAMP WHERE status = $2", PipelineRunsTable)
+	if _, err = tx.Exec(query, entities.PipelineLost, entities.PipelineRunning); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
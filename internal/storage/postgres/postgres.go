@@ -12,8 +12,260 @@ const (
 	CommandTable          = "commands"
 	ExecutedCommandsTable = "executed_commands"
 	LogsTable             = "logs"
+	SchedulesTable        = "schedules"
+	PipelinesTable        = "pipelines"
+	PipelineRunsTable     = "pipeline_runs"
+	PipelineRunStepsTable = "pipeline_run_steps"
+	CommandRevisionsTable = "command_revisions"
+	ExecutionOutputsTable = "execution_outputs"
+	ArtifactsTable        = "artifacts"
+	SecretsTable          = "secrets"
+	ApiKeysTable          = "api_keys"
+	AuditEventsTable      = "audit_events"
+	BlobDeletionsTable    = "blob_deletions"
 )
 
+// schema is applied in order on every start, so each statement must be
+// idempotent. Backfills of large tables run in one block with the column they
+// fill, so they only run when it is added.
+var schema = []string{
+	`CREATE TABLE IF NOT EXISTS commands(
+		id SERIAL PRIMARY KEY,
+		alias varchar(128) UNIQUE,
+		script varchar(256)
+	);`,
+	`CREATE TABLE IF NOT EXISTS executed_commands(
+		id SERIAL PRIMARY KEY,
+		command_id INT REFERENCES commands,
+		PID INT NOT NULL,
+		is_active BOOLEAN DEFAULT true
+	);`,
+	`CREATE TABLE IF NOT EXISTS logs(
+		id SERIAL PRIMARY KEY,
+		executed_command_id INT REFERENCES executed_commands,
+		date TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		message TEXT
+	);`,
+	`ALTER TABLE commands ADD COLUMN IF NOT EXISTS params JSONB;`,
+	`ALTER TABLE executed_commands ADD COLUMN IF NOT EXISTS params JSONB;`,
+	// Rows finished before statuses were tracked have no known outcome.
+	`DO $$ BEGIN
+		IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_schema = current_schema()
+			AND table_name = 'executed_commands' AND column_name = 'status') THEN
+			ALTER TABLE executed_commands
+				ADD COLUMN status varchar(16) NOT NULL DEFAULT 'running',
+				ADD COLUMN IF NOT EXISTS started_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				ADD COLUMN IF NOT EXISTS finished_at TIMESTAMPTZ,
+				ADD COLUMN IF NOT EXISTS exit_code INT,
+				ADD COLUMN IF NOT EXISTS signal varchar(16);
+			UPDATE executed_commands SET status = 'lost' WHERE is_active = false;
+		END IF;
+	END $$;`,
+	`ALTER TABLE commands ADD COLUMN IF NOT EXISTS timeout INT NOT NULL DEFAULT 0;`,
+	`ALTER TABLE executed_commands ADD COLUMN IF NOT EXISTS duration_ms BIGINT;`,
+	`ALTER TABLE commands
+		ADD COLUMN IF NOT EXISTS stop_signal varchar(16) NOT NULL DEFAULT '',
+		ADD COLUMN IF NOT EXISTS grace_period INT NOT NULL DEFAULT 0;`,
+	// Lines written before logs were structured keep their order and stream.
+	`DO $$ BEGIN
+		IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_schema = current_schema()
+			AND table_name = 'logs' AND column_name = 'seq') THEN
+			ALTER TABLE logs
+				ADD COLUMN seq BIGINT NOT NULL DEFAULT 0,
+				ADD COLUMN IF NOT EXISTS stream varchar(8) NOT NULL DEFAULT '',
+				ADD COLUMN IF NOT EXISTS level varchar(8) NOT NULL DEFAULT 'info';
+			UPDATE logs SET seq = id,
+				stream = CASE WHEN message LIKE '%- STDERR]%' THEN 'stderr' ELSE 'stdout' END,
+				level = CASE WHEN message LIKE '%- STDERR]%' THEN 'error' ELSE 'info' END;
+		END IF;
+	END $$;`,
+	`CREATE INDEX IF NOT EXISTS logs_executed_command_id_seq_idx ON logs (executed_command_id, seq);`,
+	`ALTER TABLE executed_commands ADD COLUMN IF NOT EXISTS dropped_lines INT NOT NULL DEFAULT 0;`,
+	`ALTER TABLE executed_commands ADD COLUMN IF NOT EXISTS process_key varchar(64) NOT NULL DEFAULT '';`,
+	`ALTER TABLE commands
+		ADD COLUMN IF NOT EXISTS max_concurrency INT NOT NULL DEFAULT 0,
+		ADD COLUMN IF NOT EXISTS overflow varchar(8) NOT NULL DEFAULT 'queue';`,
+	`CREATE TABLE IF NOT EXISTS schedules(
+		id SERIAL PRIMARY KEY,
+		alias varchar(128) NOT NULL REFERENCES commands(alias) ON UPDATE CASCADE,
+		cron varchar(128) NOT NULL,
+		timezone varchar(64) NOT NULL DEFAULT 'UTC',
+		enabled BOOLEAN NOT NULL DEFAULT true,
+		params JSONB,
+		skip_if_running BOOLEAN NOT NULL DEFAULT false,
+		catch_up BOOLEAN NOT NULL DEFAULT false,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		last_run_at TIMESTAMPTZ,
+		last_execution_id INT
+	);`,
+	`ALTER TABLE executed_commands
+		ADD COLUMN IF NOT EXISTS schedule_id INT REFERENCES schedules ON DELETE SET NULL;`,
+	`CREATE TABLE IF NOT EXISTS pipelines(
+		id SERIAL PRIMARY KEY,
+		name varchar(128) NOT NULL UNIQUE,
+		steps JSONB NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);`,
+	`CREATE TABLE IF NOT EXISTS pipeline_runs(
+		id SERIAL PRIMARY KEY,
+		pipeline_id INT NOT NULL REFERENCES pipelines,
+		status varchar(16) NOT NULL,
+		started_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		finished_at TIMESTAMPTZ
+	);`,
+	`CREATE TABLE IF NOT EXISTS pipeline_run_steps(
+		id SERIAL PRIMARY KEY,
+		run_id INT NOT NULL REFERENCES pipeline_runs,
+		name varchar(128) NOT NULL,
+		alias varchar(128) NOT NULL,
+		status varchar(16) NOT NULL,
+		executed_command_id INT REFERENCES executed_commands,
+		error TEXT NOT NULL DEFAULT '',
+		started_at TIMESTAMPTZ,
+		finished_at TIMESTAMPTZ
+	);`,
+	`ALTER TABLE commands
+		ADD COLUMN IF NOT EXISTS version INT NOT NULL DEFAULT 1,
+		ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;`,
+	`CREATE TABLE IF NOT EXISTS command_revisions(
+		id SERIAL PRIMARY KEY,
+		command_id INT NOT NULL REFERENCES commands,
+		version INT NOT NULL,
+		script TEXT NOT NULL,
+		params JSONB,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE (command_id, version)
+	);`,
+	// Commands created before revisions were kept get their first one.
+	`INSERT INTO command_revisions (command_id, version, script, params)
+		SELECT id, version, script, params FROM commands c
+		WHERE NOT EXISTS (SELECT 1 FROM command_revisions r WHERE r.command_id = c.id);`,
+	`ALTER TABLE commands ADD COLUMN IF NOT EXISTS revision_id INT REFERENCES command_revisions;`,
+	`UPDATE commands c SET revision_id = r.id FROM command_revisions r
+		WHERE c.revision_id IS NULL AND r.command_id = c.id AND r.version = c.version;`,
+	`ALTER TABLE executed_commands ADD COLUMN IF NOT EXISTS revision_id INT REFERENCES command_revisions;`,
+	// Aliases of deleted commands can be taken again, so they are unique
+	// among live commands only.
+	`ALTER TABLE schedules DROP CONSTRAINT IF EXISTS schedules_alias_fkey;`,
+	`ALTER TABLE commands DROP CONSTRAINT IF EXISTS commands_alias_key;`,
+	`CREATE UNIQUE INDEX IF NOT EXISTS commands_alias_idx ON commands (alias) WHERE deleted_at IS NULL;`,
+	`DO $$ BEGIN
+		IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_schema = current_schema()
+			AND table_name = 'executed_commands' AND column_name = 'triggered_by') THEN
+			ALTER TABLE executed_commands ADD COLUMN triggered_by varchar(16) NOT NULL DEFAULT 'api';
+			UPDATE executed_commands SET triggered_by = 'schedule' WHERE schedule_id IS NOT NULL;
+		END IF;
+	END $$;`,
+	`CREATE INDEX IF NOT EXISTS executed_commands_started_at_idx ON executed_commands (started_at, id);`,
+	`CREATE INDEX IF NOT EXISTS executed_commands_command_id_idx ON executed_commands (command_id, started_at);`,
+	// Output is not natural language, so words are indexed without stemming.
+	`CREATE INDEX IF NOT EXISTS logs_message_search_idx ON logs USING GIN (to_tsvector('simple', message));`,
+	`CREATE INDEX IF NOT EXISTS logs_date_idx ON logs (date);`,
+	`ALTER TABLE commands
+		ADD COLUMN IF NOT EXISTS retention_days INT NOT NULL DEFAULT 0,
+		ADD COLUMN IF NOT EXISTS keep_executions INT NOT NULL DEFAULT 0,
+		ADD COLUMN IF NOT EXISTS max_log_bytes BIGINT NOT NULL DEFAULT 0;`,
+	`ALTER TABLE executed_commands
+		ADD COLUMN IF NOT EXISTS trimmed_lines INT NOT NULL DEFAULT 0,
+		ADD COLUMN IF NOT EXISTS logs_compacted BOOLEAN NOT NULL DEFAULT false;`,
+	`CREATE INDEX IF NOT EXISTS executed_commands_uncompacted_idx ON executed_commands (id)
+		WHERE logs_compacted = false AND is_active = false;`,
+	// Steps of pipeline runs outlive the executions pruned by retention. The
+	// key is replaced once, adding it again would validate the whole table.
+	`DO $$ BEGIN
+		IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conrelid = 'pipeline_run_steps'::regclass
+			AND conname = 'pipeline_run_steps_executed_command_id_fkey' AND confdeltype = 'n') THEN
+			ALTER TABLE pipeline_run_steps DROP CONSTRAINT IF EXISTS pipeline_run_steps_executed_command_id_fkey;
+			ALTER TABLE pipeline_run_steps ADD CONSTRAINT pipeline_run_steps_executed_command_id_fkey
+				FOREIGN KEY (executed_command_id) REFERENCES executed_commands ON DELETE SET NULL;
+		END IF;
+	END $$;`,
+	`ALTER TABLE commands
+		ADD COLUMN IF NOT EXISTS max_line_length INT NOT NULL DEFAULT 0,
+		ADD COLUMN IF NOT EXISTS max_output_bytes BIGINT NOT NULL DEFAULT 0,
+		ADD COLUMN IF NOT EXISTS max_output_lines INT NOT NULL DEFAULT 0;`,
+	`ALTER TABLE executed_commands ADD COLUMN IF NOT EXISTS truncated BOOLEAN NOT NULL DEFAULT false;`,
+	`ALTER TABLE commands ADD COLUMN IF NOT EXISTS output_mode varchar(8) NOT NULL DEFAULT 'lines';`,
+	`CREATE TABLE IF NOT EXISTS execution_outputs(
+		executed_command_id INT NOT NULL REFERENCES executed_commands ON DELETE CASCADE,
+		stream varchar(8) NOT NULL,
+		blob_key varchar(64) NOT NULL,
+		size BIGINT NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (executed_command_id, stream)
+	);`,
+	`CREATE INDEX IF NOT EXISTS execution_outputs_blob_key_idx ON execution_outputs (blob_key);`,
+	`ALTER TABLE commands ADD COLUMN IF NOT EXISTS artifacts JSONB;`,
+	`CREATE TABLE IF NOT EXISTS artifacts(
+		id SERIAL PRIMARY KEY,
+		executed_command_id INT NOT NULL REFERENCES executed_commands ON DELETE CASCADE,
+		path TEXT NOT NULL,
+		blob_key varchar(64) NOT NULL,
+		size BIGINT NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE (executed_command_id, path)
+	);`,
+	`CREATE INDEX IF NOT EXISTS artifacts_blob_key_idx ON artifacts (blob_key);`,
+	`ALTER TABLE commands ADD COLUMN IF NOT EXISTS workdir_mode varchar(16) NOT NULL DEFAULT 'temp';`,
+	`ALTER TABLE commands ADD COLUMN IF NOT EXISTS workdir TEXT NOT NULL DEFAULT '';`,
+	`ALTER TABLE executed_commands ADD COLUMN IF NOT EXISTS workdir TEXT NOT NULL DEFAULT '';`,
+	`ALTER TABLE commands ADD COLUMN IF NOT EXISTS env JSONB;`,
+	`ALTER TABLE commands ADD COLUMN IF NOT EXISTS env_inherit varchar(16) NOT NULL DEFAULT 'allowlist';`,
+	`CREATE TABLE IF NOT EXISTS secrets(
+		id SERIAL PRIMARY KEY,
+		name varchar(255) NOT NULL UNIQUE,
+		value BYTEA NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);`,
+	`ALTER TABLE commands ADD COLUMN IF NOT EXISTS secrets JSONB;`,
+	`CREATE TABLE IF NOT EXISTS api_keys(
+		id SERIAL PRIMARY KEY,
+		name varchar(255) NOT NULL,
+		role varchar(16) NOT NULL,
+		prefix varchar(16) NOT NULL,
+		hash varchar(64) NOT NULL UNIQUE,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);`,
+	`ALTER TABLE commands ADD COLUMN IF NOT EXISTS acl JSONB;`,
+	`ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS groups JSONB;`,
+	`CREATE TABLE IF NOT EXISTS audit_events(
+		id BIGSERIAL PRIMARY KEY,
+		at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		actor varchar(255) NOT NULL,
+		role varchar(16) NOT NULL DEFAULT '',
+		source_ip varchar(64) NOT NULL DEFAULT '',
+		action varchar(64) NOT NULL,
+		target TEXT NOT NULL,
+		payload_hash varchar(64) NOT NULL DEFAULT '',
+		outcome varchar(16) NOT NULL,
+		error TEXT NOT NULL DEFAULT ''
+	);`,
+	`CREATE INDEX IF NOT EXISTS audit_events_at_idx ON audit_events (at);`,
+	`CREATE INDEX IF NOT EXISTS audit_events_actor_idx ON audit_events (actor, id);`,
+	`CREATE INDEX IF NOT EXISTS audit_events_target_idx ON audit_events (target, id);`,
+	// The audit trail is append-only, even for the application itself.
+	`CREATE OR REPLACE FUNCTION audit_events_immutable() RETURNS trigger AS $$
+	BEGIN
+		RAISE EXCEPTION 'audit events can not be changed or deleted';
+	END;
+	$$ LANGUAGE plpgsql;`,
+	`DROP TRIGGER IF EXISTS audit_events_immutable ON audit_events;`,
+	`CREATE TRIGGER audit_events_immutable BEFORE UPDATE OR DELETE ON audit_events
+		FOR EACH ROW EXECUTE FUNCTION audit_events_immutable();`,
+	`DROP TRIGGER IF EXISTS audit_events_no_truncate ON audit_events;`,
+	`CREATE TRIGGER audit_events_no_truncate BEFORE TRUNCATE ON audit_events
+		FOR EACH STATEMENT EXECUTE FUNCTION audit_events_immutable();`,
+	`ALTER TABLE executed_commands ADD COLUMN IF NOT EXISTS actor varchar(255);`,
+	`ALTER TABLE commands ADD COLUMN IF NOT EXISTS run_as varchar(255) NOT NULL DEFAULT '';`,
+	`ALTER TABLE executed_commands ADD COLUMN IF NOT EXISTS uid INTEGER;`,
+	// Blobs no execution refers to are marked and deleted by a later sweep.
+	`CREATE TABLE IF NOT EXISTS blob_deletions(
+		blob_key varchar(64) PRIMARY KEY,
+		marked_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);`,
+}
+
 func New(cfg config.PostgresDatabase) (*sqlx.DB, error) {
 	const fn = "storage.postgres.New"
 
@@ -27,54 +279,10 @@ func New(cfg config.PostgresDatabase) (*sqlx.DB, error) {
 		return nil, fmt.Errorf("%s: %w", fn, err)
 	}
 
-	stmt, err := db.Prepare(`
-		CREATE TABLE IF NOT EXISTS commands(
-			id SERIAL PRIMARY KEY,
-			alias varchar(128) UNIQUE,
-		    script varchar(256)
-		);
-	`)
-
-	if err != nil {
-		return nil, fmt.Errorf("%s: %w", fn, err)
-	}
-	_, err = stmt.Exec()
-	if err != nil {
-		return nil, fmt.Errorf("%s: %w", fn, err)
-	}
-
-	stmt, err = db.Prepare(`
-		CREATE TABLE IF NOT EXISTS executed_commands(
-			id SERIAL PRIMARY KEY,
-			command_id INT REFERENCES commands,
-			PID INT NOT NULL,
-			is_active BOOLEAN DEFAULT true
-		);
-	`)
-
-	if err != nil {
-		return nil, fmt.Errorf("%s: %w", fn, err)
-	}
-	_, err = stmt.Exec()
-	if err != nil {
-		return nil, fmt.Errorf("%s: %w", fn, err)
-	}
-
-	stmt, err = db.Prepare(`
-		CREATE TABLE IF NOT EXISTS logs(
-			id SERIAL PRIMARY KEY,
-			executed_command_id INT REFERENCES executed_commands,
-			date TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
-			message TEXT
-		);
-	`)
-
-	if err != nil {
-		return nil, fmt.Errorf("%s: %w", fn, err)
-	}
-	_, err = stmt.Exec()
-	if err != nil {
-		return nil, fmt.Errorf("%s: %w", fn, err)
+	for _, query := range schema {
+		if _, err = db.Exec(query); err != nil {
+			return nil, fmt.Errorf("%s: %w", fn, err)
+		}
 	}
 
 	return db, nil
@@ -0,0 +1,146 @@
+package postgres
+
+import (
+	"fmt"
+	"testex/internal/entities"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type RetentionStorage struct {
+	Db *sqlx.DB
+}
+
+func NewRetentionStorage(db *sqlx.DB) *RetentionStorage {
+	return &RetentionStorage{db}
+}
+
+// GetExpiredExecutions returns up to limit finished executions that are older
+// than the max age or beyond the number of executions kept for their command.
+// Limits of the command take precedence over the policy.
+func (s RetentionStorage) GetExpiredExecutions(policy entities.RetentionPolicy, limit int) ([]int, error) {
+	query := fmt.Sprintf(`SELECT id FROM (
+			SELECT e.id, e.is_active, COALESCE(e.finished_at, e.started_at) AS finished_at,
+				COALESCE(NULLIF(c.retention_days, 0) * 86400, $1) AS max_age,
+				COALESCE(NULLIF(c.keep_executions, 0), $2) AS keep,
+				ROW_NUMBER() OVER (PARTITION BY e.command_id ORDER BY e.started_at DESC, e.id DESC) AS n
+			FROM %s e JOIN %s c ON c.id = e.command_id
+		) t
+		WHERE is_active = false AND (
+			(max_age > 0 AND finished_at < now() - max_age * interval '1 second') OR (keep > 0 AND n > keep))
+		ORDER BY id LIMIT $3`, ExecutedCommandsTable, CommandTable)
+	ids := []int{}
+	err := s.Db.Select(&ids, query, int64(policy.MaxAge.Seconds()), policy.MaxExecutions, limit)
+	return ids, err
+}
+
+// DeleteExecutionLogs deletes up to limit log lines of the executions and
+// returns the number of deleted lines.
+func (s RetentionStorage) DeleteExecutionLogs(ids []int, limit int) (int64, error) {
+	query := fmt.Sprintf(`DELETE FROM %s WHERE id IN (
+		SELECT id FROM %s WHERE executed_command_id = ANY($1) LIMIT $2)`, LogsTable, LogsTable)
+	res, err := s.Db.Exec(query, pq.Array(ids), limit)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// DeleteExecutions deletes executions whose logs are already deleted. Keys of
+// raw outputs and artifacts that no execution refers to anymore are marked
+// for deletion, the blobs are removed later by a sweep.
+func (s RetentionStorage) DeleteExecutions(ids []int) error {
+	tx, err := s.Db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := fmt.Sprintf("UPDATE %s SET last_execution_id = NULL WHERE last_execution_id = ANY($1)", SchedulesTable)
+	if _, err = tx.Exec(query, pq.Array(ids)); err != nil {
+		return err
+	}
+	var keys []string
+	for _, table := range []string{ExecutionOutputsTable, ArtifactsTable} {
+		var deleted []string
+		query = fmt.Sprintf("DELETE FROM %s WHERE executed_command_id = ANY($1) RETURNING blob_key", table)
+		if err = tx.Select(&deleted, query, pq.Array(ids)); err != nil {
+			return err
+		}
+		keys = append(keys, deleted...)
+	}
+	query = fmt.Sprintf("DELETE FROM %s WHERE id = ANY($1) AND is_active = false", ExecutedCommandsTable)
+	if _, err = tx.Exec(query, pq.Array(ids)); err != nil {
+		return err
+	}
+
+	if len(keys) > 0 {
+		query = fmt.Sprintf(`INSERT INTO %s (blob_key) SELECT DISTINCT k FROM unnest($1::text[]) k
+			WHERE NOT EXISTS (SELECT 1 FROM %s o WHERE o.blob_key = k)
+				AND NOT EXISTS (SELECT 1 FROM %s a WHERE a.blob_key = k)
+			ON CONFLICT (blob_key) DO NOTHING`, BlobDeletionsTable, ExecutionOutputsTable, ArtifactsTable)
+		if _, err = tx.Exec(query, pq.Array(keys)); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetOrphanedBlobs returns up to limit keys after the given one that are
+// marked for deletion and still not referred to. A key referred to again
+// stays marked, it is checked anew once it is orphaned again.
+func (s RetentionStorage) GetOrphanedBlobs(after string, limit int) ([]string, error) {
+	query := fmt.Sprintf(`SELECT d.blob_key FROM %s d
+		WHERE d.blob_key > $1
+			AND NOT EXISTS (SELECT 1 FROM %s o WHERE o.blob_key = d.blob_key)
+			AND NOT EXISTS (SELECT 1 FROM %s a WHERE a.blob_key = d.blob_key)
+		ORDER BY d.blob_key LIMIT $2`, BlobDeletionsTable, ExecutionOutputsTable, ArtifactsTable)
+	keys := []string{}
+	err := s.Db.Select(&keys, query, after, limit)
+	return keys, err
+}
+
+// UnmarkBlobs forgets keys whose blobs are deleted.
+func (s RetentionStorage) UnmarkBlobs(keys []string) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE blob_key = ANY($1)", BlobDeletionsTable)
+	_, err := s.Db.Exec(query, pq.Array(keys))
+	return err
+}
+
+// GetUncompactedExecutions returns up to limit finished executions whose logs
+// haven't been checked against the size limit, with the limit in effect.
+func (s RetentionStorage) GetUncompactedExecutions(maxLogBytes int64, limit int) ([]entities.LogCompaction, error) {
+	query := fmt.Sprintf(`SELECT e.id, COALESCE(NULLIF(c.max_log_bytes, 0), $1) AS max_log_bytes
+		FROM %s e JOIN %s c ON c.id = e.command_id
+		WHERE e.logs_compacted = false AND e.is_active = false ORDER BY e.id LIMIT $2`,
+		ExecutedCommandsTable, CommandTable)
+	compactions := []entities.LogCompaction{}
+	err := s.Db.Select(&compactions, query, maxLogBytes, limit)
+	return compactions, err
+}
+
+// TrimLogs deletes up to limit of the earliest log lines of an execution that
+// don't fit into maxBytes, counting from the latest line. It returns the
+// number of deleted lines.
+func (s RetentionStorage) TrimLogs(executedCommandID int, maxBytes int64, limit int) (int64, error) {
+	query := fmt.Sprintf(`DELETE FROM %s WHERE id IN (
+		SELECT id FROM (
+			SELECT id, SUM(octet_length(message)) OVER (ORDER BY seq DESC) AS total
+			FROM %s WHERE executed_command_id = $1
+		) t WHERE total > $2 LIMIT $3)`, LogsTable, LogsTable)
+	res, err := s.Db.Exec(query, executedCommandID, maxBytes, limit)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// FinishCompaction marks the logs of an execution as checked and adds the
+// number of trimmed lines.
+func (s RetentionStorage) FinishCompaction(executedCommandID int, trimmed int64) error {
+	query := fmt.Sprintf(`UPDATE %s SET logs_compacted = true, trimmed_lines = trimmed_lines + $1 WHERE id = $2`,
+		ExecutedCommandsTable)
+	_, err := s.Db.Exec(query, trimmed, executedCommandID)
+	return err
+}
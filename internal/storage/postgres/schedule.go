@@ -0,0 +1,87 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"testex/internal/entities"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type ScheduleStorage struct {
+	Db *sqlx.DB
+}
+
+func NewScheduleStorage(db *sqlx.DB) *ScheduleStorage {
+	return &ScheduleStorage{db}
+}
+
+func (s ScheduleStorage) SaveSchedule(schedule entities.Schedule) (int, error) {
+	var id int
+	query := fmt.Sprintf(`INSERT INTO %s (alias, cron, timezone, enabled, params, skip_if_running, catch_up)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`, SchedulesTable)
+	row := s.Db.QueryRow(query, schedule.Alias, schedule.Cron, schedule.Timezone, schedule.Enabled,
+		schedule.Params, schedule.SkipIfRunning, schedule.CatchUp)
+	if err := row.Scan(&id); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (s ScheduleStorage) GetSchedule(id int) (entities.Schedule, error) {
+	var schedule entities.Schedule
+	query := fmt.Sprintf("SELECT * FROM %s WHERE id = $1", SchedulesTable)
+	err := s.Db.Get(&schedule, query, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return schedule, entities.ErrNotFound
+	}
+	return schedule, err
+}
+
+func (s ScheduleStorage) GetAllSchedules() ([]entities.Schedule, error) {
+	var schedules []entities.Schedule
+	query := fmt.Sprintf("SELECT * FROM %s ORDER BY id", SchedulesTable)
+	err := s.Db.Select(&schedules, query)
+	return schedules, err
+}
+
+func (s ScheduleStorage) UpdateSchedule(schedule entities.Schedule) error {
+	query := fmt.Sprintf(`UPDATE %s SET alias = $1, cron = $2, timezone = $3, enabled = $4, params = $5,
+		skip_if_running = $6, catch_up = $7 WHERE id = $8`, SchedulesTable)
+	res, err := s.Db.Exec(query, schedule.Alias, schedule.Cron, schedule.Timezone, schedule.Enabled,
+		schedule.Params, schedule.SkipIfRunning, schedule.CatchUp, schedule.Id)
+	if err != nil {
+		return err
+	}
+	return expectRow(res)
+}
+
+func (s ScheduleStorage) DeleteSchedule(id int) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE id = $1", SchedulesTable)
+	res, err := s.Db.Exec(query, id)
+	if err != nil {
+		return err
+	}
+	return expectRow(res)
+}
+
+func (s ScheduleStorage) SaveScheduleRun(id int, at time.Time, executionId *int) error {
+	query := fmt.Sprintf(`UPDATE %s SET last_run_at = $1, last_execution_id = COALESCE($2, last_execution_id)
+		WHERE id = $3`, SchedulesTable)
+	_, err := s.Db.Exec(query, at, executionId, id)
+	return err
+}
+
+// expectRow turns an update of no rows into entities.ErrNotFound.
+func expectRow(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return entities.ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package postgres
+
+import (
+	"fmt"
+	"testex/internal/entities"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type SecretStorage struct {
+	Db *sqlx.DB
+}
+
+func NewSecretStorage(db *sqlx.DB) *SecretStorage {
+	return &SecretStorage{db}
+}
+
+// SaveSecret creates a secret or replaces the value of an existing one.
+func (s SecretStorage) SaveSecret(secret entities.Secret) error {
+	query := fmt.Sprintf(`INSERT INTO %s (name, value) VALUES ($1, $2)
+		ON CONFLICT (name) DO UPDATE SET value = EXCLUDED.value, updated_at = CURRENT_TIMESTAMP`, SecretsTable)
+	_, err := s.Db.Exec(query, secret.Name, secret.Value)
+	return err
+}
+
+// GetSecrets lists secrets without their values.
+func (s SecretStorage) GetSecrets() ([]entities.Secret, error) {
+	secrets := []entities.Secret{}
+	query := fmt.Sprintf("SELECT id, name, created_at, updated_at FROM %s ORDER BY name", SecretsTable)
+	err := s.Db.Select(&secrets, query)
+	return secrets, err
+}
+
+// GetSecretsByName returns secrets with the given names that exist, with
+// their encrypted values.
+func (s SecretStorage) GetSecretsByName(names []string) ([]entities.Secret, error) {
+	var secrets []entities.Secret
+	query := fmt.Sprintf("SELECT * FROM %s WHERE name = ANY($1)", SecretsTable)
+	err := s.Db.Select(&secrets, query, pq.Array(names))
+	return secrets, err
+}
+
+func (s SecretStorage) DeleteSecret(name string) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE name = $1", SecretsTable)
+	res, err := s.Db.Exec(query, name)
+	if err != nil {
+		return err
+	}
+	return expectRow(res)
+}
@@ -1,30 +1,120 @@
 package storage
 
 import (
+	"io"
 	"testex/internal/entities"
 	"testex/internal/storage/postgres"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 )
 
 type Storage struct {
 	CommandRepository
+	ScheduleRepository
+	PipelineRepository
+	RetentionRepository
+	SecretRepository
+	AuthRepository
+	AuditRepository
+	BlobStore
 }
 
 type CommandRepository interface {
 	SaveCommand(command entities.Command) (int, error)
 	GetCommand(alias string) (entities.Command, error)
 	GetAllCommands() ([]entities.Command, error)
+	UpdateCommand(alias string, version int, command entities.Command) (entities.Command, error)
+	DeleteCommand(alias string, version int) error
+	GetCommandRevisions(commandId int) ([]entities.CommandRevision, error)
 	SaveLog(entities.Log) (int, error)
+	SaveLogs([]entities.Log) ([]int, error)
+	GetLastLogSeq(executedCommandID int) (int64, error)
 	SaveExecutedCommand(entities.ExecutedCommand) (int, error)
-	FinishCommand(commandID int) error
-	GetLogsByExecutedCommand(executedCommandID int) ([]entities.Log, error)
+	StartExecutedCommand(entities.ExecutedCommand) error
+	FinishExecutedCommand(entities.ExecutedCommand) error
+	GetLogsByExecutedCommand(executedCommandID int, filter entities.LogFilter) ([]entities.Log, error)
+	SearchLogs(search entities.LogSearch) ([]entities.LogMatch, error)
 	GetExecutedCommandById(id int) (entities.ExecutedCommand, error)
 	GetActiveExecutedCommands() ([]entities.ExecutedCommand, error)
+	GetExecutedCommands(filter entities.ExecutionFilter) ([]entities.ExecutedCommand, error)
+	GetCommandRevision(id int) (entities.CommandRevision, error)
+	CountLogs(executedCommandID int) (int, error)
+	SaveExecutionOutput(output entities.ExecutionOutput) error
+	GetExecutionOutput(executedCommandID int, stream entities.LogStream) (entities.ExecutionOutput, error)
+	GetExecutionOutputs(executedCommandID int) ([]entities.ExecutionOutput, error)
+	SaveArtifact(artifact entities.Artifact) error
+	GetArtifacts(executedCommandID int) ([]entities.Artifact, error)
+	GetArtifact(executedCommandID int, path string) (entities.Artifact, error)
 }
 
-func New(db *sqlx.DB) *Storage {
+type ScheduleRepository interface {
+	SaveSchedule(schedule entities.Schedule) (int, error)
+	GetSchedule(id int) (entities.Schedule, error)
+	GetAllSchedules() ([]entities.Schedule, error)
+	UpdateSchedule(schedule entities.Schedule) error
+	DeleteSchedule(id int) error
+	SaveScheduleRun(id int, at time.Time, executionId *int) error
+}
+
+type PipelineRepository interface {
+	SavePipeline(pipeline entities.Pipeline) (int, error)
+	GetPipeline(name string) (entities.Pipeline, error)
+	GetAllPipelines() ([]entities.Pipeline, error)
+	SavePipelineRun(run entities.PipelineRun) (entities.PipelineRun, error)
+	GetPipelineRun(id int) (entities.PipelineRun, error)
+	UpdatePipelineRunStep(step entities.PipelineRunStep) error
+	FinishPipelineRun(run entities.PipelineRun) error
+	AbandonPipelineRuns() error
+}
+
+type RetentionRepository interface {
+	GetExpiredExecutions(policy entities.RetentionPolicy, limit int) ([]int, error)
+	DeleteExecutionLogs(ids []int, limit int) (int64, error)
+	DeleteExecutions(ids []int) error
+	GetOrphanedBlobs(after string, limit int) ([]string, error)
+	UnmarkBlobs(keys []string) error
+	GetUncompactedExecutions(maxLogBytes int64, limit int) ([]entities.LogCompaction, error)
+	TrimLogs(executedCommandID int, maxBytes int64, limit int) (int64, error)
+	FinishCompaction(executedCommandID int, trimmed int64) error
+}
+
+type SecretRepository interface {
+	SaveSecret(secret entities.Secret) error
+	GetSecrets() ([]entities.Secret, error)
+	GetSecretsByName(names []string) ([]entities.Secret, error)
+	DeleteSecret(name string) error
+}
+
+type AuthRepository interface {
+	SaveApiKey(key entities.ApiKey) (entities.ApiKey, error)
+	GetApiKeys() ([]entities.ApiKey, error)
+	GetApiKeyByHash(hash string) (entities.ApiKey, error)
+	DeleteApiKey(id int) error
+}
+
+// AuditRepository only appends, events are never changed.
+type AuditRepository interface {
+	SaveAuditEvent(event entities.AuditEvent) error
+	GetAuditEvents(filter entities.AuditFilter) ([]entities.AuditEvent, error)
+}
+
+// BlobStore keeps content-addressed blobs such as raw output of executions.
+type BlobStore interface {
+	PutBlob(r io.Reader) (key string, size int64, err error)
+	OpenBlob(key string) (io.ReadSeekCloser, error)
+	DeleteBlob(key string, writtenBefore time.Time) (bool, error)
+}
+
+func New(db *sqlx.DB, blobs BlobStore) *Storage {
 	return &Storage{
-		CommandRepository: postgres.NewCommandStorage(db),
+		CommandRepository:   postgres.NewCommandStorage(db),
+		ScheduleRepository:  postgres.NewScheduleStorage(db),
+		PipelineRepository:  postgres.NewPipelineStorage(db),
+		RetentionRepository: postgres.NewRetentionStorage(db),
+		SecretRepository:    postgres.NewSecretStorage(db),
+		AuthRepository:      postgres.NewAuthStorage(db),
+		AuditRepository:     postgres.NewAuditStorage(db),
+		BlobStore:           blobs,
 	}
 }